@@ -5,8 +5,13 @@
 
 source "${BASH_SOURCE%/*}/common.bash"
 
+# ensure we are in the right directory
+cd "${BASH_SOURCE%/*}/.."
+
+export PATH=$PWD/_vendor/bin:$GOPATH/bin:$PATH
+export GOPATH=$PWD/_vendor:$GOPATH
+
 r=$HOME/.cache/gopherize.me_site
-t=$(mktemp -d)
 
 (
 	cd $r
@@ -21,19 +26,14 @@ t=$(mktemp -d)
 
 echo ""
 
-echo "Copying..."
+echo "Building..."
 
-(
-	cd $t
-	wget --quiet --mirror http://localhost:8081/myitcv.io/gopherize.me/client/
-)
+go install github.com/gopherjs/gopherjs myitcv.io/gopherize.me/cmd/gopherize-site
 
-cp -rp $t/localhost:8081/myitcv.io/gopherize.me/client/* $r
+gopherize-site -o $r
 
 du -sh $r/!(artwork)
 
-cp -rp artwork $r
-
 echo ""
 
 cd $r
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gopherjs/gopherjs/js"
+	"myitcv.io/gopherize.me/render"
+)
+
+// siteArtwork is the artwork of the server from which the client was loaded.
+// It must only be used from a goroutine other than the one handling events,
+// because fetching blocks.
+var siteArtwork = render.NewDirFunc(fetchArtwork)
+
+// fetchArtwork fetches the file name of the artwork directory of the server
+// from which the client was loaded.
+func fetchArtwork(name string) (io.ReadCloser, error) {
+	base, err := url.Parse(document.URL())
+	if err != nil {
+		return nil, err
+	}
+
+	u := base.ResolveReference(&url.URL{Path: "artwork/" + name})
+
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to fetch %v: %v", u, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// jsBytes returns a Uint8Array holding exactly the bytes of b.
+func jsBytes(b []byte) *js.Object {
+	o := js.InternalObject(b)
+	off := o.Get("$offset").Int()
+	return o.Get("$array").Call("subarray", off, off+o.Get("$length").Int())
+}
+
+// imageSrc returns a data URI holding m, drawn by way of a canvas, which is
+// much faster than encoding m as a PNG in Go.
+func imageSrc(m *image.NRGBA) string {
+	b := m.Bounds()
+
+	c := js.Global.Get("document").Call("createElement", "canvas")
+	c.Set("width", b.Dx())
+	c.Set("height", b.Dy())
+
+	ctx := c.Call("getContext", "2d")
+	id := ctx.Call("createImageData", b.Dx(), b.Dy())
+	id.Get("data").Call("set", jsBytes(m.Pix))
+	ctx.Call("putImageData", id, 0, 0)
+
+	return c.Call("toDataURL", "image/png").String()
+}
+
+// download offers the data b to the user as a file called name.
+func download(b []byte, name, mimeType string) {
+	blob := js.Global.Get("Blob").New([]interface{}{jsBytes(b)}, map[string]interface{}{"type": mimeType})
+	u := js.Global.Get("URL").Call("createObjectURL", blob)
+
+	a := js.Global.Get("document").Call("createElement", "a")
+	a.Set("href", u)
+	a.Set("download", name)
+	js.Global.Get("document").Get("body").Call("appendChild", a)
+	a.Call("click")
+	a.Get("parentNode").Call("removeChild", a)
+
+	js.Global.Get("URL").Call("revokeObjectURL", u)
+}
@@ -3,19 +3,20 @@ package main
 //go:generate reactGen
 
 import (
-	"github.com/gopherjs/gopherjs/js"
+	"myitcv.io/gopherize.me/gopher"
 	r "myitcv.io/react"
 	"myitcv.io/react/jsx"
 )
 
 type ChooserProps struct {
-	Current *Gopher
-	Config  *Config
+	Current *gopher.Gopher
+	Config  *gopher.Config
 	Update  UpdateGopher
 }
 
 type ChooserState struct {
-	open int
+	open   int
+	saving bool
 }
 
 type ChooserDef struct {
@@ -39,13 +40,31 @@ func (ch ChooserDef) Render() r.Element {
 				Category: cat,
 				Open:     st.open == i,
 				Part:     i,
-				Selected: cg.Parts[i],
+				Current:  cg,
 				Update:   props.Update,
 				Expand:   ch,
 			},
 		))
 	}
 
+	var saveElem r.Element = r.Button(
+		&r.ButtonProps{
+			ID:        "next-button",
+			ClassName: "btn btn-primary btn-lg",
+			OnClick:   saveClick{ch},
+		},
+		r.S("Save \u0026 continue\u2026"),
+		r.I(&r.IProps{ClassName: "glyphicon glyphicon glyphicon-chevron-right"}),
+	)
+
+	if st.saving {
+		saveElem = Save(SaveProps{
+			Current: cg,
+			Update:  props.Update,
+			Close:   ch,
+		})
+	}
+
 	args := []r.Element{
 		r.Button(
 			&r.ButtonProps{
@@ -64,6 +83,7 @@ func (ch ChooserDef) Render() r.Element {
 			},
 			r.S("Reset"),
 		),
+		openImageButton(props.Update),
 		r.Br(nil),
 		r.Br(nil),
 		r.Div(
@@ -74,36 +94,50 @@ func (ch ChooserDef) Render() r.Element {
 			},
 			catDivs...,
 		),
+		Texts(TextsProps{
+			Current: cg,
+			Update:  props.Update,
+		}),
+		Uploads(UploadsProps{
+			Current: cg,
+			Update:  props.Update,
+		}),
+		Layers(LayersProps{
+			Current: cg,
+			Config:  props.Config,
+			Update:  props.Update,
+		}),
+		Scene(SceneProps{
+			Current: cg,
+			Update:  props.Update,
+		}),
 		r.Div(&r.DivProps{ClassName: "panel panel-default"},
 			r.Div(
 				&r.DivProps{
 					ClassName: "panel-body text-right",
 					Style:     &r.CSS{OverflowY: "hidden"},
 				},
-				r.Button(
-					&r.ButtonProps{
-						ID:        "next-button",
-						ClassName: "btn btn-primary btn-lg",
-						OnClick:   saveClick{ch},
-					},
-					r.S("Save \u0026 continue\u2026"),
-					r.I(&r.IProps{ClassName: "glyphicon glyphicon glyphicon-chevron-right"}),
-				),
+				saveElem,
+			),
+		),
+		r.Footer(nil,
+			r.S("Be truly unique, there are "),
+			r.Span(
+				&r.SpanProps{ClassName: "total_combinations"},
+				r.S(thousands(props.Config.Combinations().String())+" possible combinations"),
 			),
+			jsx.HTMLElem(`
+				<div>
+					<hr/>
+					Artwork by <a href='https://twitter.com/ashleymcnamara' target='_blank'>Ashley McNamara</a><br />inspired by <a href='http://reneefrench.blogspot.co.uk/' target='_blank'>Renee French</a><br />
+					Original web app by <a href='https://twitter.com/matryer' target='_blank'>Mat Ryer</a><br/>
+					Front-end Go React version by <a href="https://twitter.com/_myitcv" target="_blank">Paul Jolly</a>
+					<hr>
+					<a href='https://github.com/myitcv/gopherize.me/tree/master/client'>Source on GitHub</a><br/>
+					<a href='https://github.com/matryer/gopherize.me'>Original source on GitHub</a>
+				</div>
+			`),
 		),
-		jsx.HTMLElem(`
-			<footer>
-				Be truly unique, there are
-				<span class='total_combinations'></span>
-				<hr/>
-				Artwork by <a href='https://twitter.com/ashleymcnamara' target='_blank'>Ashley McNamara</a><br />inspired by <a href='http://reneefrench.blogspot.co.uk/' target='_blank'>Renee French</a><br />
-				Original web app by <a href='https://twitter.com/matryer' target='_blank'>Mat Ryer</a><br/>
-				Front-end Go React version by <a href="https://twitter.com/_myitcv" target="_blank">Paul Jolly</a>
-				<hr>
-				<a href='https://github.com/myitcv/gopherize.me/tree/master/client'>Source on GitHub</a><br/>
-				<a href='https://github.com/matryer/gopherize.me'>Original source on GitHub</a>
-			</footer>
-		`),
 	}
 
 	return r.Div(&r.DivProps{ClassName: "col-xs-4"}, args...)
@@ -115,6 +149,12 @@ func (ch ChooserDef) Expand(i int) {
 	ch.SetState(s)
 }
 
+func (ch ChooserDef) CloseSave() {
+	s := ch.State()
+	s.saving = false
+	ch.SetState(s)
+}
+
 type ExpandPanel interface {
 	Expand(i int)
 }
@@ -136,6 +176,17 @@ func (rc resetClick) OnClick(e *r.SyntheticMouseEvent) {
 type saveClick struct{ ChooserDef }
 
 func (sc saveClick) OnClick(e *r.SyntheticMouseEvent) {
-	js.Global.Call("alert", "Frontend only for now...")
+	s := sc.State()
+	s.saving = true
+	sc.SetState(s)
 	e.PreventDefault()
 }
+
+// thousands inserts commas between each group of three digits of the decimal
+// number n.
+func thousands(n string) string {
+	for i := len(n) - 3; i > 0; i -= 3 {
+		n = n[:i] + "," + n[i:]
+	}
+	return n
+}
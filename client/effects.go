@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+	r "myitcv.io/react"
+)
+
+// The choices offered for each effect; effects are drawn at full size, so
+// lengths are in pixels of the full size canvas.
+var (
+	outlineWidths = []int{0, 12, 24}
+	pixelSizes    = []int{0, 16, 32, 48}
+)
+
+// previewWidth is the width in pixels of the preview of effects.
+const previewWidth = 160
+
+// effectsControls returns the controls through which the effects of the
+// current gopher are chosen, together with a preview of them.
+func (s SaveDef) effectsControls() r.Element {
+	props := s.Props()
+	st := s.State()
+
+	var e gopher.Effects
+	if props.Current.Effects != nil {
+		e = *props.Current.Effects
+	}
+
+	field := func(f string) effectsChange {
+		return effectsChange{s: s, e: e, field: f}
+	}
+
+	option := func(v, label string) *r.OptionElem {
+		return r.Option(&r.OptionProps{Value: v}, r.S(label))
+	}
+
+	var outlines, shadows, filters, pixels []*r.OptionElem
+	for _, w := range outlineWidths {
+		label := "No outline"
+		if w != 0 {
+			label = strconv.Itoa(w) + "px outline"
+		}
+		outlines = append(outlines, option(strconv.Itoa(w), label))
+	}
+	shadows = append(shadows, option("false", "No shadow"), option("true", "Drop shadow"))
+	filters = append(filters, option("", "Full colour"))
+	for _, f := range gopher.Filters {
+		filters = append(filters, option(f, f))
+	}
+	for _, p := range pixelSizes {
+		label := "Smooth"
+		if p != 0 {
+			label = strconv.Itoa(p) + "px pixel art"
+		}
+		pixels = append(pixels, option(strconv.Itoa(p), label))
+	}
+
+	outlineColour := e.OutlineColour
+	if outlineColour == "" {
+		outlineColour = "#ffffff"
+	}
+
+	controls := []r.Element{
+		r.Select(&r.SelectProps{Value: strconv.Itoa(e.Outline), OnChange: field("outline")}, outlines...),
+		r.Input(&r.InputProps{Type: "color", Value: outlineColour, OnChange: field("outlineColour")}),
+		r.Select(&r.SelectProps{Value: strconv.FormatBool(e.Shadow), OnChange: field("shadow")}, shadows...),
+		r.Select(&r.SelectProps{Value: e.Filter, OnChange: field("filter")}, filters...),
+	}
+
+	if e.Filter == gopher.FilterDuotone {
+		dark, light := e.DuotoneRGBA()
+		controls = append(controls,
+			r.Input(&r.InputProps{Type: "color", Value: colourString(dark.R, dark.G, dark.B), OnChange: field("dark")}),
+			r.Input(&r.InputProps{Type: "color", Value: colourString(light.R, light.G, light.B), OnChange: field("light")}),
+		)
+	}
+
+	controls = append(controls,
+		r.Select(&r.SelectProps{Value: strconv.Itoa(e.PixelSize), OnChange: field("pixelSize")}, pixels...),
+	)
+
+	var preview r.Element = r.S("")
+	switch {
+	case e.IsZero():
+	case st.preview != "" && st.previewOf == props.Current:
+		preview = r.Img(&r.ImgProps{Src: st.preview, ClassName: "effects-preview"})
+	default:
+		preview = r.Span(&r.SpanProps{ClassName: "text-muted"}, r.S("Drawing effects…"))
+	}
+
+	return r.Div(&r.DivProps{ClassName: "save-options"},
+		r.Div(nil, controls...),
+		preview,
+	)
+}
+
+// ComponentDidMount draws the preview of the effects of the gopher the
+// dialog was opened with.
+func (s SaveDef) ComponentDidMount() {
+	s.drawPreview(s.Props().Current)
+}
+
+// ComponentWillReceiveProps redraws the preview when the gopher being saved
+// changes, including when its effects are changed through the dialog.
+func (s SaveDef) ComponentWillReceiveProps(next SaveProps) {
+	if next.Current != s.Props().Current {
+		s.drawPreview(next.Current)
+	}
+}
+
+// drawPreview draws g, with its effects, in the background, showing the
+// result once it is ready if g is still the gopher being saved.
+func (s SaveDef) drawPreview(g *gopher.Gopher) {
+	if g.Effects.IsZero() {
+		return
+	}
+
+	go func() {
+		m, err := render.Compose(g, siteArtwork)
+		if err != nil {
+			return
+		}
+
+		src := imageSrc(render.Resize(m, previewWidth, previewWidth*gopher.Height/gopher.Width))
+
+		st := s.State()
+		st.preview = src
+		st.previewOf = g
+		s.SetState(st)
+	}()
+}
+
+// effectsChange updates a field of the effects e from the value of the
+// control that changed.
+type effectsChange struct {
+	s     SaveDef
+	e     gopher.Effects
+	field string
+}
+
+func (ec effectsChange) OnChange(ev *r.SyntheticEvent) {
+	v := ev.Target().Underlying().Get("value").String()
+	e := ec.e
+
+	switch ec.field {
+	case "outline":
+		e.Outline, _ = strconv.Atoi(v)
+	case "outlineColour":
+		e.OutlineColour = v
+	case "shadow":
+		e.Shadow = v == "true"
+	case "filter":
+		e.Filter = v
+	case "dark":
+		e.Dark = v
+	case "light":
+		e.Light = v
+	case "pixelSize":
+		e.PixelSize, _ = strconv.Atoi(v)
+	}
+
+	if e.Validate() != nil {
+		return
+	}
+
+	ec.s.Props().Update.SetEffects(e)
+}
+
+// colourString returns the colour in the form #rrggbb used by gopher.
+func colourString(r, g, b uint8) string {
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
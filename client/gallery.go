@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gopherjs/gopherjs/js"
+	"honnef.co/go/js/dom"
+	"myitcv.io/gopherize.me/gopher"
+)
+
+// galleryAPI is the path of the gallery API served by gopherize-server.
+const galleryAPI = "/api/gophers"
+
+// savedParam is the URL query parameter that holds the ID of the gallery
+// gopher the current gopher was last saved as, so that it can be updated
+// rather than saved again.
+const savedParam = "saved"
+
+// tokenStoragePrefix is the prefix of the localStorage keys under which the
+// edit tokens of gophers saved to the gallery are kept. The gallery has no
+// user accounts; holding the token of a gopher is what allows it to be
+// updated.
+const tokenStoragePrefix = "gopherize.me/token/"
+
+// savedID returns the ID of the gallery gopher found in the page URL, or ""
+// if there is none.
+func savedID() string {
+	u, err := url.Parse(document.URL())
+	if err != nil {
+		panic(err)
+	}
+
+	return u.Query().Get(savedParam)
+}
+
+// setSavedID records id in the page URL as that of the gallery gopher the
+// current gopher was saved as; an empty id removes it.
+func setSavedID(id string) {
+	u, err := url.Parse(document.URL())
+	if err != nil {
+		panic(err)
+	}
+
+	q := u.Query()
+	if id == "" {
+		q.Del(savedParam)
+	} else {
+		q.Set(savedParam, id)
+	}
+	u.RawQuery = q.Encode()
+
+	dom.GetWindow().History().ReplaceState(nil, "", u.String())
+}
+
+func storeToken(id, token string) {
+	defer func() {
+		// localStorage may be full or unavailable, in which case the gopher
+		// can only be saved as new
+		recover()
+	}()
+
+	js.Global.Get("localStorage").Call("setItem", tokenStoragePrefix+id, token)
+}
+
+// loadToken returns the edit token of the gallery gopher id, or "" if it is
+// not known.
+func loadToken(id string) string {
+	if id == "" {
+		return ""
+	}
+
+	v := js.Global.Get("localStorage").Call("getItem", tokenStoragePrefix+id)
+	if v == nil || v == js.Undefined {
+		return ""
+	}
+
+	return v.String()
+}
+
+func forgetToken(id string) {
+	js.Global.Get("localStorage").Call("removeItem", tokenStoragePrefix+id)
+}
+
+// saveToGallery saves g to the gallery, returning its ID. If id is not
+// empty, the gallery gopher id is updated instead, provided its edit token
+// is known. It must only be used from a goroutine other than the one
+// handling events, because saving blocks.
+func saveToGallery(g *gopher.Gopher, id string) (string, error) {
+	recipe, err := gopher.MarshalRecipe(g)
+	if err != nil {
+		return "", err
+	}
+
+	method, path, token := "POST", galleryAPI, ""
+	if id != "" {
+		method, path, token = "PUT", galleryAPI+"/"+id, loadToken(id)
+		if token == "" {
+			return "", fmt.Errorf("gopher %v was not saved from this browser", id)
+		}
+	}
+
+	req, err := http.NewRequest(method, path, bytes.NewReader(recipe))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		ID        string
+		EditToken string
+		Error     string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", fmt.Errorf("failed to read response: %v", resp.Status)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+	case http.StatusForbidden, http.StatusNotFound:
+		// the token is of no further use
+		forgetToken(id)
+		fallthrough
+	default:
+		return "", fmt.Errorf("%v", res.Error)
+	}
+
+	if res.EditToken != "" {
+		storeToken(res.ID, res.EditToken)
+	}
+
+	return res.ID, nil
+}
@@ -0,0 +1,39 @@
+// Code generated by reactGen. DO NOT EDIT.
+
+package main
+
+import "myitcv.io/react"
+
+type LayersElem struct {
+	react.Element
+}
+
+func buildLayers(cd react.ComponentDef) react.Component {
+	return LayersDef{ComponentDef: cd}
+}
+
+func buildLayersElem(props LayersProps, children ...react.Element) *LayersElem {
+	return &LayersElem{
+		Element: react.CreateElement(buildLayers, props, children...),
+	}
+}
+
+func (l LayersDef) RendersElement() react.Element {
+	return l.Render()
+}
+
+// IsProps is an auto-generated definition so that LayersProps implements
+// the myitcv.io/react.Props interface.
+func (l LayersProps) IsProps() {}
+
+// Props is an auto-generated proxy to the current props of Layers
+func (l LayersDef) Props() LayersProps {
+	uprops := l.ComponentDef.Props()
+	return uprops.(LayersProps)
+}
+
+func (l LayersProps) EqualsIntf(val react.Props) bool {
+	return l == val.(LayersProps)
+}
+
+var _ react.Props = LayersProps{}
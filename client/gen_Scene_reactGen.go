@@ -0,0 +1,67 @@
+// Code generated by reactGen. DO NOT EDIT.
+
+package main
+
+import "myitcv.io/react"
+
+type SceneElem struct {
+	react.Element
+}
+
+func buildScene(cd react.ComponentDef) react.Component {
+	return SceneDef{ComponentDef: cd}
+}
+
+func buildSceneElem(props SceneProps, children ...react.Element) *SceneElem {
+	return &SceneElem{
+		Element: react.CreateElement(buildScene, props, children...),
+	}
+}
+
+func (s SceneDef) RendersElement() react.Element {
+	return s.Render()
+}
+
+// SetState is an auto-generated proxy proxy to update the state for the
+// Scene component.  SetState does not immediately mutate s.State()
+// but creates a pending state transition.
+func (s SceneDef) SetState(state SceneState) {
+	s.ComponentDef.SetState(state)
+}
+
+// State is an auto-generated proxy to return the current state in use for the
+// render of the Scene component
+func (s SceneDef) State() SceneState {
+	return s.ComponentDef.State().(SceneState)
+}
+
+// IsState is an auto-generated definition so that SceneState implements
+// the myitcv.io/react.State interface.
+func (s SceneState) IsState() {}
+
+var _ react.State = SceneState{}
+
+// GetInitialStateIntf is an auto-generated proxy to GetInitialState
+func (s SceneDef) GetInitialStateIntf() react.State {
+	return SceneState{}
+}
+
+func (s SceneState) EqualsIntf(val react.State) bool {
+	return s == val.(SceneState)
+}
+
+// IsProps is an auto-generated definition so that SceneProps implements
+// the myitcv.io/react.Props interface.
+func (s SceneProps) IsProps() {}
+
+// Props is an auto-generated proxy to the current props of Scene
+func (s SceneDef) Props() SceneProps {
+	uprops := s.ComponentDef.Props()
+	return uprops.(SceneProps)
+}
+
+func (s SceneProps) EqualsIntf(val react.Props) bool {
+	return s == val.(SceneProps)
+}
+
+var _ react.Props = SceneProps{}
@@ -0,0 +1,39 @@
+// Code generated by reactGen. DO NOT EDIT.
+
+package main
+
+import "myitcv.io/react"
+
+type TextsElem struct {
+	react.Element
+}
+
+func buildTexts(cd react.ComponentDef) react.Component {
+	return TextsDef{ComponentDef: cd}
+}
+
+func buildTextsElem(props TextsProps, children ...react.Element) *TextsElem {
+	return &TextsElem{
+		Element: react.CreateElement(buildTexts, props, children...),
+	}
+}
+
+func (t TextsDef) RendersElement() react.Element {
+	return t.Render()
+}
+
+// IsProps is an auto-generated definition so that TextsProps implements
+// the myitcv.io/react.Props interface.
+func (t TextsProps) IsProps() {}
+
+// Props is an auto-generated proxy to the current props of Texts
+func (t TextsDef) Props() TextsProps {
+	uprops := t.ComponentDef.Props()
+	return uprops.(TextsProps)
+}
+
+func (t TextsProps) EqualsIntf(val react.Props) bool {
+	return t == val.(TextsProps)
+}
+
+var _ react.Props = TextsProps{}
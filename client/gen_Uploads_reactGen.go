@@ -0,0 +1,39 @@
+// Code generated by reactGen. DO NOT EDIT.
+
+package main
+
+import "myitcv.io/react"
+
+type UploadsElem struct {
+	react.Element
+}
+
+func buildUploads(cd react.ComponentDef) react.Component {
+	return UploadsDef{ComponentDef: cd}
+}
+
+func buildUploadsElem(props UploadsProps, children ...react.Element) *UploadsElem {
+	return &UploadsElem{
+		Element: react.CreateElement(buildUploads, props, children...),
+	}
+}
+
+func (u UploadsDef) RendersElement() react.Element {
+	return u.Render()
+}
+
+// IsProps is an auto-generated definition so that UploadsProps implements
+// the myitcv.io/react.Props interface.
+func (u UploadsProps) IsProps() {}
+
+// Props is an auto-generated proxy to the current props of Uploads
+func (u UploadsDef) Props() UploadsProps {
+	uprops := u.ComponentDef.Props()
+	return uprops.(UploadsProps)
+}
+
+func (u UploadsProps) EqualsIntf(val react.Props) bool {
+	return u == val.(UploadsProps)
+}
+
+var _ react.Props = UploadsProps{}
@@ -0,0 +1,213 @@
+package main
+
+//go:generate reactGen
+
+import (
+	"strconv"
+
+	"github.com/gopherjs/gopherjs/js"
+	"myitcv.io/gopherize.me/gopher"
+	r "myitcv.io/react"
+)
+
+// LayersProps are the props of the Layers component, which lists the parts of
+// the current gopher top layer first. Layers can be dragged to change their
+// z-order, hidden and removed.
+type LayersProps struct {
+	Current *gopher.Gopher
+	Config  *gopher.Config
+	Update  UpdateGopher
+}
+
+type LayersDef struct {
+	r.ComponentDef
+}
+
+func Layers(p LayersProps) *LayersElem {
+	return buildLayersElem(p)
+}
+
+func (l LayersDef) Render() r.Element {
+	props := l.Props()
+	g := props.Current
+
+	stack := g.Stack()
+
+	var items []r.RendersLi
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		p := stack[i]
+
+		// uploads and texts can always be removed; otherwise a part can be removed
+		// if its category allows no selection, or if another option remains
+		name, removable := "", true
+		if u, ok := g.Upload(p); ok {
+			name = u.Name
+		} else if t, ok := g.Text(p); ok {
+			name = "Text: " + t.Text
+		} else {
+			ci := g.CategoryOf(p)
+			cat := props.Config.Categories[ci]
+
+			name = cat.Name
+			removable = cat.Has("") || len(g.Parts[ci]) > 1
+		}
+
+		hidden := g.Hidden[p]
+
+		eye := "eye-open"
+		class := "list-group-item layer"
+		if hidden {
+			eye = "eye-close"
+			class += " hidden-layer"
+		}
+
+		buttons := []r.Element{
+			r.Button(
+				&r.ButtonProps{
+					ClassName: "btn btn-default btn-xs",
+					OnClick:   showLayerClick{U: props.Update, p: p, show: hidden},
+				},
+				r.I(&r.IProps{ClassName: "glyphicon glyphicon-" + eye}),
+			),
+		}
+
+		if removable {
+			buttons = append(buttons, r.Button(
+				&r.ButtonProps{
+					ClassName: "btn btn-default btn-xs",
+					OnClick:   removePartClick{U: props.Update, p: p},
+				},
+				r.I(&r.IProps{ClassName: "glyphicon glyphicon-trash"}),
+			))
+		}
+
+		items = append(items, r.Li(
+			&r.LiProps{
+				ClassName: class,
+				Key:       p,
+				Ref:       layerDrag{U: props.Update, i: i},
+			},
+			r.I(&r.IProps{ClassName: "glyphicon glyphicon-menu-hamburger"}),
+			r.Img(&r.ImgProps{Src: thumbnailSrc(g, p)}),
+			r.S(name),
+			r.Span(&r.SpanProps{ClassName: "pull-right"}, buttons...),
+		))
+	}
+
+	bg := g.Background
+	if bg == "" {
+		bg = "#ffffff"
+	}
+
+	items = append(items, r.Li(
+		&r.LiProps{ClassName: "list-group-item", Key: "background"},
+		r.S("Background "),
+		r.Input(&r.InputProps{
+			Type:     "color",
+			Value:    bg,
+			OnChange: backgroundChange{U: props.Update},
+		}),
+		r.Span(&r.SpanProps{ClassName: "pull-right"},
+			r.Button(
+				&r.ButtonProps{
+					ClassName: "btn btn-default btn-xs",
+					OnClick:   clearBackgroundClick{U: props.Update},
+				},
+				r.S("Transparent"),
+			),
+		),
+	))
+
+	return r.Div(&r.DivProps{ClassName: "panel panel-default"},
+		r.Div(&r.DivProps{ClassName: "panel-heading"},
+			r.H4(&r.H4Props{ClassName: "panel-title"}, r.S("Layers")),
+		),
+		r.Ul(&r.UlProps{ClassName: "list-group", ID: "layers"}, items...),
+	)
+}
+
+type showLayerClick struct {
+	U    UpdateGopher
+	p    string
+	show bool
+}
+
+func (s showLayerClick) OnClick(e *r.SyntheticMouseEvent) {
+	s.U.ShowLayer(s.p, s.show)
+	e.PreventDefault()
+}
+
+type backgroundChange struct {
+	U UpdateGopher
+}
+
+func (b backgroundChange) OnChange(e *r.SyntheticEvent) {
+	b.U.SetBackground(e.Target().Underlying().Get("value").String())
+}
+
+type clearBackgroundClick struct {
+	U UpdateGopher
+}
+
+func (c clearBackgroundClick) OnClick(e *r.SyntheticMouseEvent) {
+	c.U.SetBackground("")
+	e.PreventDefault()
+}
+
+type removePartClick struct {
+	U UpdateGopher
+	p string
+}
+
+func (rp removePartClick) OnClick(e *r.SyntheticMouseEvent) {
+	rp.U.RemovePart(rp.p)
+	e.PreventDefault()
+}
+
+// layerDrag makes the list item for the layer at index i of the gopher's
+// stack draggable. The React bindings do not expose drag events, so native
+// listeners are attached to the DOM node the first time it is seen; the
+// properties they rely on are refreshed on each render.
+type layerDrag struct {
+	U UpdateGopher
+	i int
+}
+
+const (
+	layerIndexProp = "gopherizeLayer"
+	layerMoveProp  = "gopherizeMove"
+	layerTypeData  = "text/x-gopherize-layer"
+)
+
+func (ld layerDrag) Ref(n *js.Object) {
+	if n == nil || n == js.Undefined {
+		return
+	}
+
+	n.Set("draggable", true)
+	n.Set(layerIndexProp, ld.i)
+	n.Set(layerMoveProp, ld.U.MoveLayer)
+
+	if n.Get(layerMoveProp+"Init") != js.Undefined {
+		return
+	}
+	n.Set(layerMoveProp+"Init", true)
+
+	n.Call("addEventListener", "dragstart", func(e *js.Object) {
+		e.Get("dataTransfer").Call("setData", layerTypeData, strconv.Itoa(n.Get(layerIndexProp).Int()))
+	})
+	n.Call("addEventListener", "dragover", func(e *js.Object) {
+		e.Call("preventDefault")
+	})
+	n.Call("addEventListener", "drop", func(e *js.Object) {
+		e.Call("preventDefault")
+
+		from, err := strconv.Atoi(e.Get("dataTransfer").Call("getData", layerTypeData).String())
+		if err != nil {
+			return
+		}
+
+		n.Get(layerMoveProp).Invoke(from, n.Get(layerIndexProp).Int())
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/gopherjs/gopherjs/js"
+	"myitcv.io/gopherize.me/export"
+	"myitcv.io/gopherize.me/gopher"
+	r "myitcv.io/react"
+)
+
+// openImageButton returns the button through which a PNG previously saved
+// from gopherize.me is opened, making the gopher recorded in it current.
+func openImageButton(u UpdateGopher) r.Element {
+	return r.Label(
+		&r.LabelProps{
+			ID:        "open-button",
+			ClassName: "btn btn-default",
+		},
+		r.I(&r.IProps{ClassName: "glyphicon glyphicon-folder-open"}),
+		r.S(" Open image"),
+		r.Input(&r.InputProps{
+			Type:     "file",
+			OnChange: openImageChange{U: u},
+		}),
+	)
+}
+
+type openImageChange struct {
+	U UpdateGopher
+}
+
+func (oc openImageChange) OnChange(e *r.SyntheticEvent) {
+	in := e.Target().Underlying()
+
+	files := in.Get("files")
+	if files.Length() == 0 {
+		return
+	}
+	f := files.Index(0)
+	name := f.Get("name").String()
+
+	// allow the same file to be chosen again
+	in.Set("value", "")
+
+	fr := js.Global.Get("FileReader").New()
+	fr.Set("onload", func() {
+		data := js.Global.Get("Uint8Array").New(fr.Get("result")).Interface().([]byte)
+
+		g, err := export.ReadPNG(bytes.NewReader(data), gopher.DefaultConfig)
+		if err != nil {
+			js.Global.Call("alert", "Cannot open "+name+": "+err.Error())
+			return
+		}
+
+		// keep the data of uploads carried by the image, and look for that
+		// of any that are not
+		for _, u := range g.Uploads {
+			if u.Data != nil {
+				storeUpload(u)
+			}
+		}
+		loadUploads(g)
+
+		oc.U.OpenGopher(g)
+	})
+	fr.Call("readAsArrayBuffer", f)
+}
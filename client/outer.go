@@ -4,8 +4,11 @@ package main
 
 import (
 	"math/rand"
+	"net/url"
 	"time"
 
+	"honnef.co/go/js/dom"
+	"myitcv.io/gopherize.me/gopher"
 	r "myitcv.io/react"
 )
 
@@ -14,8 +17,8 @@ type OuterDef struct {
 }
 
 type OuterState struct {
-	current *Gopher
-	config  *Config
+	current *gopher.Gopher
+	config  *gopher.Config
 	rand    *rand.Rand
 }
 
@@ -24,9 +27,16 @@ func Outer() *OuterElem {
 }
 
 func (o OuterDef) ComponentWillMount() {
+	c := gopher.DefaultConfig
+
+	g := urlGopher(c)
+	if g == nil {
+		g = defaultGopher(c)
+	}
+
 	o.SetState(OuterState{
-		current: defaultGopher(hackConfig),
-		config:  hackConfig,
+		current: g,
+		config:  c,
 		rand:    rand.New(rand.NewSource(time.Now().Unix())),
 	})
 }
@@ -44,45 +54,174 @@ func (o OuterDef) Render() r.Element {
 
 func (o OuterDef) ResetGopher() {
 	s := o.State()
-	s.current = defaultGopher(s.config)
-	o.SetState(s)
+	o.setGopher(s, defaultGopher(s.config))
+}
+
+// OpenGopher makes g, for example one read from an image, the current
+// gopher.
+func (o OuterDef) OpenGopher(g *gopher.Gopher) {
+	o.setGopher(o.State(), g)
 }
 
 func (o OuterDef) UpdateGopher(part int, val string) {
 	s := o.State()
 
-	nps := make([]string, len(s.current.Parts))
-	copy(nps, s.current.Parts)
-	nps[part] = val
+	g := s.current.Copy()
+	g.Choose(part, val, s.config.Categories[part].MaxSelections())
 
-	s.current = &Gopher{Parts: nps}
-	o.SetState(s)
+	o.setGopher(s, g)
 }
 
-func (o OuterDef) RandomGopher() {
+func (o OuterDef) TransformPart(part string, t gopher.Transform) {
+	s := o.State()
+
+	g := s.current.Copy()
+	g.SetTransform(part, t)
+
+	o.setGopher(s, g)
+}
+
+func (o OuterDef) RemovePart(part string) {
+	s := o.State()
+
+	g := s.current.Copy()
+	g.Remove(part)
+
+	o.setGopher(s, g)
+}
+
+func (o OuterDef) AddUpload(u gopher.Upload) {
 	s := o.State()
-	c := s.config
 
-	var parts []string
+	g := s.current.Copy()
+	g.AddUpload(u)
+
+	o.setGopher(s, g)
+}
+
+func (o OuterDef) SetText(t gopher.Text) {
+	s := o.State()
 
-	for _, cat := range c.Categories {
-		p := cat.Options[s.rand.Intn(len(cat.Options))]
-		parts = append(parts, p)
+	g := s.current.Copy()
+	g.SetText(t)
+
+	o.setGopher(s, g)
+}
+
+func (o OuterDef) SetBackground(c string) {
+	s := o.State()
+
+	g := s.current.Copy()
+	g.Background = c
+
+	o.setGopher(s, g)
+}
+
+func (o OuterDef) SetEffects(e gopher.Effects) {
+	s := o.State()
+
+	g := s.current.Copy()
+	g.Effects = nil
+	if !e.IsZero() {
+		g.Effects = &e
 	}
 
-	s.current = &Gopher{Parts: parts}
+	o.setGopher(s, g)
+}
+
+func (o OuterDef) MoveLayer(from, to int) {
+	s := o.State()
+
+	g := s.current.Copy()
+	g.MoveLayer(from, to)
+
+	o.setGopher(s, g)
+}
+
+func (o OuterDef) ShowLayer(part string, show bool) {
+	s := o.State()
+
+	g := s.current.Copy()
+	g.SetHidden(part, !show)
+
+	o.setGopher(s, g)
+}
+
+func (o OuterDef) RandomGopher() {
+	s := o.State()
+
+	g := s.config.Random(s.rand)
+	g.Uploads = s.current.Uploads
+	g.Texts = s.current.Texts
+
+	o.setGopher(s, g)
+}
+
+// setGopher makes g the current gopher, recording its recipe in the URL so
+// that it can be bookmarked. The data of uploads is kept in localStorage
+// rather than the URL.
+func (o OuterDef) setGopher(s OuterState, g *gopher.Gopher) {
+	s.current = g
 	o.SetState(s)
+
+	ug := g.Copy()
+	for i := range ug.Uploads {
+		ug.Uploads[i].Data = nil
+	}
+
+	enc, err := gopher.EncodeRecipe(ug)
+	if err != nil {
+		panic(err)
+	}
+
+	u, err := url.Parse(document.URL())
+	if err != nil {
+		panic(err)
+	}
+
+	q := u.Query()
+	q.Set(recipeParam, enc)
+	u.RawQuery = q.Encode()
+
+	dom.GetWindow().History().ReplaceState(nil, "", u.String())
 }
 
 func randElem(ss []string) string {
 	return ss[rand.Intn(len(ss))]
 }
 
-func defaultGopher(c *Config) *Gopher {
-	parts := make([]string, len(c.Categories))
+func defaultGopher(c *gopher.Config) *gopher.Gopher {
+	parts := make([][]string, len(c.Categories))
+
+	parts[0] = []string{c.Categories[0].Options[0]}
+	parts[1] = []string{c.Categories[1].Options[0]}
+
+	return &gopher.Gopher{Parts: parts}
+}
+
+// recipeParam is the URL query parameter that holds the recipe of the current
+// gopher.
+const recipeParam = "g"
+
+// urlGopher returns the gopher whose recipe is found in the page URL, or nil
+// if there is no (valid) recipe.
+func urlGopher(c *gopher.Config) *gopher.Gopher {
+	u, err := url.Parse(document.URL())
+	if err != nil {
+		panic(err)
+	}
+
+	v := u.Query().Get(recipeParam)
+	if v == "" {
+		return nil
+	}
+
+	g, err := gopher.DecodeRecipe(v, c)
+	if err != nil {
+		return nil
+	}
 
-	parts[0] = c.Categories[0].Options[0]
-	parts[1] = c.Categories[1].Options[0]
+	loadUploads(g)
 
-	return &Gopher{Parts: parts}
+	return g
 }
@@ -1,18 +1,20 @@
 package main
 
 import (
+	"fmt"
 	"path/filepath"
 
+	"myitcv.io/gopherize.me/gopher"
 	r "myitcv.io/react"
 )
 
 var blank = filepath.Join("artwork", "whitebox_thumbnail.png")
 
 type PanelProps struct {
-	Category *Category
+	Category *gopher.Category
 	Open     bool
 	Part     int
-	Selected string
+	Current  *gopher.Gopher
 	Update   UpdateGopher
 	Expand   ExpandPanel
 }
@@ -36,12 +38,18 @@ func (pa PanelDef) Render() r.Element {
 
 	var imgs []r.Element
 
+	selected := props.Current.Parts[props.Part]
+
 	if props.Open {
+		for _, p := range selected {
+			imgs = append(imgs, transformControls(props.Update, props.Current, p, len(selected) > 1))
+		}
+
 		for _, o := range props.Category.Options {
 			var src string
 			class := "item"
 
-			if o == props.Selected {
+			if props.Current.CategoryOf(o) == props.Part || o == "" && len(selected) == 0 {
 				class += " selected"
 			} else if o == "" {
 				class += " none"
@@ -84,6 +92,7 @@ func (pa PanelDef) Render() r.Element {
 					},
 					r.S(props.Category.Name),
 				),
+				maxNote(props.Category),
 			),
 		),
 		r.Div(
@@ -101,6 +110,64 @@ func (pa PanelDef) Render() r.Element {
 
 }
 
+// nudge is the distance in pixels that a part is moved by each click of one
+// of the transform arrow buttons; scaleStep is the corresponding step for the
+// scale buttons.
+const (
+	nudge     = 10
+	scaleStep = 0.1
+)
+
+// transformControls returns the buttons that transform the part p of g,
+// labelled with a thumbnail of p when label is set.
+func transformControls(u UpdateGopher, g *gopher.Gopher, p string, label bool) r.Element {
+	t := g.Transform(p).Clamp()
+
+	button := func(icon, title string, nt gopher.Transform) r.Element {
+		return r.Button(
+			&r.ButtonProps{
+				ClassName: "btn btn-default btn-xs",
+				OnClick: transformClick{
+					U: u,
+					p: p,
+					t: nt,
+				},
+			},
+			r.I(&r.IProps{ClassName: "glyphicon glyphicon-" + icon}),
+			r.S(title),
+		)
+	}
+
+	flip, left, right, up, down, smaller, bigger := t, t, t, t, t, t, t
+
+	flip.FlipH = !t.FlipH
+	left.X -= nudge
+	right.X += nudge
+	up.Y -= nudge
+	down.Y += nudge
+	smaller.Scale -= scaleStep
+	bigger.Scale += scaleStep
+
+	var elems []r.Element
+
+	if label {
+		elems = append(elems, r.Img(&r.ImgProps{Src: thumbnailSrc(g, p)}))
+	}
+
+	elems = append(elems,
+		button("transfer", " Flip", flip),
+		button("arrow-left", "", left),
+		button("arrow-right", "", right),
+		button("arrow-up", "", up),
+		button("arrow-down", "", down),
+		button("zoom-out", "", smaller),
+		button("zoom-in", "", bigger),
+		button("remove", " Reset", gopher.Transform{}),
+	)
+
+	return r.Div(&r.DivProps{ClassName: "transform"}, elems...)
+}
+
 type expandClick struct {
 	E ExpandPanel
 	i int
@@ -121,3 +188,25 @@ func (c chooseItemClick) OnClick(e *r.SyntheticMouseEvent) {
 	c.U.UpdateGopher(c.ci, c.v)
 	e.PreventDefault()
 }
+
+type transformClick struct {
+	U UpdateGopher
+	p string
+	t gopher.Transform
+}
+
+func (tc transformClick) OnClick(e *r.SyntheticMouseEvent) {
+	tc.U.TransformPart(tc.p, tc.t)
+	e.PreventDefault()
+}
+
+// maxNote returns a note of the number of options that can be selected in a
+// multiple selection category.
+func maxNote(c *gopher.Category) r.Element {
+	var note string
+	if c.MaxSelections() > 1 {
+		note = fmt.Sprintf(" (choose up to %v)", c.MaxSelections())
+	}
+
+	return r.Span(&r.SpanProps{ClassName: "text-muted"}, r.S(note))
+}
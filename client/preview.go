@@ -3,13 +3,15 @@ package main
 //go:generate reactGen
 
 import (
-	"path/filepath"
+	"fmt"
+	"net/url"
 
+	"myitcv.io/gopherize.me/gopher"
 	r "myitcv.io/react"
 )
 
 type PreviewProps struct {
-	Current *Gopher
+	Current *gopher.Gopher
 }
 
 type PreviewDef struct {
@@ -26,18 +28,29 @@ func (o PreviewDef) Render() r.Element {
 	curr := o.Props().Current
 
 	addPart := func(p string) {
+		t := curr.Transform(p)
+
+		var class string
+		if t.FlipH {
+			class = "flip"
+		}
+
 		parts = append(parts, r.Img(&r.ImgProps{
-			Src: filepath.Join("artwork", p+".png"),
-			Style: &r.CSS{
-				MarginTop: "0px",
-			},
+			ClassName: class,
+			Src:       layerSrc(curr, p),
+			Style:     transformCSS(t),
 		}))
 	}
 
-	for _, p := range curr.Parts {
-		if p != "" {
-			addPart(p)
-		}
+	if curr.Background != "" {
+		parts = append(parts, r.Img(&r.ImgProps{
+			Src:   backgroundSrc(curr.Background),
+			Style: transformCSS(gopher.Transform{}),
+		}))
+	}
+
+	for _, p := range curr.Layers() {
+		addPart(p)
 	}
 
 	return r.Div(&r.DivProps{ClassName: "col-xs-8"},
@@ -46,3 +59,29 @@ func (o PreviewDef) Render() r.Element {
 		),
 	)
 }
+
+// transformCSS returns the style that places a layer as described by t. All
+// lengths are given as percentages of the preview width (percentage margins
+// are relative to the width of the containing block, even when vertical) so
+// that the layer scales with the preview. Mirroring is handled by the flip
+// class.
+func transformCSS(t gopher.Transform) *r.CSS {
+	x, y, w, _ := t.Rect()
+
+	pc := func(v float64) string {
+		return fmt.Sprintf("%.4f%%", v*100/gopher.Width)
+	}
+
+	return &r.CSS{
+		Left:      pc(x),
+		MarginTop: pc(y),
+		Width:     pc(w),
+	}
+}
+
+// backgroundSrc returns the image source for a canvas filled with the colour
+// c.
+func backgroundSrc(c string) string {
+	svg := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%v" height="%v"><rect width="100%%" height="100%%" fill="%v"/></svg>`, gopher.Width, gopher.Height, c)
+	return "data:image/svg+xml," + url.PathEscape(svg)
+}
@@ -0,0 +1,411 @@
+package main
+
+//go:generate reactGen
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+
+	"github.com/gopherjs/gopherjs/js"
+	"myitcv.io/gopherize.me/export"
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+	r "myitcv.io/react"
+)
+
+// SaveProps are the props of the Save component, the dialog through which
+// the current gopher is downloaded in one of a number of formats.
+type SaveProps struct {
+	Current *gopher.Gopher
+	Update  UpdateGopher
+	Close   CloseSave
+}
+
+type SaveState struct {
+	busy bool
+
+	// the layout of PDF downloads; the zero values are a single 50mm
+	// sticker without cut lines
+	pdfSize  int
+	pdfSheet string
+	pdfMarks int
+
+	// the index in export.Animations of the animation of GIF downloads
+	gifAnim int
+
+	// preview is the image source of the gopher previewOf with its effects
+	// applied, once it has been drawn
+	preview   string
+	previewOf *gopher.Gopher
+
+	// saving is set while the gopher is being saved to the gallery, and
+	// saved holds the ID it was last saved as
+	saving bool
+	saved  string
+}
+
+type SaveDef struct {
+	r.ComponentDef
+}
+
+func Save(p SaveProps) *SaveElem {
+	return buildSaveElem(p)
+}
+
+type CloseSave interface {
+	CloseSave()
+}
+
+// saveFormat is a format in which a gopher can be downloaded.
+type saveFormat struct {
+	name     string
+	file     string
+	mimeType string
+	write    func(w io.Writer, g *gopher.Gopher) error
+}
+
+var saveFormats = []saveFormat{
+	{
+		name:     "PNG",
+		file:     "gopher.png",
+		mimeType: "image/png",
+		write: func(w io.Writer, g *gopher.Gopher) error {
+			m, err := render.Compose(g, siteArtwork)
+			if err != nil {
+				return err
+			}
+			return export.PNG(w, m, g)
+		},
+	},
+	{
+		name:     "SVG",
+		file:     "gopher.svg",
+		mimeType: "image/svg+xml",
+		write: func(w io.Writer, g *gopher.Gopher) error {
+			return export.SVG(w, g, siteArtwork)
+		},
+	},
+	{
+		name:     "emoji pack",
+		file:     "gopher-expressions.zip",
+		mimeType: "application/zip",
+		write: func(w io.Writer, g *gopher.Gopher) error {
+			return export.Expressions(w, g, siteArtwork, "gopher", export.DefaultEmojiSize)
+		},
+	},
+	{
+		name:     "sticker",
+		file:     "gopher-sticker.png",
+		mimeType: "image/png",
+		write: func(w io.Writer, g *gopher.Gopher) error {
+			m, err := export.Sticker(g, siteArtwork, "", export.DefaultStickerOptions)
+			if err != nil {
+				return err
+			}
+			return export.PNG(w, m, g)
+		},
+	},
+	{
+		name:     "sticker pack",
+		file:     "gopher-stickers.zip",
+		mimeType: "application/zip",
+		write: func(w io.Writer, g *gopher.Gopher) error {
+			return export.Stickers(w, "gopher", export.EyeVariants("gopher", g), siteArtwork, export.DefaultStickerOptions)
+		},
+	},
+}
+
+// pdfSizes are the widths, in millimetres, offered for PDF downloads.
+var pdfSizes = []int{50, 75, 100}
+
+// pdfMarks are the cut lines and bleed offered for PDF downloads.
+var pdfMarks = []struct {
+	name  string
+	cut   bool
+	bleed float64
+}{
+	{name: "No cut lines"},
+	{name: "Cut lines", cut: true},
+	{name: "Cut lines, 3mm bleed", cut: true, bleed: 3},
+}
+
+// pdfFormat returns the PDF format laid out as currently chosen.
+func (s SaveDef) pdfFormat() saveFormat {
+	st := s.State()
+
+	o := export.PDFOptions{
+		Size:     float64(st.pdfSize),
+		CutLines: pdfMarks[st.pdfMarks].cut,
+		Bleed:    pdfMarks[st.pdfMarks].bleed,
+	}
+	if o.Size == 0 {
+		o.Size = float64(pdfSizes[0])
+	}
+
+	if st.pdfSheet != "" {
+		sh, err := export.LookupSheet(st.pdfSheet)
+		if err == nil {
+			o.Sheet = &sh
+		}
+	}
+
+	return saveFormat{
+		name:     "PDF",
+		file:     "gopher.pdf",
+		mimeType: "application/pdf",
+		write: func(w io.Writer, g *gopher.Gopher) error {
+			return export.PDF(w, g, siteArtwork, o)
+		},
+	}
+}
+
+// gifWidth is the width in pixels of GIF downloads, a size suited to chat
+// and social media.
+const gifWidth = 320
+
+// gifFormat returns the animated GIF format with the animation currently
+// chosen.
+func (s SaveDef) gifFormat() saveFormat {
+	a := export.Animations[s.State().gifAnim]
+
+	return saveFormat{
+		name:     "GIF",
+		file:     "gopher-" + a.Name + ".gif",
+		mimeType: "image/gif",
+		write: func(w io.Writer, g *gopher.Gopher) error {
+			return export.GIF(w, g, siteArtwork, export.GIFOptions{
+				Frames: a.Frames,
+				Width:  gifWidth,
+			})
+		},
+	}
+}
+
+func (s SaveDef) Render() r.Element {
+	props := s.Props()
+	st := s.State()
+
+	buttons := []r.Element{s.effectsControls()}
+
+	button := func(f saveFormat) r.Element {
+		label := "Download " + f.name
+		if st.busy {
+			label = "Preparing…"
+		}
+
+		return r.Button(
+			&r.ButtonProps{
+				ClassName: "btn btn-default",
+				OnClick:   saveFormatClick{s: s, f: f},
+			},
+			r.I(&r.IProps{ClassName: "glyphicon glyphicon-download-alt"}),
+			r.S(" "+label),
+		)
+	}
+
+	for _, f := range saveFormats {
+		buttons = append(buttons, button(f))
+	}
+
+	var sizes, sheets, marks []*r.OptionElem
+	for _, v := range pdfSizes {
+		sizes = append(sizes, r.Option(&r.OptionProps{Value: strconv.Itoa(v)}, r.S(strconv.Itoa(v)+"mm")))
+	}
+	sheets = append(sheets, r.Option(&r.OptionProps{Value: ""}, r.S("Single sticker")))
+	for _, sh := range export.Sheets {
+		sheets = append(sheets, r.Option(&r.OptionProps{Value: sh.Name}, r.S(sh.Name+" sheet")))
+	}
+	for i, m := range pdfMarks {
+		marks = append(marks, r.Option(&r.OptionProps{Value: strconv.Itoa(i)}, r.S(m.name)))
+	}
+
+	size := st.pdfSize
+	if size == 0 {
+		size = pdfSizes[0]
+	}
+
+	buttons = append(buttons, r.Div(&r.DivProps{ClassName: "save-options"},
+		r.Select(&r.SelectProps{Value: strconv.Itoa(size), OnChange: saveOptionChange{s: s, field: "pdfSize"}}, sizes...),
+		r.Select(&r.SelectProps{Value: st.pdfSheet, OnChange: saveOptionChange{s: s, field: "pdfSheet"}}, sheets...),
+		r.Select(&r.SelectProps{Value: strconv.Itoa(st.pdfMarks), OnChange: saveOptionChange{s: s, field: "pdfMarks"}}, marks...),
+		button(s.pdfFormat()),
+	))
+
+	var anims []*r.OptionElem
+	for i, a := range export.Animations {
+		anims = append(anims, r.Option(&r.OptionProps{Value: strconv.Itoa(i)}, r.S(a.Name)))
+	}
+
+	buttons = append(buttons, r.Div(&r.DivProps{ClassName: "save-options"},
+		r.Select(&r.SelectProps{Value: strconv.Itoa(st.gifAnim), OnChange: saveOptionChange{s: s, field: "gifAnim"}}, anims...),
+		button(s.gifFormat()),
+	))
+
+	buttons = append(buttons, s.galleryControls())
+
+	buttons = append(buttons, r.Button(
+		&r.ButtonProps{
+			ClassName: "btn btn-link",
+			OnClick:   closeSaveClick{props.Close},
+		},
+		r.S("Close"),
+	))
+
+	return r.Div(&r.DivProps{ID: "save-dialog", ClassName: "text-left"}, buttons...)
+}
+
+func (s SaveDef) download(f saveFormat) {
+	if s.State().busy {
+		return
+	}
+
+	st := s.State()
+	st.busy = true
+	s.SetState(st)
+
+	g := s.Props().Current
+
+	go func() {
+		defer func() {
+			st := s.State()
+			st.busy = false
+			s.SetState(st)
+		}()
+
+		var buf bytes.Buffer
+		if err := f.write(&buf, g); err != nil {
+			js.Global.Call("alert", "Failed to save gopher: "+err.Error())
+			return
+		}
+
+		download(buf.Bytes(), f.file, f.mimeType)
+	}()
+}
+
+// galleryControls returns the buttons through which the gopher is saved to
+// the gallery. A gopher that was saved from this browser, and whose edit
+// token is therefore known, is offered to be updated in place.
+func (s SaveDef) galleryControls() r.Element {
+	st := s.State()
+
+	button := func(label string, update bool) r.Element {
+		if st.saving {
+			label = "Saving…"
+		}
+
+		return r.Button(
+			&r.ButtonProps{
+				ClassName: "btn btn-default",
+				OnClick:   galleryClick{s: s, update: update},
+			},
+			r.I(&r.IProps{ClassName: "glyphicon glyphicon-cloud-upload"}),
+			r.S(" "+label),
+		)
+	}
+
+	var controls []r.Element
+	if loadToken(savedID()) != "" {
+		controls = append(controls,
+			button("Update my saved gopher", true),
+			button("Save as new gopher", false),
+		)
+	} else {
+		controls = append(controls, button("Save to gallery", false))
+	}
+
+	if st.saved != "" {
+		controls = append(controls, r.Span(&r.SpanProps{ClassName: "gallery-saved"}, r.S("Saved as "+st.saved)))
+	}
+
+	return r.Div(&r.DivProps{ClassName: "save-options"}, controls...)
+}
+
+// saveToGallery saves the current gopher to the gallery, updating the gopher
+// it was last saved as if update is set.
+func (s SaveDef) saveToGallery(update bool) {
+	if s.State().saving {
+		return
+	}
+
+	st := s.State()
+	st.saving = true
+	s.SetState(st)
+
+	g := s.Props().Current
+
+	var id string
+	if update {
+		id = savedID()
+	}
+
+	go func() {
+		saved, err := saveToGallery(g, id)
+
+		st := s.State()
+		st.saving = false
+		if err == nil {
+			st.saved = saved
+			setSavedID(saved)
+		}
+		s.SetState(st)
+
+		if err != nil {
+			js.Global.Call("alert", "Failed to save gopher to the gallery: "+err.Error())
+		}
+	}()
+}
+
+type galleryClick struct {
+	s      SaveDef
+	update bool
+}
+
+func (gc galleryClick) OnClick(e *r.SyntheticMouseEvent) {
+	gc.s.saveToGallery(gc.update)
+	e.PreventDefault()
+}
+
+type saveFormatClick struct {
+	s SaveDef
+	f saveFormat
+}
+
+func (sf saveFormatClick) OnClick(e *r.SyntheticMouseEvent) {
+	sf.s.download(sf.f)
+	e.PreventDefault()
+}
+
+// saveOptionChange updates an option of a format from the value of the
+// select that changed.
+type saveOptionChange struct {
+	s     SaveDef
+	field string
+}
+
+func (sc saveOptionChange) OnChange(e *r.SyntheticEvent) {
+	v := e.Target().Underlying().Get("value").String()
+	st := sc.s.State()
+
+	switch sc.field {
+	case "pdfSize":
+		st.pdfSize, _ = strconv.Atoi(v)
+	case "pdfSheet":
+		st.pdfSheet = v
+	case "pdfMarks":
+		st.pdfMarks, _ = strconv.Atoi(v)
+	case "gifAnim":
+		st.gifAnim, _ = strconv.Atoi(v)
+	}
+
+	sc.s.SetState(st)
+}
+
+type closeSaveClick struct {
+	C CloseSave
+}
+
+func (cs closeSaveClick) OnClick(e *r.SyntheticMouseEvent) {
+	cs.C.CloseSave()
+	e.PreventDefault()
+}
@@ -0,0 +1,455 @@
+package main
+
+//go:generate reactGen
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/gopherjs/gopherjs/js"
+	"myitcv.io/gopherize.me/export"
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+	r "myitcv.io/react"
+)
+
+// SceneProps are the props of the Scene component, which composes several
+// gophers on a shared canvas, for banners and slide headers. Gophers are
+// added to the scene from the editor, and can be sent back to it to be
+// changed.
+type SceneProps struct {
+	Current *gopher.Gopher
+	Update  UpdateGopher
+}
+
+type SceneState struct {
+	scene *gopher.Scene
+
+	busy bool
+
+	// preview is the image source of the scene previewOf, once it has been
+	// drawn
+	preview   string
+	previewOf *gopher.Scene
+}
+
+type SceneDef struct {
+	r.ComponentDef
+}
+
+func Scene(p SceneProps) *SceneElem {
+	return buildSceneElem(p)
+}
+
+// sceneSizes are the canvas sizes offered for scenes.
+var sceneSizes = []struct {
+	name          string
+	width, height int
+}{
+	{"Banner 1500×500", 1500, 500},
+	{"Slide header 1920×400", 1920, 400},
+	{"Slide 1920×1080", 1920, 1080},
+	{"Square 1080×1080", 1080, 1080},
+}
+
+// scenePreviewWidth is the width in pixels of the preview of a scene.
+const scenePreviewWidth = 400
+
+// sceneStorageKey is the localStorage key under which the scene is kept, so
+// that it survives reloading the page.
+const sceneStorageKey = "gopherize.me/scene"
+
+func (s SceneDef) ComponentWillMount() {
+	sc := loadScene()
+	if sc == nil {
+		sc = &gopher.Scene{
+			Width:      sceneSizes[0].width,
+			Height:     sceneSizes[0].height,
+			Background: "#ffffff",
+		}
+	}
+
+	s.SetState(SceneState{scene: sc})
+	s.drawPreview(sc)
+}
+
+func (s SceneDef) Render() r.Element {
+	st := s.State()
+	sc := st.scene
+
+	var sizes []*r.OptionElem
+	for i, z := range sceneSizes {
+		sizes = append(sizes, r.Option(&r.OptionProps{Value: strconv.Itoa(i)}, r.S(z.name)))
+	}
+	size := 0
+	for i, z := range sceneSizes {
+		if z.width == sc.Width && z.height == sc.Height {
+			size = i
+		}
+	}
+
+	bg := sc.Background
+	if bg == "" {
+		bg = "#ffffff"
+	}
+
+	items := []r.RendersLi{
+		r.Li(&r.LiProps{ClassName: "list-group-item", Key: "canvas"},
+			r.Select(&r.SelectProps{Value: strconv.Itoa(size), OnChange: sceneChange{s: s, field: "canvas"}}, sizes...),
+			r.S(" Background "),
+			r.Input(&r.InputProps{Type: "color", Value: bg, OnChange: sceneChange{s: s, field: "background"}}),
+			r.Span(&r.SpanProps{ClassName: "pull-right"},
+				r.Button(
+					&r.ButtonProps{
+						ClassName: "btn btn-default btn-xs",
+						OnClick:   sceneClick{s: s, action: "transparent"},
+					},
+					r.S("Transparent"),
+				),
+			),
+		),
+	}
+
+	for i, p := range sc.Gophers {
+		slider := func(field string, v float64) r.Element {
+			return r.Input(&r.InputProps{
+				Type:     "range",
+				Value:    strconv.Itoa(int(math.Round(v))),
+				OnChange: sceneChange{s: s, field: field, i: i},
+			})
+		}
+
+		button := func(action, icon string) r.Element {
+			return r.Button(
+				&r.ButtonProps{
+					ClassName: "btn btn-default btn-xs",
+					OnClick:   sceneClick{s: s, action: action, i: i},
+				},
+				r.I(&r.IProps{ClassName: "glyphicon glyphicon-" + icon}),
+			)
+		}
+
+		items = append(items, r.Li(&r.LiProps{ClassName: "list-group-item scene-gopher", Key: strconv.Itoa(i)},
+			r.S("Gopher "+strconv.Itoa(i+1)),
+			r.Span(&r.SpanProps{ClassName: "pull-right"},
+				button("flip", "resize-horizontal"),
+				button("front", "arrow-up"),
+				button("edit", "pencil"),
+				button("remove", "trash"),
+			),
+			r.Div(nil, r.S("Across "), slider("x", 100*p.X/float64(sc.Width))),
+			r.Div(nil, r.S("Down "), slider("y", 100*p.Y/float64(sc.Height))),
+			r.Div(nil, r.S("Size "), slider("size", sceneSize(sc, p.Scale))),
+		))
+	}
+
+	var preview r.Element = r.S("")
+	switch {
+	case len(sc.Gophers) == 0:
+		preview = r.P(&r.PProps{ClassName: "text-muted"}, r.S("Add gophers from the editor to build a scene."))
+	case st.preview != "" && st.previewOf == sc:
+		preview = r.Img(&r.ImgProps{Src: st.preview, ClassName: "scene-preview"})
+	default:
+		preview = r.P(&r.PProps{ClassName: "text-muted"}, r.S("Drawing scene…"))
+	}
+
+	add := r.Button(
+		&r.ButtonProps{
+			ClassName: "btn btn-default",
+			OnClick:   sceneClick{s: s, action: "add"},
+		},
+		r.I(&r.IProps{ClassName: "glyphicon glyphicon-plus"}),
+		r.S(" Add this gopher"),
+	)
+
+	download := func(f sceneFormat) r.Element {
+		label := "Download " + f.name
+		if st.busy {
+			label = "Preparing…"
+		}
+
+		return r.Button(
+			&r.ButtonProps{
+				ClassName: "btn btn-default",
+				OnClick:   sceneDownloadClick{s: s, f: f},
+			},
+			r.I(&r.IProps{ClassName: "glyphicon glyphicon-download-alt"}),
+			r.S(" "+label),
+		)
+	}
+
+	body := []r.Element{preview, add}
+	if len(sc.Gophers) > 0 {
+		for _, f := range sceneFormats {
+			body = append(body, download(f))
+		}
+	}
+
+	return r.Div(&r.DivProps{ClassName: "panel panel-default", ID: "scene"},
+		r.Div(&r.DivProps{ClassName: "panel-heading"},
+			r.H4(&r.H4Props{ClassName: "panel-title"}, r.S("Scene")),
+		),
+		r.Div(&r.DivProps{ClassName: "panel-body"}, body...),
+		r.Ul(&r.UlProps{ClassName: "list-group"}, items...),
+	)
+}
+
+// sceneSize returns the value of the size slider for a gopher of the given
+// scale in sc: 50 when the gopher is as tall as the scene.
+func sceneSize(sc *gopher.Scene, scale float64) float64 {
+	if scale == 0 {
+		scale = 1
+	}
+	return 50 * scale * gopher.Height / float64(sc.Height)
+}
+
+// sceneScale is the inverse of sceneSize, clamped to the limits of scenes.
+func sceneScale(sc *gopher.Scene, size float64) float64 {
+	s := size / 50 * float64(sc.Height) / gopher.Height
+	return math.Max(gopher.MinSceneScale, math.Min(gopher.MaxSceneScale, s))
+}
+
+// setScene makes sc the scene, keeping it in localStorage and redrawing its
+// preview.
+func (s SceneDef) setScene(sc *gopher.Scene) {
+	st := s.State()
+	st.scene = sc
+	s.SetState(st)
+
+	storeScene(sc)
+	s.drawPreview(sc)
+}
+
+// drawPreview draws sc at the size of the preview in the background, showing
+// the result once it is ready if sc is still the scene.
+func (s SceneDef) drawPreview(sc *gopher.Scene) {
+	if len(sc.Gophers) == 0 {
+		return
+	}
+
+	k := float64(scenePreviewWidth) / float64(sc.Width)
+
+	small := sc.Copy()
+	small.Width = scenePreviewWidth
+	small.Height = int(math.Round(float64(sc.Height) * k))
+	for i := range small.Gophers {
+		p := &small.Gophers[i]
+		if p.Scale == 0 {
+			p.Scale = 1
+		}
+		p.X, p.Y, p.Scale = p.X*k, p.Y*k, p.Scale*k
+	}
+
+	go func() {
+		m, err := render.ComposeScene(small, siteArtwork)
+		if err != nil {
+			return
+		}
+
+		src := imageSrc(m)
+
+		st := s.State()
+		st.preview = src
+		st.previewOf = sc
+		s.SetState(st)
+	}()
+}
+
+// sceneChange updates the scene, or the gopher at index i of it, from the
+// value of the control that changed.
+type sceneChange struct {
+	s     SceneDef
+	field string
+	i     int
+}
+
+func (sc sceneChange) OnChange(e *r.SyntheticEvent) {
+	v := e.Target().Underlying().Get("value").String()
+	n, _ := strconv.ParseFloat(v, 64)
+
+	s := sc.s.State().scene.Copy()
+
+	switch sc.field {
+	case "canvas":
+		// keep the gophers in the same relative positions
+		z := sceneSizes[int(n)]
+		for i := range s.Gophers {
+			p := &s.Gophers[i]
+			p.X = p.X * float64(z.width) / float64(s.Width)
+			p.Y = p.Y * float64(z.height) / float64(s.Height)
+		}
+		s.Width, s.Height = z.width, z.height
+	case "background":
+		s.Background = v
+	case "x":
+		s.Gophers[sc.i].X = n / 100 * float64(s.Width)
+	case "y":
+		s.Gophers[sc.i].Y = n / 100 * float64(s.Height)
+	case "size":
+		s.Gophers[sc.i].Scale = sceneScale(s, n)
+	}
+
+	sc.s.setScene(s)
+}
+
+// sceneClick carries out an action on the scene, or on the gopher at index i
+// of it.
+type sceneClick struct {
+	s      SceneDef
+	action string
+	i      int
+}
+
+func (sc sceneClick) OnClick(e *r.SyntheticMouseEvent) {
+	e.PreventDefault()
+
+	s := sc.s.State().scene.Copy()
+
+	switch sc.action {
+	case "transparent":
+		s.Background = ""
+	case "add":
+		if len(s.Gophers) >= gopher.MaxSceneGophers {
+			js.Global.Call("alert", "A scene can have at most "+strconv.Itoa(gopher.MaxSceneGophers)+" gophers")
+			return
+		}
+		if s.NumUploads()+len(sc.s.Props().Current.Uploads) > gopher.MaxUploads {
+			js.Global.Call("alert", "A scene can have at most "+strconv.Itoa(gopher.MaxUploads)+" uploads across its gophers")
+			return
+		}
+
+		// place new gophers as tall as the scene, across it from the left
+		n := len(s.Gophers)
+		s.Gophers = append(s.Gophers, gopher.Placement{
+			Gopher: sc.s.Props().Current.Copy(),
+			X:      float64(s.Width) * (0.2 + 0.15*float64(n%5)),
+			Y:      float64(s.Height) / 2,
+			Scale:  sceneScale(s, 50),
+		})
+	case "flip":
+		s.Gophers[sc.i].FlipH = !s.Gophers[sc.i].FlipH
+	case "front":
+		p := s.Gophers[sc.i]
+		s.Gophers = append(append(s.Gophers[:sc.i], s.Gophers[sc.i+1:]...), p)
+	case "edit":
+		sc.s.Props().Update.OpenGopher(s.Gophers[sc.i].Gopher.Copy())
+		return
+	case "remove":
+		s.Gophers = append(s.Gophers[:sc.i], s.Gophers[sc.i+1:]...)
+	}
+
+	sc.s.setScene(s)
+}
+
+// sceneFormat is a format in which a scene can be downloaded.
+type sceneFormat struct {
+	name     string
+	file     string
+	mimeType string
+	write    func(w io.Writer, s *gopher.Scene) error
+}
+
+var sceneFormats = []sceneFormat{
+	{
+		name:     "PNG",
+		file:     "gopher-scene.png",
+		mimeType: "image/png",
+		write: func(w io.Writer, s *gopher.Scene) error {
+			m, err := render.ComposeScene(s, siteArtwork)
+			if err != nil {
+				return err
+			}
+			return export.ScenePNG(w, m, s)
+		},
+	},
+	{
+		name:     "SVG",
+		file:     "gopher-scene.svg",
+		mimeType: "image/svg+xml",
+		write: func(w io.Writer, s *gopher.Scene) error {
+			return export.SceneSVG(w, s, siteArtwork)
+		},
+	},
+	{
+		name:     "scene file",
+		file:     "gopher-scene.json",
+		mimeType: "application/json",
+		write: func(w io.Writer, s *gopher.Scene) error {
+			b, err := gopher.MarshalScene(s)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(b)
+			return err
+		},
+	},
+}
+
+type sceneDownloadClick struct {
+	s SceneDef
+	f sceneFormat
+}
+
+func (sd sceneDownloadClick) OnClick(e *r.SyntheticMouseEvent) {
+	e.PreventDefault()
+
+	s := sd.s
+	if s.State().busy {
+		return
+	}
+
+	st := s.State()
+	st.busy = true
+	s.SetState(st)
+
+	sc := st.scene
+
+	go func() {
+		defer func() {
+			st := s.State()
+			st.busy = false
+			s.SetState(st)
+		}()
+
+		var buf bytes.Buffer
+		if err := sd.f.write(&buf, sc); err != nil {
+			js.Global.Call("alert", "Failed to save scene: "+err.Error())
+			return
+		}
+
+		download(buf.Bytes(), sd.f.file, sd.f.mimeType)
+	}()
+}
+
+func storeScene(sc *gopher.Scene) {
+	defer func() {
+		// localStorage may be full or unavailable, in which case the scene
+		// is only available until the page is reloaded
+		recover()
+	}()
+
+	b, err := gopher.MarshalScene(sc)
+	if err != nil {
+		return
+	}
+
+	js.Global.Get("localStorage").Call("setItem", sceneStorageKey, string(b))
+}
+
+// loadScene returns the scene kept in localStorage, or nil if there is none
+// or it is no longer valid.
+func loadScene() *gopher.Scene {
+	v := js.Global.Get("localStorage").Call("getItem", sceneStorageKey)
+	if v == nil || v == js.Undefined {
+		return nil
+	}
+
+	sc, err := gopher.UnmarshalScene([]byte(v.String()), gopher.DefaultConfig)
+	if err != nil {
+		return nil
+	}
+
+	return sc
+}
@@ -0,0 +1,161 @@
+package main
+
+//go:generate reactGen
+
+import (
+	"strconv"
+
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+	r "myitcv.io/react"
+)
+
+// TextsProps are the props of the Texts component, which edits the text
+// layers of the current gopher, for example a name on its shirt.
+type TextsProps struct {
+	Current *gopher.Gopher
+	Update  UpdateGopher
+}
+
+type TextsDef struct {
+	r.ComponentDef
+}
+
+func Texts(p TextsProps) *TextsElem {
+	return buildTextsElem(p)
+}
+
+// textSizes are the sizes offered for a text; 0 sizes the text to fit.
+var textSizes = []int{0, 28, 42, 56, 84, 112, 140}
+
+func (t TextsDef) Render() r.Element {
+	props := t.Props()
+	g := props.Current
+
+	var elems []r.Element
+
+	for _, tx := range g.Texts {
+		field := func(f string) textChange {
+			return textChange{U: props.Update, t: tx, field: f}
+		}
+
+		var fonts, positions, sizes []*r.OptionElem
+		for _, f := range gopher.Fonts {
+			fonts = append(fonts, r.Option(&r.OptionProps{Value: f}, r.S(f)))
+		}
+		for _, p := range gopher.Positions {
+			positions = append(positions, r.Option(&r.OptionProps{Value: p}, r.S(p)))
+		}
+		for _, s := range textSizes {
+			label := "fit"
+			if s != 0 {
+				label = strconv.Itoa(s) + "px"
+			}
+			sizes = append(sizes, r.Option(&r.OptionProps{Value: strconv.Itoa(s)}, r.S(label)))
+		}
+
+		colour := tx.Colour
+		if colour == "" {
+			colour = "#000000"
+		}
+
+		elems = append(elems, r.Div(&r.DivProps{ClassName: "text-layer"},
+			r.Input(&r.InputProps{
+				Type:        "text",
+				ClassName:   "form-control",
+				Placeholder: "Your name",
+				Value:       tx.Text,
+				OnChange:    field("text"),
+			}),
+			r.Select(&r.SelectProps{Value: tx.FontName(), OnChange: field("font")}, fonts...),
+			r.Select(&r.SelectProps{Value: tx.PositionName(), OnChange: field("position")}, positions...),
+			r.Select(&r.SelectProps{Value: strconv.Itoa(tx.Size), OnChange: field("size")}, sizes...),
+			r.Input(&r.InputProps{Type: "color", Value: colour, OnChange: field("colour")}),
+			r.Button(
+				&r.ButtonProps{
+					ClassName: "btn btn-default btn-xs",
+					OnClick:   removePartClick{U: props.Update, p: tx.Part()},
+				},
+				r.I(&r.IProps{ClassName: "glyphicon glyphicon-trash"}),
+			),
+		))
+	}
+
+	elems = append(elems, r.Button(
+		&r.ButtonProps{
+			ClassName: "btn btn-default btn-sm",
+			OnClick:   addTextClick{U: props.Update},
+		},
+		r.I(&r.IProps{ClassName: "glyphicon glyphicon-font"}),
+		r.S(" Add text"),
+	))
+
+	return r.Div(&r.DivProps{ClassName: "panel panel-default"},
+		r.Div(&r.DivProps{ClassName: "panel-heading"},
+			r.H4(&r.H4Props{ClassName: "panel-title"}, r.S("Text")),
+		),
+		r.Div(&r.DivProps{ClassName: "panel-body", ID: "texts"}, elems...),
+	)
+}
+
+type addTextClick struct {
+	U UpdateGopher
+}
+
+func (a addTextClick) OnClick(e *r.SyntheticMouseEvent) {
+	a.U.SetText(gopher.Text{Text: "Gopher"})
+	e.PreventDefault()
+}
+
+// textChange updates a field of the text t from the value of the input that
+// changed.
+type textChange struct {
+	U     UpdateGopher
+	t     gopher.Text
+	field string
+}
+
+func (tc textChange) OnChange(e *r.SyntheticEvent) {
+	v := e.Target().Underlying().Get("value").String()
+	t := tc.t
+
+	switch tc.field {
+	case "text":
+		t.Text = v
+	case "font":
+		t.Font = v
+	case "position":
+		t.Position = v
+	case "colour":
+		t.Colour = v
+	case "size":
+		t.Size, _ = strconv.Atoi(v)
+	}
+
+	if t.Validate() != nil {
+		return
+	}
+
+	tc.U.SetText(t)
+}
+
+// textSrcs caches the image sources of text layers, which are drawn by the
+// same Go code that draws them elsewhere.
+var textSrcs = make(map[gopher.Text]string)
+
+// textSrc returns the image source of the text layer t.
+func textSrc(t gopher.Text) string {
+	if src, ok := textSrcs[t]; ok {
+		return src
+	}
+
+	src := imageSrc(render.Text(t))
+
+	// texts change with every key press, so keep the cache small
+	if len(textSrcs) > 20 {
+		textSrcs = make(map[gopher.Text]string)
+	}
+	textSrcs[t] = src
+
+	return src
+}
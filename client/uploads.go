@@ -0,0 +1,197 @@
+package main
+
+//go:generate reactGen
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopherjs/gopherjs/js"
+	"myitcv.io/gopherize.me/gopher"
+	r "myitcv.io/react"
+)
+
+// UploadsProps are the props of the Uploads component, which allows the user
+// to add their own PNG images to the current gopher as additional layers.
+type UploadsProps struct {
+	Current *gopher.Gopher
+	Update  UpdateGopher
+}
+
+type UploadsDef struct {
+	r.ComponentDef
+}
+
+func Uploads(p UploadsProps) *UploadsElem {
+	return buildUploadsElem(p)
+}
+
+func (u UploadsDef) Render() r.Element {
+	props := u.Props()
+	g := props.Current
+
+	elems := []r.Element{
+		r.Input(&r.InputProps{
+			Type:     "file",
+			ID:       "upload-input",
+			OnChange: uploadChange{G: g, U: props.Update},
+		}),
+	}
+
+	for _, up := range g.Uploads {
+		elems = append(elems, transformControls(props.Update, g, up.Part(), true))
+	}
+
+	return r.Div(&r.DivProps{ClassName: "panel panel-default"},
+		r.Div(&r.DivProps{ClassName: "panel-heading"},
+			r.H4(&r.H4Props{ClassName: "panel-title"}, r.S("Your own artwork")),
+		),
+		r.Div(&r.DivProps{ClassName: "panel-body", ID: "uploads"}, elems...),
+	)
+}
+
+type uploadChange struct {
+	G *gopher.Gopher
+	U UpdateGopher
+}
+
+func (uc uploadChange) OnChange(e *r.SyntheticEvent) {
+	in := e.Target().Underlying()
+
+	files := in.Get("files")
+	if files.Length() == 0 {
+		return
+	}
+	f := files.Index(0)
+	name := f.Get("name").String()
+
+	// allow the same file to be chosen again
+	in.Set("value", "")
+
+	fr := js.Global.Get("FileReader").New()
+	fr.Set("onload", func() {
+		img := js.Global.Get("Image").New()
+		img.Set("onload", func() {
+			data, err := uploadLayer(img)
+			if err != nil {
+				js.Global.Call("alert", err.Error())
+				return
+			}
+
+			up := gopher.Upload{
+				ID:   gopher.UploadID(data),
+				Name: name,
+				Data: data,
+			}
+
+			if _, ok := uc.G.Upload(up.Part()); !ok && len(uc.G.Uploads) >= gopher.MaxUploads {
+				js.Global.Call("alert", fmt.Sprintf("A gopher can have at most %v uploads; remove one to add %v", gopher.MaxUploads, name))
+				return
+			}
+
+			storeUpload(up)
+
+			uc.U.AddUpload(up)
+		})
+		img.Set("onerror", func() {
+			js.Global.Call("alert", name+" is not an image that can be used")
+		})
+		img.Set("src", fr.Get("result"))
+	})
+	fr.Call("readAsDataURL", f)
+}
+
+// uploadLayer draws the loaded image img onto a transparent canvas the size of
+// the catalogue artwork, scaled to fit as described by gopher.FitUpload, and
+// returns the resulting PNG data.
+func uploadLayer(img *js.Object) ([]byte, error) {
+	c := js.Global.Get("document").Call("createElement", "canvas")
+	c.Set("width", gopher.Width)
+	c.Set("height", gopher.Height)
+
+	x, y, w, h := gopher.FitUpload(img.Get("naturalWidth").Int(), img.Get("naturalHeight").Int())
+	c.Call("getContext", "2d").Call("drawImage", img, x, y, w, h)
+
+	uri := c.Call("toDataURL", "image/png").String()
+
+	data, err := base64.StdEncoding.DecodeString(uri[strings.Index(uri, ",")+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := (gopher.Upload{ID: gopher.UploadID(data), Data: data}).Validate(); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// uploadStoragePrefix is the prefix of the localStorage keys under which the
+// data of uploads is kept. Uploads are far too large to be included in the
+// recipe held in the URL.
+const uploadStoragePrefix = "gopherize.me/upload/"
+
+func storeUpload(u gopher.Upload) {
+	defer func() {
+		// localStorage may be full or unavailable, in which case the upload
+		// is only available until the page is reloaded
+		recover()
+	}()
+
+	js.Global.Get("localStorage").Call("setItem", uploadStoragePrefix+u.ID, base64.StdEncoding.EncodeToString(u.Data))
+}
+
+// loadUploads fills in the data of the uploads of g from localStorage, removing
+// any whose data cannot be found.
+func loadUploads(g *gopher.Gopher) {
+	var missing []string
+
+	for i, u := range g.Uploads {
+		if u.Data != nil {
+			continue
+		}
+
+		v := js.Global.Get("localStorage").Call("getItem", uploadStoragePrefix+u.ID)
+		if v == nil || v == js.Undefined {
+			missing = append(missing, u.Part())
+			continue
+		}
+
+		data, err := base64.StdEncoding.DecodeString(v.String())
+		if err != nil || gopher.UploadID(data) != u.ID {
+			missing = append(missing, u.Part())
+			continue
+		}
+
+		g.Uploads[i].Data = data
+	}
+
+	for _, p := range missing {
+		g.Remove(p)
+	}
+}
+
+// layerSrc returns the image source for the part p of g.
+func layerSrc(g *gopher.Gopher, p string) string {
+	if u, ok := g.Upload(p); ok {
+		return dataURI(u)
+	}
+	if t, ok := g.Text(p); ok {
+		return textSrc(t)
+	}
+	return filepath.Join("artwork", p+".png")
+}
+
+// thumbnailSrc returns the image source for a thumbnail of the part p of g.
+func thumbnailSrc(g *gopher.Gopher, p string) string {
+	if gopher.IsUpload(p) || gopher.IsText(p) {
+		return layerSrc(g, p)
+	}
+	return filepath.Join("artwork", p+"_thumbnail.png")
+}
+
+func dataURI(u gopher.Upload) string {
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(u.Data)
+}
@@ -0,0 +1,479 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// gopherize-dev serves the client during development, rebuilding it as its
+// sources change and reloading the pages open on it.
+//
+// Usage:
+//
+//	gopherize-dev [-addr :8080] [-gopherjs cmd]
+//
+// It watches the client and artwork directories. When a Go file of the
+// client changes, it runs go generate in the client directory, which runs
+// reactGen, and compiles the client with GopherJS. When images are added to
+// or removed from the artwork, it first runs go generate in the gopher
+// directory, which writes the catalogue. Other changes, to the page, its
+// stylesheets or the images themselves, need no build. Either way, the open
+// pages are told to reload over server-sent events; if the build fails, they
+// show why instead. reactGen and gopherjs must be on PATH, and the client
+// must be in GOPATH.
+//
+// The gallery API is served too, with gophers kept in memory. It uses the
+// catalogue with which gopherize-dev was built, so a restart is needed for
+// it to accept new options.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"myitcv.io/gopherize.me/render"
+	"myitcv.io/gopherize.me/server"
+	"myitcv.io/gopherize.me/site"
+	"myitcv.io/gopherize.me/store"
+)
+
+var (
+	fAddr     = flag.String("addr", ":8080", "the address on which to listen")
+	fGopherJS = flag.String("gopherjs", "gopherjs", "the gopherjs command")
+)
+
+// eventsPath is the path of the server-sent events that reload the page.
+const eventsPath = "/_dev/events"
+
+// settle is how long to wait after a change for any that follow it, such as
+// the other files written by an editor or a checkout, before acting.
+const settle = 200 * time.Millisecond
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gopherize-dev [flags]\n\nflags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "gopherize-dev: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	p, err := build.Import(site.ClientPackage, "", build.FindOnly)
+	if err != nil {
+		return fmt.Errorf("failed to locate client: %v", err)
+	}
+
+	art, err := filepath.EvalSymlinks(filepath.Join(p.Dir, "..", "artwork"))
+	if err != nil {
+		return fmt.Errorf("failed to locate artwork: %v", err)
+	}
+
+	d := &dev{
+		client:  p.Dir,
+		gopher:  filepath.Join(p.Dir, "..", "gopher"),
+		artwork: art,
+		store:   store.NewMemory(),
+		start:   time.Now(),
+		clients: make(map[chan string]bool),
+	}
+	d.api = server.New(d.store, render.NewDir(d.artwork))
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	for _, dir := range []string{d.client, d.artwork} {
+		if err := watchDirs(w, dir, dir == d.client); err != nil {
+			return err
+		}
+	}
+
+	d.build(false)
+	go d.watch(w)
+
+	static := site.Handler(http.Dir(d.client))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" || r.URL.Path == "/"+site.Page {
+			d.page(w, r)
+			return
+		}
+		static.ServeHTTP(noCache{w}, r)
+	})
+	mux.HandleFunc("/"+site.ClientJS, d.clientJS)
+	mux.HandleFunc(eventsPath, d.events)
+	for _, p := range []string{"/api/", "/teams/", "/catalogue"} {
+		mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+			d.mu.Lock()
+			api := d.api
+			d.mu.Unlock()
+			api.ServeHTTP(w, r)
+		})
+	}
+
+	log.Printf("serving %v on %v", d.client, *fAddr)
+
+	return http.ListenAndServe(*fAddr, mux)
+}
+
+// dev is the state of the development server.
+type dev struct {
+	client  string
+	gopher  string
+	artwork string
+	store   store.Store
+	start   time.Time
+
+	mu sync.Mutex
+
+	// js is the client as last compiled successfully, and err the reason
+	// the last build failed, if it did
+	js  []byte
+	err error
+
+	// builds counts the changes served, which with start gives the
+	// version of the site reported to pages
+	builds int
+
+	// api serves the gallery, with artwork that is replaced when it
+	// changes
+	api http.Handler
+
+	// clients are the channels of the pages listening for events
+	clients map[chan string]bool
+}
+
+// watchDirs watches dir and, unless top is set, the directories below it;
+// the client directory is watched along with inc.
+func watchDirs(w *fsnotify.Watcher, dir string, top bool) error {
+	if top {
+		if err := w.Add(dir); err != nil {
+			return err
+		}
+		dir = filepath.Join(dir, "inc")
+	}
+
+	return filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.IsDir() {
+			return err
+		}
+		return w.Add(p)
+	})
+}
+
+// change is what needs doing after the sources change.
+type change struct {
+	catalogue bool
+	compile   bool
+	artwork   bool
+	reload    bool
+}
+
+// watch acts on the events of w, once they settle.
+func (d *dev) watch(w *fsnotify.Watcher) {
+	var c change
+	timer := time.NewTimer(settle)
+	timer.Stop()
+
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if d.classify(w, ev, &c) {
+				timer.Reset(settle)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("watch: %v", err)
+		case <-timer.C:
+			d.act(c)
+			c = change{}
+		}
+	}
+}
+
+// classify records in c what the event ev requires, reporting whether it
+// requires anything.
+func (d *dev) classify(w *fsnotify.Watcher, ev fsnotify.Event, c *change) bool {
+	name := filepath.Base(ev.Name)
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+		// editor and version control files
+		return false
+	}
+
+	added := ev.Op&(fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0
+
+	if strings.HasPrefix(ev.Name, d.artwork+string(filepath.Separator)) {
+		if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+			if err := watchDirs(w, ev.Name, false); err != nil {
+				log.Printf("watch: %v", err)
+			}
+		}
+
+		c.artwork = true
+		c.reload = true
+		if added {
+			c.catalogue = true
+		}
+		return true
+	}
+
+	switch {
+	case strings.HasPrefix(name, "gen_"):
+		// written by reactGen
+		return false
+	case strings.HasSuffix(name, ".go"):
+		c.compile = true
+	}
+	c.reload = true
+
+	return true
+}
+
+// act does what c requires, then tells the open pages.
+func (d *dev) act(c change) {
+	if c.artwork {
+		d.mu.Lock()
+		d.api = server.New(d.store, render.NewDir(d.artwork))
+		d.mu.Unlock()
+	}
+
+	if c.catalogue || c.compile {
+		d.build(c.catalogue)
+		return
+	}
+
+	if c.reload {
+		d.mu.Lock()
+		d.builds++
+		d.mu.Unlock()
+		d.notify()
+	}
+}
+
+// build writes the catalogue, if catalogue is set, then generates and
+// compiles the client, and tells the open pages.
+func (d *dev) build(catalogue bool) {
+	start := time.Now()
+
+	var js []byte
+	var err error
+
+	if catalogue {
+		err = generate(d.gopher)
+	}
+	if err == nil {
+		err = generate(d.client)
+	}
+	if err == nil {
+		js, err = site.Compile(*fGopherJS, false)
+	}
+
+	d.mu.Lock()
+	d.err = err
+	if err == nil {
+		d.js = js
+		d.builds++
+	}
+	d.mu.Unlock()
+
+	if err != nil {
+		log.Printf("build failed: %v", err)
+	} else {
+		log.Printf("built client in %v", time.Since(start).Round(time.Millisecond))
+	}
+
+	d.notify()
+}
+
+// generate runs go generate in dir.
+func generate(dir string) error {
+	cmd := exec.Command("go", "generate")
+	cmd.Dir = dir
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("go generate in %v failed: %v\n%s", dir, err, out)
+	}
+	return nil
+}
+
+// message returns the event that tells a page the state of the site: the
+// failure of its build, or else its version.
+func (d *dev) message() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.err != nil {
+		return event("failed", strings.TrimSpace(d.err.Error()))
+	}
+	return event("version", fmt.Sprintf("%v-%v", d.start.UnixNano(), d.builds))
+}
+
+// event formats a server-sent event.
+func event(name, data string) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "event: %v\n", name)
+	for _, l := range strings.Split(data, "\n") {
+		fmt.Fprintf(&buf, "data: %v\n", l)
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+// notify tells the open pages the state of the site.
+func (d *dev) notify() {
+	m := d.message()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for ch := range d.clients {
+		select {
+		case ch <- m:
+		default:
+			// the page has yet to read the last event
+		}
+	}
+}
+
+func (d *dev) events(w http.ResponseWriter, r *http.Request) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan string, 8)
+
+	d.mu.Lock()
+	d.clients[ch] = true
+	d.mu.Unlock()
+
+	defer func() {
+		d.mu.Lock()
+		delete(d.clients, ch)
+		d.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	fmt.Fprint(w, d.message())
+	f.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case m := <-ch:
+			fmt.Fprint(w, m)
+			f.Flush()
+		}
+	}
+}
+
+// reloadScript listens for the events of the server, reloading the page
+// when the version of the site changes and showing why a build failed.
+const reloadScript = `<script>
+(function() {
+	var version, failed;
+	var es = new EventSource("` + eventsPath + `");
+	es.addEventListener("version", function(e) {
+		if ((version !== undefined || failed) && version !== e.data) {
+			location.reload();
+		}
+		version = e.data;
+		failed = false;
+		var pre = document.getElementById("gopherize-dev-error");
+		if (pre) {
+			pre.parentNode.removeChild(pre);
+		}
+	});
+	es.addEventListener("failed", function(e) {
+		failed = true;
+		var pre = document.getElementById("gopherize-dev-error");
+		if (!pre) {
+			pre = document.createElement("pre");
+			pre.id = "gopherize-dev-error";
+			pre.style.cssText = "position: fixed; top: 0; left: 0; right: 0; max-height: 50%; overflow: auto; margin: 0; padding: 1em; z-index: 10000; background: #fee; color: #a00;";
+			document.body.appendChild(pre);
+		}
+		pre.textContent = e.data;
+	});
+})();
+</script>
+`
+
+// page serves the page of the client, with reloadScript added.
+func (d *dev) page(w http.ResponseWriter, r *http.Request) {
+	b, err := ioutil.ReadFile(filepath.Join(d.client, site.Page))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if i := bytes.LastIndex(b, []byte("</body>")); i != -1 {
+		b = append(b[:i:i], append([]byte(reloadScript), b[i:]...)...)
+	} else {
+		b = append(b, reloadScript...)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(b)
+}
+
+// clientJS serves the client as last compiled successfully.
+func (d *dev) clientJS(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	js := d.js
+	d.mu.Unlock()
+
+	if js == nil {
+		msg, _ := json.Marshal("the client has not been built")
+		js = []byte("console.error(" + string(msg) + ");\n")
+	}
+
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(js)
+}
+
+// noCache is a ResponseWriter that stops the response being cached, so that
+// pages always load the latest files.
+type noCache struct {
+	http.ResponseWriter
+}
+
+func (n noCache) WriteHeader(code int) {
+	n.Header().Set("Cache-Control", "no-cache")
+	n.ResponseWriter.WriteHeader(code)
+}
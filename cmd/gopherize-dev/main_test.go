@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestClassify(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "gopherize-dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	d := &dev{
+		client:  filepath.Join(tmp, "client"),
+		artwork: filepath.Join(tmp, "artwork"),
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	tests := []struct {
+		name string
+		op   fsnotify.Op
+		want bool
+		c    change
+	}{
+		{"client/main.go", fsnotify.Write, true, change{compile: true, reload: true}},
+		{"client/main.go", fsnotify.Create, true, change{compile: true, reload: true}},
+		{"client/client.css", fsnotify.Write, true, change{reload: true}},
+		{"client/index.html", fsnotify.Write, true, change{reload: true}},
+		{"client/inc/bootstrap/bootstrap.min.css", fsnotify.Write, true, change{reload: true}},
+		{"client/gen_Chooser_reactGen.go", fsnotify.Write, false, change{}},
+		{"client/.main.go.swp", fsnotify.Write, false, change{}},
+		{"client/main.go~", fsnotify.Write, false, change{}},
+		{"artwork/010-Body/blue_gopher.png", fsnotify.Write, true, change{artwork: true, reload: true}},
+		{"artwork/010-Body/new.png", fsnotify.Create, true, change{catalogue: true, artwork: true, reload: true}},
+		{"artwork/010-Body/old.png", fsnotify.Remove, true, change{catalogue: true, artwork: true, reload: true}},
+		{"artwork/010-Body/old.png", fsnotify.Rename, true, change{catalogue: true, artwork: true, reload: true}},
+		{"artwork/010-Body/.DS_Store", fsnotify.Create, false, change{}},
+		{"artwork-old/x.png", fsnotify.Create, true, change{reload: true}},
+	}
+
+	for _, test := range tests {
+		var c change
+		ev := fsnotify.Event{Name: filepath.Join(tmp, filepath.FromSlash(test.name)), Op: test.op}
+
+		if got := d.classify(w, ev, &c); got != test.want || c != test.c {
+			t.Errorf("classify(%v %v) = %v, %+v; want %v, %+v", test.op, test.name, got, c, test.want, test.c)
+		}
+	}
+}
+
+func TestClassifyNewArtworkDir(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "gopherize-dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	d := &dev{
+		client:  filepath.Join(tmp, "client"),
+		artwork: filepath.Join(tmp, "artwork"),
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	cat := filepath.Join(d.artwork, "100-New")
+	if err := os.MkdirAll(cat, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	var c change
+	if !d.classify(w, fsnotify.Event{Name: cat, Op: fsnotify.Create}, &c) || !c.catalogue {
+		t.Fatalf("classify of a new category gave %+v; want a catalogue", c)
+	}
+
+	// the new category is watched
+	p := filepath.Join(cat, "hat.png")
+	if err := ioutil.WriteFile(p, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-w.Events:
+		if ev.Name != p {
+			t.Errorf("got event for %v; want %v", ev.Name, p)
+		}
+	case err := <-w.Errors:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Errorf("no event for a file added to a new category")
+	}
+}
+
+func TestEvent(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"version", "1-2", "event: version\ndata: 1-2\n\n"},
+		{"failed", "", "event: failed\ndata: \n\n"},
+		{"failed", "go generate failed\nmain.go:1: oops", "event: failed\ndata: go generate failed\ndata: main.go:1: oops\n\n"},
+	}
+
+	for _, test := range tests {
+		if got := event(test.name, test.data); got != test.want {
+			t.Errorf("event(%q, %q) = %q; want %q", test.name, test.data, got, test.want)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	d := &dev{start: time.Unix(0, 42), builds: 3}
+
+	if got, want := d.message(), event("version", "42-3"); got != want {
+		t.Errorf("message = %q; want %q", got, want)
+	}
+
+	d.err = errors.New("build failed\n")
+	if got, want := d.message(), event("failed", "build failed"); got != want {
+		t.Errorf("message of a failed build = %q; want %q", got, want)
+	}
+}
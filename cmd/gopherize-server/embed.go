@@ -0,0 +1,90 @@
+//go:build embedsite
+// +build embedsite
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// embedded returns the site embedded in the binary, if any.
+func embedded() (http.FileSystem, bool) {
+	dirs := make(map[string]bool)
+	for name := range siteFiles {
+		for d := path.Dir(name); d != "."; d = path.Dir(d) {
+			dirs[d] = true
+		}
+	}
+
+	return embeddedFS{dirs: dirs}, true
+}
+
+// embeddedFS is the http.FileSystem of the files of siteFiles and of the
+// directories that hold them.
+type embeddedFS struct {
+	dirs map[string]bool
+}
+
+func (e embeddedFS) Open(name string) (http.File, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+
+	if data, ok := siteFiles[name]; ok {
+		return &embeddedFile{
+			Reader: strings.NewReader(data),
+			fi:     embeddedFileInfo{name: path.Base(name), size: int64(len(data))},
+		}, nil
+	}
+
+	if name == "" || e.dirs[name] {
+		return &embeddedFile{
+			Reader: strings.NewReader(""),
+			fi:     embeddedFileInfo{name: path.Base("/" + name), dir: true},
+		}, nil
+	}
+
+	return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
+
+type embeddedFile struct {
+	*strings.Reader
+	fi embeddedFileInfo
+}
+
+func (f *embeddedFile) Close() error {
+	return nil
+}
+
+func (f *embeddedFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, errors.New("listing embedded directories is not supported")
+}
+
+func (f *embeddedFile) Stat() (os.FileInfo, error) {
+	return f.fi, nil
+}
+
+// embeddedFileInfo describes a file of siteFiles. Embedded files have no
+// modification time, so that site_files.go is the same whatever the times
+// of the files of the site; they are revalidated by their ETags.
+type embeddedFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (fi embeddedFileInfo) Name() string       { return fi.name }
+func (fi embeddedFileInfo) Size() int64        { return fi.size }
+func (fi embeddedFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi embeddedFileInfo) IsDir() bool        { return fi.dir }
+func (fi embeddedFileInfo) Sys() interface{}   { return nil }
+
+func (fi embeddedFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// gopherize-server serves the gallery of saved gophers; see package server
+// for its API. It also serves the site itself, when one is embedded or given
+// by -dir.
+//
+// Usage:
+//
+//	gopherize-server [-addr :8080] [-store kind:path] [-artwork dir] [-dir dir]
+//
+// Gophers are kept in the store given by -store: dir:path for a directory
+// of files, log:path for a single append-only file, or memory, the default,
+// in which case they are lost when the server stops.
+//
+// A single binary that serves everything, with no need of the repository,
+// embeds a site built by gopherize-site, from which mksite.go writes
+// site_files.go:
+//
+//	cd cmd/gopherize-server
+//	gopherize-site -o _site
+//	go run mksite.go
+//	go build -tags embedsite
+//
+// The flag -dir serves the site in a directory instead, such as one built by
+// gopherize-site or, during development, the client directory. Unless -artwork
+// is given, gophers are drawn with the artwork of the site served, if any.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"myitcv.io/gopherize.me/render"
+	"myitcv.io/gopherize.me/server"
+	"myitcv.io/gopherize.me/site"
+	"myitcv.io/gopherize.me/store"
+)
+
+// The timeouts of the server. Requests may carry recipes of several
+// megabytes, and responses may take a few seconds to render.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 60 * time.Second
+	writeTimeout      = 60 * time.Second
+	idleTimeout       = 120 * time.Second
+)
+
+var (
+	fAddr    = flag.String("addr", ":8080", "the address on which to listen")
+	fStore   = flag.String("store", "memory", "where to keep gophers: memory, dir:path or log:path")
+	fArtwork = flag.String("artwork", "", "the artwork directory (default: that of the site, or of myitcv.io/gopherize.me in GOPATH)")
+	fDir     = flag.String("dir", "", "the directory of the site to serve (default: the site embedded, if any)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gopherize-server [flags]\n\nflags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "gopherize-server: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	siteFS, ok := embedded()
+	if *fDir != "" {
+		if fi, err := os.Stat(*fDir); err != nil || !fi.IsDir() {
+			return fmt.Errorf("site directory %v not found", *fDir)
+		}
+		siteFS, ok = http.Dir(*fDir), true
+	}
+
+	art, err := artwork(siteFS)
+	if err != nil {
+		return err
+	}
+
+	st, err := openStore(*fStore)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	var h http.Handler = server.New(st, art)
+	if ok {
+		mux := http.NewServeMux()
+		mux.Handle("/api/", h)
+		mux.Handle("/teams/", h)
+		mux.Handle("/catalogue", h)
+		mux.Handle("/", site.Handler(siteFS))
+		h = mux
+
+		log.Printf("serving site and gallery on %v", *fAddr)
+	} else {
+		log.Printf("serving gallery on %v", *fAddr)
+	}
+
+	srv := &http.Server{
+		Addr:              *fAddr,
+		Handler:           h,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	return srv.ListenAndServe()
+}
+
+// artwork returns the artwork named by the -artwork flag, or else that of
+// the site in siteFS, if not nil, or else that of this repository.
+func artwork(siteFS http.FileSystem) (render.Artwork, error) {
+	dir := *fArtwork
+
+	if dir == "" && siteFS != nil && isDir(siteFS, "/artwork") {
+		return render.NewDirFunc(func(name string) (io.ReadCloser, error) {
+			return siteFS.Open("/artwork/" + name)
+		}), nil
+	}
+
+	if dir == "" {
+		p, err := build.Import("myitcv.io/gopherize.me/gopher", "", build.FindOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate artwork; use -artwork: %v", err)
+		}
+		dir = filepath.Join(p.Dir, "..", "artwork")
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return nil, fmt.Errorf("artwork directory %v not found", dir)
+	}
+
+	return render.NewDir(dir), nil
+}
+
+// isDir reports whether name is a directory of fs.
+func isDir(fs http.FileSystem, name string) bool {
+	f, err := fs.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	return err == nil && fi.IsDir()
+}
+
+// openStore opens the store described by the -store flag v.
+func openStore(v string) (store.Store, error) {
+	kind, path := v, ""
+	if i := strings.IndexByte(v, ':'); i != -1 {
+		kind, path = v[:i], v[i+1:]
+	}
+
+	switch {
+	case kind == "memory" && path == "":
+		return store.NewMemory(), nil
+	case kind == "dir" && path != "":
+		return store.OpenDir(path)
+	case kind == "log" && path != "":
+		return store.OpenLog(path)
+	}
+
+	return nil, fmt.Errorf("invalid store %q; expected memory, dir:path or log:path", v)
+}
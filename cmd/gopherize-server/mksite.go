@@ -0,0 +1,84 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+//go:build ignore
+// +build ignore
+
+// mksite writes site_files.go, which embeds in gopherize-server, when built
+// with the embedsite tag, the site built into _site by gopherize-site.
+//
+// Each regular file of the site becomes an entry of siteFiles, its contents
+// keyed by its slash separated name relative to the root of the site. The
+// modification times of the files are left out, so that the same site gives
+// the same site_files.go.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var (
+	fSite = flag.String("site", "_site", "the directory of the site to embed")
+	fOut  = flag.String("o", "site_files.go", "the file to write")
+)
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "mksite: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if fi, err := os.Stat(*fSite); err != nil || !fi.IsDir() {
+		return fmt.Errorf("site directory %v not found; build one with gopherize-site -o %v", *fSite, *fSite)
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "// Code generated by mksite.go from the site in %v. DO NOT EDIT.\n\n", *fSite)
+	fmt.Fprintf(&buf, "//go:build embedsite\n// +build embedsite\n\n")
+	fmt.Fprintf(&buf, "package main\n\n")
+	fmt.Fprintf(&buf, "var siteFiles = map[string]string{\n")
+
+	// Walk visits files in lexical order, so entries are sorted by name
+	err := filepath.Walk(*fSite, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+
+		rel, err := filepath.Rel(*fSite, p)
+		if err != nil {
+			return err
+		}
+
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&buf, "%q: %q,\n", filepath.ToSlash(rel), b)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(&buf, "}\n")
+
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format site files: %v", err)
+	}
+
+	return ioutil.WriteFile(*fOut, b, 0666)
+}
@@ -0,0 +1,12 @@
+//go:build !embedsite
+// +build !embedsite
+
+package main
+
+import "net/http"
+
+// embedded returns the site embedded in the binary, if any; build with the
+// embedsite tag to embed one.
+func embedded() (http.FileSystem, bool) {
+	return nil, false
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// gopherize-site builds the static site of gopherize.me, ready to be
+// published; see package site.
+//
+// Usage:
+//
+//	gopherize-site [-o dir] [-gopherjs cmd] [-minify=false] [-js file]
+//
+// The client is compiled with GopherJS, which must be able to find the
+// client and its dependencies in GOPATH, unless -js gives the compiled
+// client. The site is written to the directory given by -o, which must
+// either not exist, be empty but for hidden files such as .git, or hold a
+// site previously built by gopherize-site.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"myitcv.io/gopherize.me/site"
+)
+
+var (
+	fOut      = flag.String("o", "_site", "the directory to write")
+	fGopherJS = flag.String("gopherjs", "gopherjs", "the gopherjs command")
+	fMinify   = flag.Bool("minify", true, "minify the compiled client")
+	fJS       = flag.String("js", "", "the compiled client to use, instead of compiling it")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gopherize-site [flags]\n\nflags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "gopherize-site: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	p, err := build.Import(site.ClientPackage, "", build.FindOnly)
+	if err != nil {
+		return fmt.Errorf("failed to locate client: %v", err)
+	}
+
+	var js []byte
+	if *fJS != "" {
+		js, err = ioutil.ReadFile(*fJS)
+	} else {
+		js, err = site.Compile(*fGopherJS, *fMinify)
+	}
+	if err != nil {
+		return err
+	}
+
+	m, err := site.Build(*fOut, site.Options{
+		Client:  p.Dir,
+		Artwork: filepath.Join(p.Dir, "..", "artwork"),
+		JS:      js,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %v, with %v fingerprinted assets\n", *fOut, len(m))
+
+	return nil
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"myitcv.io/gopherize.me/export"
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+)
+
+var cmdBatch = &command{
+	name:  "batch",
+	args:  "roster.csv",
+	short: "write a PNG of a gopher for each person of a roster, with an index.html",
+	flags: func(fs *flag.FlagSet) func(args []string) error {
+		art := artworkFlag(fs)
+		out := fs.String("o", "gophers", "the directory to write")
+		width := fs.Int("width", 512, "the width of each PNG in pixels")
+		jobs := fs.Int("j", runtime.NumCPU(), "the number of gophers to draw at once")
+		label := fs.String("label", "", "draw each name on its gopher, at one of: "+strings.Join(gopher.Positions, ", "))
+
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usageError("expected a single roster")
+			}
+			if *width < 1 {
+				return usageError(fmt.Sprintf("invalid -width %v", *width))
+			}
+			if *jobs < 1 {
+				return usageError(fmt.Sprintf("invalid -j %v", *jobs))
+			}
+			if *label != "" && !isPosition(*label) {
+				return usageError(fmt.Sprintf("invalid -label %q", *label))
+			}
+
+			rows, err := readRoster(args[0])
+			if err != nil {
+				return err
+			}
+
+			a, err := artwork(*art)
+			if err != nil {
+				return err
+			}
+
+			if err := os.MkdirAll(*out, 0777); err != nil {
+				return err
+			}
+
+			b := batch{
+				roster: args[0],
+				dir:    *out,
+				width:  *width,
+				label:  *label,
+				art:    a,
+			}
+
+			return b.run(rows, *jobs)
+		}
+	},
+}
+
+// rosterRow is a row of a roster: the name of a person, and either the
+// recipe or the seed of their gopher, or neither, in which case the gopher
+// is chosen by their name.
+type rosterRow struct {
+	line   int
+	name   string
+	recipe string
+	seed   string
+
+	// file is the name of the PNG written for the row, and err the reason
+	// it could not be written
+	file string
+	err  error
+}
+
+// readRoster reads the CSV roster in file. Its columns are the name, recipe
+// and seed of each person, in that order unless the first row is a header
+// that names them. Lines starting with # are ignored.
+func readRoster(file string) ([]*rosterRow, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseRoster(file, f)
+}
+
+// parseRoster parses the roster read from r, as described by readRoster,
+// reporting errors against file.
+func parseRoster(file string, r io.Reader) ([]*rosterRow, error) {
+	recs, err := readRecords(file, r)
+	if err != nil {
+		return nil, err
+	}
+
+	cols := map[string]int{"name": 0, "recipe": 1, "seed": 2}
+
+	var rows []*rosterRow
+	for i, rec := range recs {
+		if rec.err != nil {
+			rows = append(rows, &rosterRow{line: rec.line, err: rec.err})
+			continue
+		}
+
+		if i == 0 && isRosterHeader(rec.fields) {
+			cols = make(map[string]int)
+			for i, v := range rec.fields {
+				cols[strings.ToLower(strings.TrimSpace(v))] = i
+			}
+			if _, ok := cols["name"]; !ok {
+				return nil, fmt.Errorf("%v: header has no name column", file)
+			}
+			continue
+		}
+
+		field := func(col string) string {
+			i, ok := cols[col]
+			if !ok || i >= len(rec.fields) {
+				return ""
+			}
+			return strings.TrimSpace(rec.fields[i])
+		}
+
+		rows = append(rows, &rosterRow{
+			line:   rec.line,
+			name:   field("name"),
+			recipe: field("recipe"),
+			seed:   field("seed"),
+		})
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%v: roster is empty", file)
+	}
+
+	return rows, nil
+}
+
+// csvRecord is a record of a CSV file and the line on which it starts, or
+// the reason it is invalid.
+type csvRecord struct {
+	line   int
+	fields []string
+	err    error
+}
+
+// readRecords returns the records of the CSV file read from r, skipping
+// blank lines and lines starting with #. Each record is read on its own, so
+// that its line is known, and an invalid record does not stop those that
+// follow being read; a record continues over following lines for as long as
+// it has an unterminated quoted field.
+func readRecords(file string, r io.Reader) ([]csvRecord, error) {
+	var res []csvRecord
+
+	var text bytes.Buffer
+	start := 0
+
+	read := func() {
+		cr := csv.NewReader(&text)
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return
+		}
+		if pe, ok := err.(*csv.ParseError); ok {
+			err = pe.Err
+		}
+		if err != nil {
+			res = append(res, csvRecord{line: start, err: fmt.Errorf("invalid row: %v", err)})
+			return
+		}
+
+		res = append(res, csvRecord{line: start, fields: rec})
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+
+	for line := 1; sc.Scan(); line++ {
+		l := sc.Bytes()
+		if text.Len() == 0 {
+			if bytes.HasPrefix(l, []byte("#")) {
+				continue
+			}
+			start = line
+		}
+		text.Write(l)
+		text.WriteByte('\n')
+
+		if inQuotedField(text.Bytes()) {
+			continue
+		}
+
+		read()
+		text.Reset()
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%v: failed to read roster: %v", file, err)
+	}
+
+	if text.Len() > 0 {
+		read()
+	}
+
+	return res, nil
+}
+
+// inQuotedField reports whether the CSV text b ends within a quoted field,
+// so that its record continues on the next line. Quotes within quoted fields
+// are doubled; quotes within other fields are left for csv to report, rather
+// than taken to start a field that swallows the rows that follow.
+func inQuotedField(b []byte) bool {
+	quoted, start := false, true
+
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case quoted && c == '"' && i+1 < len(b) && b[i+1] == '"':
+			i++
+		case quoted && c == '"':
+			quoted = false
+		case quoted:
+		case start && c == '"':
+			quoted, start = true, false
+		case start && (c == ' ' || c == '\t'):
+		case c == ',' || c == '\n':
+			start = true
+		default:
+			start = false
+		}
+	}
+
+	return quoted
+}
+
+// isRosterHeader reports whether the row rec is a header naming the columns
+// of a roster.
+func isRosterHeader(rec []string) bool {
+	for _, v := range rec {
+		if strings.EqualFold(strings.TrimSpace(v), "name") {
+			return true
+		}
+	}
+	return false
+}
+
+// batch draws the gophers of a roster.
+type batch struct {
+	roster string
+	dir    string
+	width  int
+	label  string
+	art    render.Artwork
+}
+
+// run draws the gophers of rows, n at a time, then writes the index. Rows
+// that fail, including those that could not be read, are reported, and do
+// not stop the others being drawn.
+func (b batch) run(rows []*rosterRow, n int) error {
+	// choose the file names up front, so that they do not depend on the
+	// order in which rows are drawn
+	used := make(map[string]bool)
+	for _, r := range rows {
+		base := slug(r.name)
+		r.file = base + ".png"
+		for i := 2; used[r.file]; i++ {
+			r.file = base + "-" + strconv.Itoa(i) + ".png"
+		}
+		used[r.file] = true
+	}
+
+	todo := make(chan *rosterRow)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range todo {
+				if r.err == nil {
+					r.err = b.draw(r)
+				}
+			}
+		}()
+	}
+
+	for _, r := range rows {
+		todo <- r
+	}
+	close(todo)
+	wg.Wait()
+
+	failed := 0
+	for _, r := range rows {
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "%v:%v: %v: %v\n", b.roster, r.line, r.name, r.err)
+			failed++
+		}
+	}
+
+	if err := b.writeIndex(rows); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v gophers failed", failed, len(rows))
+	}
+
+	return nil
+}
+
+// draw writes the PNG of the gopher of r.
+func (b batch) draw(r *rosterRow) error {
+	if r.name == "" {
+		return fmt.Errorf("no name")
+	}
+
+	g, err := b.gopher(r)
+	if err != nil {
+		return err
+	}
+
+	if b.label != "" {
+		t := gopher.Text{ID: "name", Text: r.name, Position: b.label}
+		if err := t.Validate(); err != nil {
+			return err
+		}
+		g.SetText(t)
+	}
+
+	m, err := render.Compose(g, b.art)
+	if err != nil {
+		return err
+	}
+	m = render.Resize(m, b.width, b.width*gopher.Height/gopher.Width)
+
+	var buf bytes.Buffer
+	if err := export.PNG(&buf, m, g); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(b.dir, r.file), buf.Bytes(), 0666)
+}
+
+// gopher returns the gopher of r, from its recipe, its seed or its name.
+func (b batch) gopher(r *rosterRow) (*gopher.Gopher, error) {
+	switch {
+	case r.recipe != "" && r.seed != "":
+		return nil, fmt.Errorf("both a recipe and a seed given")
+	case r.recipe == "-":
+		return nil, fmt.Errorf("invalid recipe %q", r.recipe)
+	case r.recipe != "":
+		arg := r.recipe
+		if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+			return readGopher(arg)
+		}
+
+		// recipe files are relative to the roster; encoded recipes never
+		// contain a dot or slash
+		if !filepath.IsAbs(arg) {
+			if p := filepath.Join(filepath.Dir(b.roster), arg); fileExists(p) || strings.ContainsAny(arg, "./") {
+				arg = p
+			}
+		}
+		if strings.ContainsAny(arg, "./") && !fileExists(arg) {
+			return nil, fmt.Errorf("recipe file %v not found", arg)
+		}
+		return readGopher(arg)
+	case r.seed != "":
+		return randomGopher(r.seed), nil
+	}
+
+	// the same name gives the same gopher, whatever its case or spacing
+	return randomGopher(strings.ToLower(strings.Join(strings.Fields(r.name), " "))), nil
+}
+
+// randomGopher returns the random gopher chosen by seed, which is either an
+// integer or any other string.
+func randomGopher(seed string) *gopher.Gopher {
+	n, err := strconv.ParseInt(seed, 10, 64)
+	if err != nil {
+		h := fnv.New64a()
+		h.Write([]byte(seed))
+		n = int64(h.Sum64())
+	}
+
+	return gopher.DefaultConfig.Random(rand.New(rand.NewSource(n)))
+}
+
+func fileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+func isPosition(p string) bool {
+	for _, v := range gopher.Positions {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
+
+// slug returns a file name for name, made of lower case letters, digits and
+// hyphens.
+func slug(name string) string {
+	var sb strings.Builder
+	hyphen := false
+
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+
+	if sb.Len() == 0 {
+		return "gopher"
+	}
+	return sb.String()
+}
+
+var batchIndex = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.gophers { display: flex; flex-wrap: wrap; gap: 16px; }
+.gopher { width: 160px; text-align: center; }
+.gopher img { width: 160px; }
+.failed { color: #a00; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="gophers">
+{{range .Drawn}}<div class="gopher"><a href="{{.File}}"><img src="{{.File}}" alt=""></a><div>{{.Name}}</div></div>
+{{end}}</div>
+{{if .Failed}}<h2 class="failed">Failed</h2>
+<ul class="failed">
+{{range .Failed}}<li>line {{.Line}}: {{.Name}}: {{.Err}}</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
+
+// writeIndex writes index.html, a contact sheet of the gophers drawn for
+// rows followed by the rows that failed.
+func (b batch) writeIndex(rows []*rosterRow) error {
+	type entry struct {
+		Line int
+		Name string
+		File string
+		Err  error
+	}
+
+	data := struct {
+		Title  string
+		Drawn  []entry
+		Failed []entry
+	}{
+		Title: strings.TrimSuffix(filepath.Base(b.roster), filepath.Ext(b.roster)),
+	}
+
+	for _, r := range rows {
+		e := entry{Line: r.line, Name: r.name, File: r.file, Err: r.err}
+		if r.err != nil {
+			data.Failed = append(data.Failed, e)
+		} else {
+			data.Drawn = append(data.Drawn, e)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := batchIndex.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(b.dir, "index.html"), buf.Bytes(), 0666)
+}
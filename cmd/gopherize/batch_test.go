@@ -0,0 +1,254 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"myitcv.io/gopherize.me/render"
+)
+
+func TestParseRoster(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     []rosterRow
+	}{
+		{
+			name: "default columns",
+			in:   "Ada Lovelace\nAlan Turing, body=blue_gopher eyes=eyes\nGrace Hopper,,42\n",
+			want: []rosterRow{
+				{line: 1, name: "Ada Lovelace"},
+				{line: 2, name: "Alan Turing", recipe: "body=blue_gopher eyes=eyes"},
+				{line: 3, name: "Grace Hopper", seed: "42"},
+			},
+		},
+		{
+			name: "header",
+			in:   "Seed,Team,Name\n7,core,Ada\n,docs,Alan\n",
+			want: []rosterRow{
+				{line: 2, name: "Ada", seed: "7"},
+				{line: 3, name: "Alan"},
+			},
+		},
+		{
+			name: "comments and blank lines",
+			in:   "# the team\n\nAda\n\n# more\nAlan\r\n",
+			want: []rosterRow{
+				{line: 3, name: "Ada"},
+				{line: 6, name: "Alan"},
+			},
+		},
+		{
+			name: "quoted",
+			in:   "\"Lovelace, Ada\"\n\"Turing,\nAlan\", \"body=blue_gopher \"\"eyes\"\"\"\nGrace\n",
+			want: []rosterRow{
+				{line: 1, name: "Lovelace, Ada"},
+				{line: 2, name: "Turing,\nAlan", recipe: `body=blue_gopher "eyes"`},
+				{line: 4, name: "Grace"},
+			},
+		},
+		{
+			name: "no final newline",
+			in:   "Ada\nAlan",
+			want: []rosterRow{
+				{line: 1, name: "Ada"},
+				{line: 2, name: "Alan"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		rows, err := parseRoster("roster.csv", strings.NewReader(test.in))
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		var got []rosterRow
+		for _, r := range rows {
+			got = append(got, *r)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %+v; want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseRosterInvalidRows(t *testing.T) {
+	tests := []struct {
+		in    string
+		line  int
+		names []string
+	}{
+		{"Ada\nAl\"an\nGrace\n", 2, []string{"Ada", "Grace"}},
+		{"Ada\n\"Al\"an\"\nGrace\n", 2, []string{"Ada", "Grace"}},
+		{"Ada\nGrace\n\"Alan\n", 3, []string{"Ada", "Grace"}},
+	}
+
+	for _, test := range tests {
+		rows, err := parseRoster("roster.csv", strings.NewReader(test.in))
+		if err != nil {
+			t.Errorf("parseRoster(%q): %v", test.in, err)
+			continue
+		}
+
+		// the invalid row is reported on its own, and the rest read
+		var bad []int
+		var names []string
+		for _, r := range rows {
+			if r.err == nil {
+				names = append(names, r.name)
+				continue
+			}
+			if !strings.HasPrefix(r.err.Error(), "invalid row: ") {
+				t.Errorf("parseRoster(%q) gave row error %v", test.in, r.err)
+			}
+			bad = append(bad, r.line)
+		}
+		if !reflect.DeepEqual(bad, []int{test.line}) || !reflect.DeepEqual(names, test.names) {
+			t.Errorf("parseRoster(%q) gave invalid rows on lines %v and names %q; want %v and %q", test.in, bad, names, test.line, test.names)
+		}
+	}
+}
+
+func TestParseRosterErrors(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "roster.csv: roster is empty"},
+		{"# nobody\n", "roster.csv: roster is empty"},
+		{"Name,Seed\n", "roster.csv: roster is empty"},
+	}
+
+	for _, test := range tests {
+		_, err := parseRoster("roster.csv", strings.NewReader(test.in))
+		if err == nil || !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("parseRoster(%q) gave error %v; want %q", test.in, err, test.want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Ada Lovelace", "ada-lovelace"},
+		{"  Grace   Hopper  ", "grace-hopper"},
+		{"O'Brien, Pat", "o-brien-pat"},
+		{"R2-D2", "r2-d2"},
+		{"Zoë", "zo"},
+		{"日本", "gopher"},
+		{"", "gopher"},
+	}
+
+	for _, test := range tests {
+		if got := slug(test.in); got != test.want {
+			t.Errorf("slug(%q) = %q; want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestBatchGopher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "ada.txt"), []byte("body=pink_gopher eyes=eyes"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// recipe files are found relative to the roster
+	b := batch{roster: filepath.Join(dir, "roster.csv")}
+
+	same := func(r1, r2 rosterRow) bool {
+		g1, err := b.gopher(&r1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g2, err := b.gopher(&r2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reflect.DeepEqual(g1, g2)
+	}
+
+	if !same(rosterRow{name: "Ada Lovelace"}, rosterRow{name: " ada  LOVELACE"}) {
+		t.Errorf("names differing only in case and spacing gave different gophers")
+	}
+	if same(rosterRow{name: "Ada Lovelace"}, rosterRow{name: "Alan Turing"}) {
+		t.Errorf("different names gave the same gopher")
+	}
+	if !same(rosterRow{name: "Ada", seed: "42"}, rosterRow{name: "Alan", seed: "42"}) {
+		t.Errorf("the same seed gave different gophers")
+	}
+
+	g, err := b.gopher(&rosterRow{name: "Ada", recipe: "ada.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Parts[0][0] != "010-Body/pink_gopher" {
+		t.Errorf("recipe file gave body %v", g.Parts[0])
+	}
+
+	for _, r := range []rosterRow{
+		{name: "Ada", recipe: "ada.txt", seed: "1"},
+		{name: "Ada", recipe: "-"},
+		{name: "Ada", recipe: "missing/recipe.json"},
+	} {
+		if _, err := b.gopher(&r); err == nil {
+			t.Errorf("gopher(%+v) gave no error", r)
+		}
+	}
+}
+
+func TestBatchRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	roster := filepath.Join(dir, "team.csv")
+	in := "name,seed\nAda,1\nada,2\n,3\nAl\"an,4\nAlan,\n"
+	if err := ioutil.WriteFile(roster, []byte(in), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := readRoster(roster)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := batch{
+		roster: roster,
+		dir:    dir,
+		width:  16,
+		art:    render.NewDir(filepath.Join("..", "..", "artwork")),
+	}
+
+	// the row without a name and the malformed row fail, but not the others
+	if err := b.run(rows, 2); err == nil || err.Error() != "2 of 5 gophers failed" {
+		t.Errorf("run gave error %v", err)
+	}
+
+	for _, f := range []string{"ada.png", "ada-2.png", "alan.png", "index.html"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			t.Errorf("%v not written: %v", f, err)
+		}
+	}
+
+	index, err := ioutil.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"line 4: : no name", "line 5: : invalid row: "} {
+		if !strings.Contains(string(index), want) {
+			t.Errorf("index does not report %q:\n%s", want, index)
+		}
+	}
+}
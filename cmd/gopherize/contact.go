@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+
+	"myitcv.io/gopherize.me/export"
+	"myitcv.io/gopherize.me/gopher"
+)
+
+var cmdContact = &command{
+	name:  "contact",
+	short: "draw a contact sheet of the options of a category of the artwork, or of all of them",
+	flags: func(fs *flag.FlagSet) func(args []string) error {
+		def := export.DefaultContactSheetOptions
+
+		art := artworkFlag(fs)
+		out := fs.String("o", "contact.png", "the PNG file to write, or - for stdout")
+		category := fs.String("category", "", "the category whose options are drawn (default: all of them)")
+		base := fs.String("base", "", "the recipe of the gopher that wears each option (default: the first Body and Eyes)")
+		columns := fs.Int("columns", 0, "the number of options in each row (default: as many as make the sheet roughly square)")
+		width := fs.Int("width", def.Mosaic.Width, "the width of each gopher in pixels")
+		spacing := fs.Int("spacing", def.Mosaic.Spacing, "the gap between gophers in pixels")
+		background := fs.String("background", "#ffffff", "the colour of the sheet, or none for transparent")
+		captions := fs.Bool("captions", true, "show the name of each option beneath it")
+
+		return func(args []string) error {
+			if len(args) != 0 {
+				return usageError("unexpected arguments")
+			}
+			if *columns < 0 {
+				return usageError(fmt.Sprintf("invalid -columns %v", *columns))
+			}
+			if *width < 1 {
+				return usageError(fmt.Sprintf("invalid -width %v", *width))
+			}
+			if *spacing < 0 {
+				return usageError(fmt.Sprintf("invalid -spacing %v", *spacing))
+			}
+
+			o := def
+			o.Category = *category
+			o.Mosaic.Columns = *columns
+			o.Mosaic.Width = *width
+			o.Mosaic.Spacing = *spacing
+			o.Mosaic.Captions = *captions
+
+			switch *background {
+			case "none":
+				o.Mosaic.Background = nil
+			default:
+				c, err := gopher.ParseColour(*background)
+				if err != nil {
+					return usageError(fmt.Sprintf("invalid -background %q: %v", *background, err))
+				}
+				o.Mosaic.Background = c
+			}
+
+			if *base != "" {
+				g, err := readGopher(*base)
+				if err != nil {
+					return err
+				}
+				o.Base = g
+			}
+
+			a, err := artwork(*art)
+			if err != nil {
+				return err
+			}
+
+			m, missing, err := export.ContactSheet(gopher.DefaultConfig, a, o)
+			for _, p := range missing {
+				fmt.Fprintf(os.Stderr, "no artwork for %v; left out\n", p)
+			}
+			if err != nil {
+				return err
+			}
+
+			return writeOutput(*out, func(w io.Writer) error {
+				return png.Encode(w, m)
+			})
+		}
+	},
+}
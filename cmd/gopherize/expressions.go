@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"io"
+
+	"myitcv.io/gopherize.me/export"
+)
+
+var cmdExpressions = &command{
+	name:  "expressions",
+	args:  "recipe",
+	short: "write a zip of emoji of a gopher, one for each of its possible eyes",
+	flags: func(fs *flag.FlagSet) func(args []string) error {
+		art := artworkFlag(fs)
+		out := fs.String("o", "gopher-expressions.zip", "the file to write, or - for stdout")
+		name := fs.String("name", "gopher", "the prefix of the name of each emoji")
+		size := fs.Int("size", export.DefaultEmojiSize, "the width and height of each emoji in pixels")
+
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usageError("expected a single recipe")
+			}
+
+			g, err := readGopher(args[0])
+			if err != nil {
+				return err
+			}
+
+			a, err := artwork(*art)
+			if err != nil {
+				return err
+			}
+
+			return writeOutput(*out, func(w io.Writer) error {
+				return export.Expressions(w, g, a, *name, *size)
+			})
+		}
+	},
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+var cmdFmt = &command{
+	name:  "fmt",
+	args:  "recipe...",
+	short: "check text recipes and print them in canonical form",
+	flags: func(fs *flag.FlagSet) func(args []string) error {
+		write := fs.Bool("w", false, "write the canonical form back to each file instead of printing it")
+		list := fs.Bool("l", false, "list the files whose formatting differs from the canonical form")
+
+		return func(args []string) error {
+			if len(args) == 0 {
+				return usageError("expected at least one recipe file")
+			}
+
+			// check every file before failing, as gofmt does
+			failed := false
+
+			for _, arg := range args {
+				var b []byte
+				var err error
+				if arg == "-" {
+					if *write {
+						return usageError("cannot write the canonical form of stdin")
+					}
+					b, err = ioutil.ReadAll(os.Stdin)
+				} else {
+					b, err = ioutil.ReadFile(arg)
+				}
+
+				if err == nil {
+					err = fmtRecipe(arg, b, *write, *list)
+				}
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					failed = true
+				}
+			}
+
+			if failed {
+				return fmt.Errorf("some recipes could not be formatted")
+			}
+
+			return nil
+		}
+	},
+}
+
+// fmtRecipe formats the recipe b read from the file arg.
+func fmtRecipe(arg string, b []byte, write, list bool) error {
+	g, err := parseRecipe(arg, b, gopher.DefaultConfig)
+	if err != nil {
+		return err
+	}
+
+	res, err := gopher.FormatRecipeText(g, gopher.DefaultConfig)
+	if err != nil {
+		return fmt.Errorf("%v: %v", arg, err)
+	}
+
+	if write && !isRecipeText(b) {
+		return fmt.Errorf("%v: not a text recipe; refusing to overwrite it", arg)
+	}
+
+	changed := !bytes.Equal(b, res)
+
+	if list && changed {
+		fmt.Println(arg)
+	}
+
+	switch {
+	case write:
+		if changed {
+			return ioutil.WriteFile(arg, res, 0666)
+		}
+	case !list:
+		_, err = os.Stdout.Write(res)
+	}
+
+	return err
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+
+	"myitcv.io/gopherize.me/export"
+	"myitcv.io/gopherize.me/gopher"
+)
+
+var cmdGIF = &command{
+	name:  "gif",
+	args:  "recipe",
+	short: "write an animated GIF of a gopher, its eyes blinking or looking around",
+	flags: func(fs *flag.FlagSet) func(args []string) error {
+		var names []string
+		for _, a := range export.Animations {
+			names = append(names, a.Name)
+		}
+
+		art := artworkFlag(fs)
+		out := fs.String("o", "gopher.gif", "the file to write, or - for stdout")
+		anim := fs.String("anim", "blink", "the animation: "+strings.Join(names, ", "))
+		frames := fs.String("frames", "", "a custom animation, e.g. current:2000,blink:120,looking_left:800")
+		width := fs.Int("width", 320, "the width of the GIF in pixels")
+		colours := fs.Int("colours", 256, "the number of colours in the palette")
+		dither := fs.Bool("dither", false, "dither the colours of each frame")
+
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usageError("expected a single recipe")
+			}
+
+			o := export.GIFOptions{
+				Width:   *width,
+				Colours: *colours,
+				Dither:  *dither,
+			}
+
+			if *frames != "" {
+				fr, err := export.ParseFrames(*frames, gopher.DefaultConfig)
+				if err != nil {
+					return err
+				}
+				o.Frames = fr
+			} else {
+				a, err := export.LookupAnimation(*anim)
+				if err != nil {
+					return err
+				}
+				o.Frames = a.Frames
+			}
+
+			g, err := readGopher(args[0])
+			if err != nil {
+				return err
+			}
+
+			a, err := artwork(*art)
+			if err != nil {
+				return err
+			}
+
+			return writeOutput(*out, func(w io.Writer) error {
+				return export.GIF(w, g, a, o)
+			})
+		}
+	},
+}
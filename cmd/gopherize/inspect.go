@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+var cmdInspect = &command{
+	name:  "inspect",
+	args:  "image|recipe",
+	short: "print the recipe of a gopher, such as that recorded in an exported PNG",
+	flags: func(fs *flag.FlagSet) func(args []string) error {
+		encoded := fs.Bool("encoded", false, "print the recipe encoded as in the g parameter of a gopherize.me URL")
+		text := fs.Bool("text", false, "print the recipe in the text recipe format")
+
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usageError("expected a single image or recipe")
+			}
+			if *encoded && *text {
+				return usageError("-encoded and -text cannot be used together")
+			}
+
+			g, err := readGopher(args[0])
+			if err != nil {
+				return err
+			}
+
+			if *text {
+				b, err := gopher.FormatRecipeText(g, gopher.DefaultConfig)
+				if err != nil {
+					return err
+				}
+				_, err = os.Stdout.Write(b)
+				return err
+			}
+
+			if *encoded {
+				s, err := gopher.EncodeRecipe(g)
+				if err != nil {
+					return err
+				}
+				fmt.Println(s)
+				return nil
+			}
+
+			b, err := gopher.MarshalRecipe(g)
+			if err != nil {
+				return err
+			}
+
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, b, "", "  "); err != nil {
+				return err
+			}
+			buf.WriteByte('\n')
+
+			_, err = buf.WriteTo(os.Stdout)
+			return err
+		}
+	},
+}
@@ -0,0 +1,278 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// gopherize is the command line counterpart of gopherize.me. It renders
+// gophers described by recipes, the same recipes used by the site, into the
+// formats supported by the export package.
+//
+// Usage:
+//
+//	gopherize <command> [flags] [arguments]
+//
+// Run "gopherize help <command>" for the flags of a command.
+//
+// A recipe argument is either the path of a file holding a JSON or text
+// recipe (see gopher.ParseRecipeText) or a PNG image exported with its
+// recipe, "-" for any of these read from stdin, a gopherize.me URL, or the
+// encoded recipe found in the g parameter of such a URL.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"myitcv.io/gopherize.me/export"
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+)
+
+// command is a subcommand of gopherize.
+type command struct {
+	name  string
+	args  string
+	short string
+
+	// flags declares the flags of the command on fs, returning the function
+	// that runs the command once the flags are parsed.
+	flags func(fs *flag.FlagSet) func(args []string) error
+}
+
+var commands = []*command{
+	cmdPDF,
+	cmdGIF,
+	cmdExpressions,
+	cmdStickers,
+	cmdScene,
+	cmdBatch,
+	cmdContact,
+	cmdShow,
+	cmdInspect,
+	cmdFmt,
+}
+
+// usageError is returned by a command whose arguments are invalid.
+type usageError string
+
+func (u usageError) Error() string {
+	return string(u)
+}
+
+func main() {
+	os.Exit(main1(os.Args[1:]))
+}
+
+func main1(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+
+	name, args := args[0], args[1:]
+
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		if len(args) == 1 {
+			if c := lookup(args[0]); c != nil {
+				fs := newFlagSet(c)
+				c.flags(fs)
+				fs.Usage()
+				return 0
+			}
+		}
+		usage()
+		return 0
+	}
+
+	c := lookup(name)
+	if c == nil {
+		fmt.Fprintf(os.Stderr, "gopherize: unknown command %q\n", name)
+		usage()
+		return 2
+	}
+
+	fs := newFlagSet(c)
+	run := c.flags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	if err := run(fs.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "gopherize %v: %v\n", c.name, err)
+		if _, ok := err.(usageError); ok {
+			fs.Usage()
+			return 2
+		}
+		return 1
+	}
+
+	return 0
+}
+
+func lookup(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func newFlagSet(c *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gopherize %v [flags] %v\n\n%v\n", c.name, c.args, c.short)
+		if hasFlags(fs) {
+			fmt.Fprintln(os.Stderr, "\nflags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	res := false
+	fs.VisitAll(func(*flag.Flag) { res = true })
+	return res
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: gopherize <command> [flags] [arguments]\n\ncommands:\n")
+	tw := tabwriter.NewWriter(os.Stderr, 0, 8, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %v\t%v\n", c.name, c.short)
+	}
+	tw.Flush()
+	fmt.Fprintf(os.Stderr, "\nRun \"gopherize help <command>\" for more information about a command.\n")
+}
+
+// artworkFlag declares the -artwork flag on fs.
+func artworkFlag(fs *flag.FlagSet) *string {
+	return fs.String("artwork", "", "the artwork directory (default: that of myitcv.io/gopherize.me in GOPATH)")
+}
+
+// artwork returns the artwork named by the -artwork flag dir, locating the
+// artwork directory of this repository if dir is empty.
+func artwork(dir string) (*render.Dir, error) {
+	if dir == "" {
+		p, err := build.Import("myitcv.io/gopherize.me/gopher", "", build.FindOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate artwork; use -artwork: %v", err)
+		}
+		dir = filepath.Join(p.Dir, "..", "artwork")
+	}
+
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return nil, fmt.Errorf("artwork directory %v not found", dir)
+	}
+
+	return render.NewDir(dir), nil
+}
+
+// readGopher returns the gopher described by the recipe argument arg.
+func readGopher(arg string) (*gopher.Gopher, error) {
+	c := gopher.DefaultConfig
+
+	var b []byte
+	var err error
+
+	switch {
+	case arg == "-":
+		b, err = ioutil.ReadAll(os.Stdin)
+	case strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://"):
+		u, err := url.Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+		g := u.Query().Get("g")
+		if g == "" {
+			return nil, fmt.Errorf("URL %v has no recipe", arg)
+		}
+		return gopher.DecodeRecipe(g, c)
+	default:
+		if _, serr := os.Stat(arg); serr != nil {
+			return gopher.DecodeRecipe(arg, c)
+		}
+		b, err = ioutil.ReadFile(arg)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRecipe(arg, b, c)
+}
+
+// parseRecipe returns the gopher described by b, the contents of the recipe
+// argument arg, which is a PNG image, a JSON recipe or a text recipe.
+func parseRecipe(arg string, b []byte, c *gopher.Config) (*gopher.Gopher, error) {
+	if bytes.HasPrefix(b, []byte("\x89PNG")) {
+		return export.ReadPNG(bytes.NewReader(b), c)
+	}
+
+	if !isRecipeText(b) {
+		return gopher.UnmarshalRecipe(b, c)
+	}
+
+	g, err := gopher.ParseRecipeText(b, c)
+	if errs, ok := err.(gopher.RecipeTextErrors); ok {
+		// report every error, in the form file:line:col used by editors
+		if arg == "-" {
+			arg = "<stdin>"
+		}
+		var lines []string
+		for _, e := range errs {
+			sep := ":"
+			if e.Line == 0 {
+				sep = ": "
+			}
+			lines = append(lines, arg+sep+e.Error())
+		}
+		return nil, fmt.Errorf("invalid recipe:\n%v", strings.Join(lines, "\n"))
+	}
+
+	return g, err
+}
+
+// isRecipeText reports whether b, the contents of a recipe argument, is a
+// text recipe rather than a PNG image or JSON recipe.
+func isRecipeText(b []byte) bool {
+	if bytes.HasPrefix(b, []byte("\x89PNG")) {
+		return false
+	}
+	t := bytes.TrimSpace(b)
+	return len(t) == 0 || t[0] != '{'
+}
+
+// writeOutput calls write with the file named by the -o flag out, or with
+// stdout, which is left open, if out is "-". The file is removed if write
+// fails, rather than left half written.
+func writeOutput(out string, write func(w io.Writer) error) error {
+	if out == "-" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(out)
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"myitcv.io/gopherize.me/export"
+)
+
+var cmdPDF = &command{
+	name:  "pdf",
+	args:  "recipe",
+	short: "write a print-ready PDF of a gopher, for stickers and badges",
+	flags: func(fs *flag.FlagSet) func(args []string) error {
+		art := artworkFlag(fs)
+		out := fs.String("o", "gopher.pdf", "the file to write, or - for stdout")
+		size := fs.Float64("size", 50, "the width of the gopher in millimetres")
+		bleed := fs.Float64("bleed", 0, "the bleed in millimetres")
+		cut := fs.Bool("cut", false, "draw cut lines")
+		sheet := fs.String("sheet", "", "lay out copies on a sheet: A4 or Letter")
+		grid := fs.String("grid", "", "the columns x rows of the sheet, e.g. 3x4 (default: as many as fit)")
+
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usageError("expected a single recipe")
+			}
+
+			o := export.PDFOptions{
+				Size:     *size,
+				Bleed:    *bleed,
+				CutLines: *cut,
+			}
+
+			if *sheet != "" {
+				s, err := export.LookupSheet(*sheet)
+				if err != nil {
+					return err
+				}
+				o.Sheet = &s
+			}
+
+			if *grid != "" {
+				if o.Sheet == nil {
+					return usageError("-grid requires -sheet")
+				}
+				c, r, ok := parseGrid(*grid)
+				if !ok {
+					return usageError(fmt.Sprintf("invalid -grid %q; expected columns x rows, e.g. 3x4", *grid))
+				}
+				o.Columns, o.Rows = c, r
+			}
+
+			g, err := readGopher(args[0])
+			if err != nil {
+				return err
+			}
+
+			a, err := artwork(*art)
+			if err != nil {
+				return err
+			}
+
+			return writeOutput(*out, func(w io.Writer) error {
+				return export.PDF(w, g, a, o)
+			})
+		}
+	},
+}
+
+// parseGrid parses the columns x rows of a -grid flag, such as 3x4; a zero
+// number of either fits as many as the sheet allows.
+func parseGrid(s string) (columns, rows int, ok bool) {
+	f := strings.Split(s, "x")
+	if len(f) != 2 {
+		return 0, 0, false
+	}
+
+	columns, err := strconv.Atoi(f[0])
+	if err != nil || columns < 0 {
+		return 0, 0, false
+	}
+	rows, err = strconv.Atoi(f[1])
+	if err != nil || rows < 0 {
+		return 0, 0, false
+	}
+
+	return columns, rows, true
+}
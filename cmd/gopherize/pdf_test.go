@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestParseGrid(t *testing.T) {
+	tests := []struct {
+		in            string
+		columns, rows int
+		ok            bool
+	}{
+		{"3x4", 3, 4, true},
+		{"1x1", 1, 1, true},
+		{"0x2", 0, 2, true},
+		{"3x4foo", 0, 0, false},
+		{"3x4x5", 0, 0, false},
+		{"3 x 4", 0, 0, false},
+		{"3X4", 0, 0, false},
+		{"x4", 0, 0, false},
+		{"3x", 0, 0, false},
+		{"-1x4", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, test := range tests {
+		c, r, ok := parseGrid(test.in)
+		if c != test.columns || r != test.rows || ok != test.ok {
+			t.Errorf("parseGrid(%q) = %v, %v, %v; want %v, %v, %v", test.in, c, r, ok, test.columns, test.rows, test.ok)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"myitcv.io/gopherize.me/export"
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+)
+
+var cmdScene = &command{
+	name:  "scene",
+	args:  "scene",
+	short: "draw a scene of several gophers as a PNG or SVG image",
+	flags: func(fs *flag.FlagSet) func(args []string) error {
+		art := artworkFlag(fs)
+		out := fs.String("o", "scene.png", "the file to write, or - for stdout; its extension, .png or .svg, gives the format")
+
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usageError("expected a single scene")
+			}
+
+			var write func(w io.Writer, s *gopher.Scene, a render.Artwork) error
+			switch ext := strings.ToLower(filepath.Ext(*out)); {
+			case *out == "-" || ext == ".png":
+				write = func(w io.Writer, s *gopher.Scene, a render.Artwork) error {
+					m, err := render.ComposeScene(s, a)
+					if err != nil {
+						return err
+					}
+					return export.ScenePNG(w, m, s)
+				}
+			case ext == ".svg":
+				write = export.SceneSVG
+			default:
+				return usageError(fmt.Sprintf("unsupported output format %q; expected .png or .svg", ext))
+			}
+
+			s, err := readScene(args[0])
+			if err != nil {
+				return err
+			}
+
+			a, err := artwork(*art)
+			if err != nil {
+				return err
+			}
+
+			return writeOutput(*out, func(w io.Writer) error {
+				return write(w, s, a)
+			})
+		}
+	},
+}
+
+// readScene returns the scene held in the file arg, "-" for stdin, which is
+// a JSON scene or a PNG image written by the scene command.
+func readScene(arg string) (*gopher.Scene, error) {
+	var b []byte
+	var err error
+
+	if arg == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(arg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(b, []byte("\x89PNG")) {
+		return export.ReadScene(bytes.NewReader(b), gopher.DefaultConfig)
+	}
+
+	return gopher.UnmarshalScene(b, gopher.DefaultConfig)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"image"
+	"os"
+	"strings"
+
+	"myitcv.io/gopherize.me/render"
+	"myitcv.io/gopherize.me/term"
+)
+
+var cmdShow = &command{
+	name:  "show",
+	args:  "recipe",
+	short: "draw a gopher in the terminal",
+	flags: func(fs *flag.FlagSet) func(args []string) error {
+		var modes []string
+		for _, m := range term.Modes {
+			modes = append(modes, string(m))
+		}
+
+		art := artworkFlag(fs)
+		mode := fs.String("mode", "", "how to draw: "+strings.Join(modes, ", ")+" (default: detected from the environment)")
+		width := fs.Int("width", 0, "the width in columns (default: to fit the terminal)")
+		crop := fs.Bool("crop", true, "crop away the transparent space around the gopher")
+
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usageError("expected a single recipe")
+			}
+
+			o := term.Options{
+				Mode:    term.Detect(),
+				Columns: *width,
+			}
+			o.CellWidth, o.CellHeight = term.CellSize()
+			if *mode != "" {
+				m, err := term.ParseMode(*mode)
+				if err != nil {
+					return err
+				}
+				o.Mode = m
+			}
+
+			g, err := readGopher(args[0])
+			if err != nil {
+				return err
+			}
+
+			a, err := artwork(*art)
+			if err != nil {
+				return err
+			}
+
+			m, err := render.Compose(g, a)
+			if err != nil {
+				return err
+			}
+
+			if *crop && g.Background == "" {
+				if b := render.OpaqueBounds(m); !b.Empty() {
+					m = m.SubImage(b).(*image.NRGBA)
+				}
+			}
+
+			if o.Columns == 0 {
+				// fit the terminal, leaving a line for the prompt; cells
+				// are two pixels tall when drawn as characters
+				cols, rows := term.Size()
+				b := m.Bounds()
+				aspect := 2.0
+				switch o.Mode {
+				case term.ModeKitty, term.ModeSixel:
+					aspect = float64(o.CellHeight) / float64(o.CellWidth)
+				}
+				o.Columns = cols
+				if fit := int(float64((rows-1)*b.Dx()) * aspect / float64(b.Dy())); fit < o.Columns {
+					o.Columns = fit
+				}
+				// a terminal of a single line, or a narrow one, still shows
+				// something
+				if o.Columns < 1 {
+					o.Columns = 1
+				}
+			}
+
+			return term.Draw(os.Stdout, m, o)
+		}
+	},
+}
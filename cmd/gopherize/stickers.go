@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"myitcv.io/gopherize.me/export"
+	"myitcv.io/gopherize.me/gopher"
+)
+
+var cmdStickers = &command{
+	name:  "stickers",
+	args:  "recipe...",
+	short: "write a zip of stickers for messaging apps, or a single sticker PNG",
+	flags: func(fs *flag.FlagSet) func(args []string) error {
+		def := export.DefaultStickerOptions
+
+		art := artworkFlag(fs)
+		out := fs.String("o", "gopher-stickers.zip", "the file to write, or - for stdout; a .png file holds a single sticker")
+		name := fs.String("name", "gopher", "the name of the pack")
+		eyes := fs.Bool("eyes", false, "make a sticker for each of the possible eyes of each gopher")
+		size := fs.Int("size", def.Size, "the width and height of each sticker in pixels")
+		outline := fs.Float64("outline", def.Outline, "the width of the outline in pixels, 0 for none")
+		colour := fs.String("outline-colour", "#ffffff", "the colour of the outline")
+		shadow := fs.Bool("shadow", def.Shadow, "draw a drop shadow")
+
+		return func(args []string) error {
+			if len(args) == 0 {
+				return usageError("expected at least one recipe")
+			}
+
+			c, err := gopher.ParseColour(*colour)
+			if err != nil {
+				return err
+			}
+
+			o := export.StickerOptions{
+				Size:          *size,
+				Outline:       *outline,
+				OutlineColour: c,
+				Shadow:        *shadow,
+			}
+
+			var srcs []export.StickerSource
+			for i, arg := range args {
+				g, err := readGopher(arg)
+				if err != nil {
+					return fmt.Errorf("%v: %v", arg, err)
+				}
+
+				// name stickers after the files of their recipes where
+				// possible
+				n := fmt.Sprintf("%v%v", *name, i+1)
+				if _, err := os.Stat(arg); err == nil {
+					n = strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg))
+				} else if len(args) == 1 {
+					n = *name
+				}
+
+				if *eyes {
+					srcs = append(srcs, export.EyeVariants(n, g)...)
+				} else {
+					srcs = append(srcs, export.StickerSource{Name: n, Gopher: g})
+				}
+			}
+
+			a, err := artwork(*art)
+			if err != nil {
+				return err
+			}
+
+			if strings.HasSuffix(*out, ".png") && len(srcs) != 1 {
+				return fmt.Errorf("a .png file holds a single sticker; write %v stickers to a .zip", len(srcs))
+			}
+
+			return writeOutput(*out, func(w io.Writer) error {
+				if !strings.HasSuffix(*out, ".png") {
+					return export.Stickers(w, *name, srcs, a, o)
+				}
+				m, err := export.Sticker(srcs[0].Gopher, a, srcs[0].Eyes, o)
+				if err != nil {
+					return err
+				}
+				return export.PNG(w, m, srcs[0].Gopher)
+			})
+		}
+	},
+}
@@ -0,0 +1,148 @@
+package export
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+)
+
+// ContactSheetOptions describe a contact sheet drawn by ContactSheet.
+type ContactSheetOptions struct {
+	// Category is the name of the category whose options are shown, or ""
+	// for the options of every category. Its case is ignored, and it may be
+	// given as its key in the text recipe format, as in facial_hair.
+	Category string
+
+	// Base is the gopher that wears each option in turn, nil for the
+	// default of ContactSheetBase.
+	Base *gopher.Gopher
+
+	// Mosaic lays out the sheet; each option is captioned with its name.
+	Mosaic MosaicOptions
+}
+
+// DefaultContactSheetOptions are the options of a contact sheet of every
+// category, captioned on white.
+var DefaultContactSheetOptions = ContactSheetOptions{
+	Mosaic: MosaicOptions{
+		Width:      DefaultMosaicOptions.Width * 3 / 4,
+		Spacing:    DefaultMosaicOptions.Spacing,
+		Background: DefaultMosaicOptions.Background,
+		Captions:   true,
+	},
+}
+
+// ContactSheetBase returns the default base gopher of a contact sheet of c:
+// the first option of the Body and Eyes categories, as on the site.
+func ContactSheetBase(c *gopher.Config) *gopher.Gopher {
+	g := &gopher.Gopher{Parts: make([][]string, len(c.Categories))}
+
+	for _, name := range []string{"Body", "Eyes"} {
+		if i, cat := c.Category(name); cat != nil && len(cat.Options) > 0 {
+			g.Choose(i, cat.Options[0], cat.MaxSelections())
+		}
+	}
+
+	return g
+}
+
+// ContactSheetMembers returns the gophers of the contact sheet described by
+// o: the base wearing each option of the category, or of every category, in
+// the order of the catalogue c. In a category that permits a single option,
+// the option replaces that of the base.
+func ContactSheetMembers(c *gopher.Config, o ContactSheetOptions) ([]MosaicMember, error) {
+	base := o.Base
+	if base == nil {
+		base = ContactSheetBase(c)
+	}
+
+	var res []MosaicMember
+
+	for i, cat := range c.Categories {
+		if o.Category != "" && gopher.CategoryKey(cat.Name) != gopher.CategoryKey(o.Category) {
+			continue
+		}
+
+		for _, opt := range cat.Options {
+			if opt == "" {
+				continue
+			}
+
+			g := base.Copy()
+
+			// wear only opt of this category; a single option is replaced in
+			// place, keeping its layer
+			if max := cat.MaxSelections(); max > 1 {
+				g.Choose(i, "", max)
+			}
+			g.Choose(i, opt, cat.MaxSelections())
+
+			// captions are the names of options, and of their categories too
+			// when they are mixed
+			name := g.Label(opt)
+			if o.Category != "" {
+				name = name[strings.Index(name, ": ")+2:]
+			}
+
+			res = append(res, MosaicMember{Name: name, Gopher: g})
+		}
+	}
+
+	if len(res) == 0 {
+		var names []string
+		for _, cat := range c.Categories {
+			if gopher.CategoryKey(cat.Name) == gopher.CategoryKey(o.Category) {
+				return nil, fmt.Errorf("category %v has no options", cat.Name)
+			}
+			names = append(names, cat.Name)
+		}
+		return nil, fmt.Errorf("unknown category %q; expected one of: %v", o.Category, strings.Join(names, ", "))
+	}
+
+	return res, nil
+}
+
+// ContactSheet draws the contact sheet of the options of the catalogue c
+// described by o. Options whose artwork cannot be loaded from a are left out
+// of the sheet, and returned in missing, so that a sheet of the whole
+// catalogue also shows what is absent from it.
+func ContactSheet(c *gopher.Config, a render.Artwork, o ContactSheetOptions) (m *image.NRGBA, missing []string, err error) {
+	ms, err := ContactSheetMembers(c, o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// a is asked for each layer up front, to find what is missing before
+	// drawing; a render.Dir keeps those most recently used, such as the base,
+	// cached
+	seen := make(map[string]bool)
+	var drawn []MosaicMember
+	for _, m := range ms {
+		ok := true
+		for _, p := range m.Gopher.Layers() {
+			if gopher.IsText(p) || gopher.IsUpload(p) {
+				continue
+			}
+			if _, err := a.Layer(p); err != nil {
+				if !seen[p] {
+					missing = append(missing, p)
+					seen[p] = true
+				}
+				ok = false
+			}
+		}
+		if ok {
+			drawn = append(drawn, m)
+		}
+	}
+
+	if len(drawn) == 0 {
+		return nil, missing, fmt.Errorf("no artwork found for any of %v options", len(ms))
+	}
+
+	m, err = Mosaic(drawn, a, o.Mosaic)
+	return m, missing, err
+}
@@ -0,0 +1,33 @@
+package export
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"myitcv.io/gopherize.me/render"
+)
+
+// square returns the square centred on r whose side is that of the longer
+// side of r plus a margin of the given fraction of it on each side.
+func square(r image.Rectangle, margin float64) image.Rectangle {
+	side := maxInt(r.Dx(), r.Dy())
+	side += 2 * int(float64(side)*margin+0.5)
+
+	min := image.Pt(r.Min.X+(r.Dx()-side)/2, r.Min.Y+(r.Dy()-side)/2)
+	return image.Rectangle{Min: min, Max: min.Add(image.Pt(side, side))}
+}
+
+// cropResize returns the area r of m, which may extend beyond m, scaled to
+// size x size pixels. m is drawn over bg, which fills the whole of r, and
+// so shows through the transparent parts of m as well as beyond it; a nil bg
+// leaves them transparent.
+func cropResize(m image.Image, r image.Rectangle, size int, bg color.Color) *image.NRGBA {
+	c := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	if bg != nil {
+		draw.Draw(c, c.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	}
+	draw.Draw(c, m.Bounds().Sub(r.Min), m, m.Bounds().Min, draw.Over)
+
+	return render.Resize(c, size, size)
+}
@@ -0,0 +1,67 @@
+package export
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSquare(t *testing.T) {
+	tests := []struct {
+		r      image.Rectangle
+		margin float64
+		want   image.Rectangle
+	}{
+		{image.Rect(0, 0, 10, 10), 0, image.Rect(0, 0, 10, 10)},
+		{image.Rect(0, 0, 10, 20), 0, image.Rect(-5, 0, 15, 20)},
+		{image.Rect(10, 10, 30, 20), 0, image.Rect(10, 5, 30, 25)},
+		{image.Rect(0, 0, 100, 100), 0.1, image.Rect(-10, -10, 110, 110)},
+		{image.Rect(300, 200, 1000, 1200), 0.02, image.Rect(130, 180, 1170, 1220)},
+	}
+
+	for _, test := range tests {
+		if got := square(test.r, test.margin); got != test.want {
+			t.Errorf("square(%v, %v) = %v; want %v", test.r, test.margin, got, test.want)
+		}
+	}
+}
+
+func TestCropResize(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 40, 40))
+	for i := range m.Pix {
+		m.Pix[i] = 0xff
+	}
+	// a transparent hole in the middle
+	for y := 15; y < 25; y++ {
+		for x := 15; x < 25; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{})
+		}
+	}
+
+	green := color.NRGBA{G: 0xff, A: 0xff}
+
+	// the crop extends beyond m on the left and top, and is halved, so that
+	// m fills the bottom right quarter, its hole about (30, 30)
+	r := image.Rect(-40, -40, 40, 40)
+
+	tests := []struct {
+		name string
+		bg   color.Color
+		want map[image.Point]color.NRGBA
+	}{
+		{"transparent", nil, map[image.Point]color.NRGBA{{5, 5}: {}, {22, 22}: white, {37, 37}: white, {30, 30}: {}}},
+		{"background", green, map[image.Point]color.NRGBA{{5, 5}: green, {22, 22}: white, {37, 37}: white, {30, 30}: green}},
+	}
+
+	for _, test := range tests {
+		got := cropResize(m, r, 40, test.bg)
+		if b := got.Bounds(); b != image.Rect(0, 0, 40, 40) {
+			t.Errorf("%v: cropResize gave %v; want 40x40", test.name, b)
+		}
+		for p, want := range test.want {
+			if c := got.NRGBAAt(p.X, p.Y); !near(c, want) {
+				t.Errorf("%v: %v at %v; want %v", test.name, c, p, want)
+			}
+		}
+	}
+}
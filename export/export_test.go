@@ -0,0 +1,86 @@
+package export
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+const (
+	testBody = "010-Body/blue_gopher"
+	testEyes = "020-Eyes/eyes"
+)
+
+var (
+	white      = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	bodyColour = color.NRGBA{B: 0xff, A: 0xff}
+
+	// the body fills most of the canvas, and the eyes sit within it
+	bodyRect = image.Rect(300, 200, 1000, 1200)
+	eyesRect = image.Rect(450, 300, 850, 500)
+)
+
+// testArtwork is an Artwork of images held in memory.
+type testArtwork map[string]image.Image
+
+func (a testArtwork) Layer(o string) (image.Image, error) {
+	l, ok := a[o]
+	if !ok {
+		return nil, fmt.Errorf("no artwork for %v", o)
+	}
+	return l, nil
+}
+
+// newTestArtwork returns artwork of a body, filling bodyRect, and of each
+// option of the Eyes category, filling eyesRect with the colour given by
+// eyesColour.
+func newTestArtwork() testArtwork {
+	a := testArtwork{testBody: layer(bodyRect, bodyColour)}
+	for _, o := range eyesOptions() {
+		a[o] = layer(eyesRect, eyesColour(o))
+	}
+	return a
+}
+
+// eyesOptions returns the options of the Eyes category, the second of the
+// default catalogue.
+func eyesOptions() []string {
+	var res []string
+	for _, o := range gopher.DefaultConfig.Categories[1].Options {
+		if o != "" {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+// eyesColour returns a colour that differs for each option of the Eyes
+// category.
+func eyesColour(o string) color.NRGBA {
+	for i, e := range eyesOptions() {
+		if e == o {
+			return color.NRGBA{R: uint8(0x40 + 0x10*i), G: 0x80, A: 0xff}
+		}
+	}
+	return color.NRGBA{}
+}
+
+// layer returns a transparent, gopher sized layer with r filled with c.
+func layer(r image.Rectangle, c color.Color) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, gopher.Width, gopher.Height))
+	draw.Draw(m, r, image.NewUniform(c), image.Point{}, draw.Src)
+	return m
+}
+
+// testGopher returns a gopher of the test artwork, with the eyes eyes.
+func testGopher(eyes string) *gopher.Gopher {
+	g := &gopher.Gopher{Parts: make([][]string, len(gopher.DefaultConfig.Categories))}
+	g.Parts[0] = []string{testBody}
+	if eyes != "" {
+		g.Parts[1] = []string{eyes}
+	}
+	return g
+}
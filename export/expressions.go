@@ -0,0 +1,97 @@
+package export
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"path"
+
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+)
+
+// DefaultEmojiSize is the default size in pixels of the images of an
+// expression pack, that used for custom emoji by most chat services.
+const DefaultEmojiSize = 128
+
+// emojiMargin is the margin left around a tightly cropped gopher, as a
+// fraction of its size.
+const emojiMargin = 0.02
+
+// Expressions writes a zip archive holding an image of g for each option of
+// the Eyes category, together with a manifest. Each image is size x size
+// pixels, cropped tightly around the gopher; every image is cropped alike,
+// so that the gopher stays in the same place as its expression changes.
+// Images are named after the option they show, prefixed by name, for example
+// gopher_eyes_angry.png.
+func Expressions(w io.Writer, g *gopher.Gopher, a render.Artwork, name string, size int) error {
+	if size <= 0 {
+		return fmt.Errorf("invalid size %v", size)
+	}
+
+	_, cat := gopher.DefaultConfig.Category(eyesCategory)
+	if cat == nil {
+		return fmt.Errorf("no %v category", eyesCategory)
+	}
+
+	var bg color.Color
+	if g.Background != "" {
+		c, err := gopher.ParseColour(g.Background)
+		if err != nil {
+			return err
+		}
+		bg = c
+	}
+
+	// crop without the background, which would otherwise fill the canvas
+	fg := g.Copy()
+	fg.Background = ""
+
+	var opts []string
+	var images []*image.NRGBA
+	var crop image.Rectangle
+
+	for _, o := range cat.Options {
+		if o == "" {
+			continue
+		}
+
+		m, err := eyesFrame(fg, a, Frame{Eyes: o})
+		if err != nil {
+			return err
+		}
+
+		opts = append(opts, o)
+		images = append(images, m)
+		crop = crop.Union(render.OpaqueBounds(m))
+	}
+
+	if crop.Empty() {
+		return fmt.Errorf("gopher has no visible layers")
+	}
+	crop = square(crop, emojiMargin)
+
+	recipe, err := gopher.MarshalRecipe(g)
+	if err != nil {
+		return err
+	}
+
+	pk := newPack(w, Manifest{
+		Name:   name,
+		Size:   size,
+		Recipe: recipe,
+	})
+
+	for i, o := range opts {
+		im := ManifestImage{
+			Name:   name + "_" + path.Base(o),
+			Option: o,
+		}
+		if err := pk.add(im, cropResize(images[i], crop, size, bg)); err != nil {
+			return err
+		}
+	}
+
+	return pk.close()
+}
@@ -0,0 +1,156 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+// readPack returns the manifest and images of the pack b.
+func readPack(t *testing.T, b []byte) (Manifest, map[string]image.Image) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var man Manifest
+	images := make(map[string]image.Image)
+
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if f.Name == ManifestFile {
+			if err := json.Unmarshal(data, &man); err != nil {
+				t.Fatalf("failed to parse manifest: %v", err)
+			}
+			continue
+		}
+
+		m, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("failed to decode %v: %v", f.Name, err)
+		}
+		images[f.Name] = m
+	}
+
+	if len(man.Images) != len(images) {
+		t.Errorf("manifest lists %v images; the pack holds %v", len(man.Images), len(images))
+	}
+	for _, im := range man.Images {
+		if images[im.File] == nil {
+			t.Errorf("manifest lists %v, which the pack does not hold", im.File)
+		}
+	}
+
+	return man, images
+}
+
+func TestExpressions(t *testing.T) {
+	tests := []struct {
+		name       string
+		background string
+		corner     color.NRGBA
+	}{
+		{"transparent", "", color.NRGBA{}},
+		{"background", "#00ff00", color.NRGBA{G: 0xff, A: 0xff}},
+	}
+
+	for _, test := range tests {
+		g := testGopher(testEyes)
+		g.Background = test.background
+
+		var buf bytes.Buffer
+		if err := Expressions(&buf, g, newTestArtwork(), "gopher", 104); err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		man, images := readPack(t, buf.Bytes())
+
+		recipe, err := gopher.MarshalRecipe(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got bytes.Buffer
+		if err := json.Compact(&got, man.Recipe); err != nil {
+			t.Fatal(err)
+		}
+		if man.Name != "gopher" || man.Size != 104 || !bytes.Equal(got.Bytes(), recipe) {
+			t.Errorf("%v: manifest %v, %v, %s; want gopher, 104, %s", test.name, man.Name, man.Size, got.Bytes(), recipe)
+		}
+
+		opts := eyesOptions()
+		if len(man.Images) != len(opts) {
+			t.Fatalf("%v: %v images; want one for each of %v eyes", test.name, len(man.Images), len(opts))
+		}
+
+		for i, im := range man.Images {
+			o := opts[i]
+			if want := "gopher_" + path.Base(o); im.Name != want || im.File != want+".png" || im.Option != o {
+				t.Errorf("%v: image %+v; want %v for %v", test.name, im, want, o)
+			}
+
+			m := images[im.File]
+			if m == nil {
+				continue
+			}
+			if b := m.Bounds(); b != image.Rect(0, 0, 104, 104) {
+				t.Errorf("%v: %v is %v; want 104x104", test.name, im.File, b)
+			}
+
+			// the gopher is cropped to a square about the body, 1040 pixels
+			// wide with its margin, so that the middle of the eyes is at
+			// (52, 22) and the body just reaches the top
+			for _, c := range []struct {
+				at     image.Point
+				colour color.NRGBA
+			}{
+				{image.Pt(52, 22), eyesColour(o)},
+				{image.Pt(52, 80), bodyColour},
+				{image.Pt(52, 6), bodyColour},
+				{image.Pt(0, 0), test.corner},
+			} {
+				if got := color.NRGBAModel.Convert(m.At(c.at.X, c.at.Y)); !near(got, c.colour) {
+					t.Errorf("%v: %v is %v at %v; want %v", test.name, im.File, got, c.at, c.colour)
+				}
+			}
+		}
+	}
+}
+
+func TestExpressionsErrors(t *testing.T) {
+	if err := Expressions(&bytes.Buffer{}, testGopher(testEyes), newTestArtwork(), "gopher", 0); err == nil {
+		t.Errorf("Expressions of size 0 gave no error")
+	}
+
+	// artwork in which every option is transparent
+	blank := testArtwork{}
+	for o := range newTestArtwork() {
+		blank[o] = image.NewNRGBA(image.Rect(0, 0, gopher.Width, gopher.Height))
+	}
+	if err := Expressions(&bytes.Buffer{}, testGopher(testEyes), blank, "gopher", 128); err == nil {
+		t.Errorf("Expressions of an invisible gopher gave no error")
+	}
+
+	g := testGopher(testEyes)
+	g.Background = "green"
+	if err := Expressions(&bytes.Buffer{}, g, newTestArtwork(), "gopher", 128); err == nil {
+		t.Errorf("Expressions of a gopher with an invalid background gave no error")
+	}
+}
@@ -0,0 +1,334 @@
+package export
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+)
+
+// eyesCategory is the category whose layer is animated.
+const eyesCategory = "Eyes"
+
+// Frame is a frame of an animation of a gopher, in which only its eyes
+// change.
+type Frame struct {
+	// Eyes is the option of the Eyes category shown in the frame, or "" for
+	// the eyes chosen for the gopher.
+	Eyes string
+
+	// Blink shows the eyes closed.
+	Blink bool
+
+	Delay time.Duration
+}
+
+// MinFrameDelay is the shortest delay of a frame; many GIF viewers slow
+// shorter frames down.
+const MinFrameDelay = 20 * time.Millisecond
+
+// Animation is a named sequence of frames.
+type Animation struct {
+	Name   string
+	Frames []Frame
+}
+
+// Animations are the animations offered by default.
+var Animations = []Animation{
+	{
+		Name: "blink",
+		Frames: []Frame{
+			{Delay: 2500 * time.Millisecond},
+			{Blink: true, Delay: 120 * time.Millisecond},
+			{Delay: 1500 * time.Millisecond},
+			{Blink: true, Delay: 120 * time.Millisecond},
+		},
+	},
+	{
+		Name: "look-around",
+		Frames: []Frame{
+			{Delay: 1200 * time.Millisecond},
+			{Eyes: "020-Eyes/looking_left", Delay: 800 * time.Millisecond},
+			{Delay: 300 * time.Millisecond},
+			{Eyes: "020-Eyes/looking_right", Delay: 800 * time.Millisecond},
+			{Delay: 300 * time.Millisecond},
+			{Eyes: "020-Eyes/looking_up_no_lashes", Delay: 800 * time.Millisecond},
+			{Blink: true, Delay: 120 * time.Millisecond},
+		},
+	},
+	{
+		Name:   "cycle",
+		Frames: cycleFrames(gopher.DefaultConfig, 500*time.Millisecond),
+	},
+}
+
+// cycleFrames returns a frame for each option of the Eyes category of c.
+func cycleFrames(c *gopher.Config, d time.Duration) []Frame {
+	_, cat := c.Category(eyesCategory)
+	if cat == nil {
+		return nil
+	}
+
+	var res []Frame
+	for _, o := range cat.Options {
+		if o != "" {
+			res = append(res, Frame{Eyes: o, Delay: d})
+		}
+	}
+	return res
+}
+
+// LookupAnimation returns the animation with the given name.
+func LookupAnimation(name string) (Animation, error) {
+	for _, a := range Animations {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return Animation{}, fmt.Errorf("unknown animation %q", name)
+}
+
+// ParseFrames parses a comma separated sequence of frames, each of the form
+// name[:ms], where name is "current" for the eyes of the gopher, "blink" for
+// those eyes closed, or the name of an option of the Eyes category of c, with
+// or without its directory. The delay of a frame defaults to 500ms.
+//
+// For example:
+//
+//	current:2000,blink:120,looking_left:800
+func ParseFrames(s string, c *gopher.Config) ([]Frame, error) {
+	_, cat := c.Category(eyesCategory)
+	if cat == nil {
+		return nil, fmt.Errorf("no %v category", eyesCategory)
+	}
+
+	var res []Frame
+
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		fr := Frame{Delay: 500 * time.Millisecond}
+
+		name := f
+		if i := strings.Index(f, ":"); i != -1 {
+			ms, err := strconv.Atoi(f[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid delay in frame %q", f)
+			}
+			name, fr.Delay = f[:i], time.Duration(ms)*time.Millisecond
+		}
+
+		switch name {
+		case "current":
+		case "blink":
+			fr.Blink = true
+		default:
+			for _, o := range cat.Options {
+				if o != "" && (o == name || path.Base(o) == name) {
+					fr.Eyes = o
+				}
+			}
+			if fr.Eyes == "" {
+				return nil, fmt.Errorf("unknown eyes %q in frame %q", name, f)
+			}
+		}
+
+		res = append(res, fr)
+	}
+
+	return res, nil
+}
+
+// GIFOptions describe an animated GIF.
+type GIFOptions struct {
+	Frames []Frame
+
+	// Width is the width in pixels of the GIF; its height follows from the
+	// aspect ratio of the artwork. Zero means full size.
+	Width int
+
+	// Colours is the size of the palette shared by all frames, at most 256,
+	// including a transparent colour if the gopher has no background. Zero
+	// means 256.
+	Colours int
+
+	// Dither diffuses the error of mapping each pixel to the palette, which
+	// helps uploads with smooth gradients but adds noise to flat artwork.
+	Dither bool
+}
+
+// GIF writes an animated GIF of g, its eyes changing as described by the
+// frames of o. The animation loops forever.
+//
+// GIF supports only fully transparent pixels, so a gopher without a
+// background has hard edges; give it a background for smooth ones.
+func GIF(w io.Writer, g *gopher.Gopher, a render.Artwork, o GIFOptions) error {
+	if len(o.Frames) == 0 {
+		return fmt.Errorf("no frames")
+	}
+	if o.Width < 0 || o.Width > gopher.Width {
+		return fmt.Errorf("invalid width %v", o.Width)
+	}
+	if o.Colours < 0 || o.Colours > 256 {
+		return fmt.Errorf("invalid number of colours %v", o.Colours)
+	}
+
+	width, colours := o.Width, o.Colours
+	if width == 0 {
+		width = gopher.Width
+	}
+	if colours == 0 {
+		colours = 256
+	}
+	height := width * gopher.Height / gopher.Width
+
+	// frames that show the same eyes are drawn once
+	images := make(map[Frame]*image.NRGBA)
+	var order []*image.NRGBA
+
+	for _, f := range o.Frames {
+		if f.Delay < MinFrameDelay {
+			return fmt.Errorf("frame delay %v is shorter than %v", f.Delay, MinFrameDelay)
+		}
+
+		k := Frame{Eyes: f.Eyes, Blink: f.Blink}
+		m, ok := images[k]
+		if !ok {
+			var err error
+			m, err = eyesFrame(g, a, k)
+			if err != nil {
+				return err
+			}
+			if width != gopher.Width {
+				m = render.Resize(m, width, height)
+			}
+			images[k] = m
+		}
+		order = append(order, m)
+	}
+
+	var distinct []*image.NRGBA
+	for _, m := range images {
+		distinct = append(distinct, m)
+	}
+
+	pal := quantize(distinct, colours)
+
+	res := &gif.GIF{}
+
+	for i, m := range order {
+		p := image.NewPaletted(m.Bounds(), pal)
+
+		// threshold the alpha first, so that only the colours of opaque
+		// pixels are matched to the palette
+		src := image.NewNRGBA(m.Bounds())
+		for j := 0; j < len(m.Pix); j += 4 {
+			if m.Pix[j+3] >= 0x80 {
+				copy(src.Pix[j:j+3], m.Pix[j:j+3])
+				src.Pix[j+3] = 0xff
+			}
+		}
+
+		if o.Dither {
+			draw.FloydSteinberg.Draw(p, p.Bounds(), src, image.Point{})
+		} else {
+			draw.Draw(p, p.Bounds(), src, image.Point{}, draw.Src)
+		}
+
+		res.Image = append(res.Image, p)
+		res.Delay = append(res.Delay, int(math.Round(float64(o.Frames[i].Delay)/float64(10*time.Millisecond))))
+		res.Disposal = append(res.Disposal, gif.DisposalBackground)
+	}
+
+	return gif.EncodeAll(w, res)
+}
+
+// eyesFrame composes g with its eyes as described by f.
+func eyesFrame(g *gopher.Gopher, a render.Artwork, f Frame) (*image.NRGBA, error) {
+	i, _ := gopher.DefaultConfig.Category(eyesCategory)
+
+	var current string
+	if i != -1 && i < len(g.Parts) && len(g.Parts[i]) > 0 {
+		current = g.Parts[i][0]
+	}
+
+	if current == "" {
+		// the gopher has no eyes, so give it some in the usual place
+		if i == -1 || f.Eyes == "" {
+			return render.Compose(g, a)
+		}
+		g = g.Copy()
+		g.Choose(i, f.Eyes, 1)
+		current = f.Eyes
+	}
+
+	to := current
+	if f.Eyes != "" {
+		to = f.Eyes
+	}
+
+	return render.Compose(g, eyesArtwork{Artwork: a, from: current, to: to, blink: f.Blink})
+}
+
+// eyesArtwork is an Artwork in which the layer of the option from is
+// replaced by that of to, closed if blink is set. Replacing the layer rather
+// than the choice of option keeps any transform and place in the layer order
+// of the original eyes.
+type eyesArtwork struct {
+	render.Artwork
+
+	from, to string
+	blink    bool
+}
+
+func (e eyesArtwork) Layer(o string) (image.Image, error) {
+	if o != e.from {
+		return e.Artwork.Layer(o)
+	}
+
+	l, err := e.Artwork.Layer(e.to)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.blink {
+		return closeEyes(l), nil
+	}
+
+	return l, nil
+}
+
+// closedEyes is the height of closed eyes as a fraction of that of open
+// eyes.
+const closedEyes = 0.12
+
+// closeEyes returns the eyes layer l squashed vertically about the middle of
+// its opaque area, so that the eyes appear closed.
+func closeEyes(l image.Image) image.Image {
+	b := l.Bounds()
+
+	full := image.NewNRGBA(b)
+	draw.Draw(full, b, l, b.Min, draw.Src)
+
+	opaque := render.OpaqueBounds(full)
+	if opaque.Empty() {
+		return l
+	}
+
+	h := int(math.Max(1, math.Round(float64(opaque.Dy())*closedEyes)))
+	closed := render.Resize(full.SubImage(opaque), opaque.Dx(), h)
+
+	res := image.NewNRGBA(b)
+	at := image.Pt(opaque.Min.X, opaque.Min.Y+(opaque.Dy()-h)/2)
+	draw.Draw(res, closed.Bounds().Add(at), closed, image.Point{}, draw.Src)
+
+	return res
+}
@@ -0,0 +1,241 @@
+package export
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"reflect"
+	"testing"
+	"time"
+
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+)
+
+func TestLookupAnimation(t *testing.T) {
+	for _, a := range Animations {
+		got, err := LookupAnimation(a.Name)
+		if err != nil || !reflect.DeepEqual(got, a) {
+			t.Errorf("LookupAnimation(%q) = %v, %v; want %v", a.Name, got, err, a)
+		}
+		if len(a.Frames) == 0 {
+			t.Errorf("animation %q has no frames", a.Name)
+		}
+	}
+
+	if _, err := LookupAnimation("dance"); err == nil {
+		t.Errorf("LookupAnimation of an unknown animation gave no error")
+	}
+}
+
+func TestCycleFrames(t *testing.T) {
+	fs := cycleFrames(gopher.DefaultConfig, time.Second)
+
+	var eyes []string
+	for _, f := range fs {
+		if f.Delay != time.Second || f.Blink {
+			t.Errorf("cycle frame %+v; want open eyes for a second", f)
+		}
+		eyes = append(eyes, f.Eyes)
+	}
+	if !reflect.DeepEqual(eyes, eyesOptions()) {
+		t.Errorf("cycle frames show %q; want %q", eyes, eyesOptions())
+	}
+
+	if fs := cycleFrames(&gopher.Config{}, time.Second); fs != nil {
+		t.Errorf("cycle frames of a catalogue without eyes %v; want none", fs)
+	}
+}
+
+func TestParseFrames(t *testing.T) {
+	const ms = time.Millisecond
+
+	tests := []struct {
+		in   string
+		want []Frame
+	}{
+		{"current", []Frame{{Delay: 500 * ms}}},
+		{
+			"current:2000,blink:120,looking_left:800",
+			[]Frame{{Delay: 2000 * ms}, {Blink: true, Delay: 120 * ms}, {Eyes: "020-Eyes/looking_left", Delay: 800 * ms}},
+		},
+		{" 020-Eyes/eyes , blink ", []Frame{{Eyes: "020-Eyes/eyes", Delay: 500 * ms}, {Blink: true, Delay: 500 * ms}}},
+		{"", nil},
+		{"sleepy", nil},
+		{"010-Body/blue_gopher", nil},
+		{"blink:", nil},
+		{"blink:soon", nil},
+		{"current,", nil},
+	}
+
+	for _, test := range tests {
+		got, err := ParseFrames(test.in, gopher.DefaultConfig)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("ParseFrames(%q) = %v; want an error", test.in, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseFrames(%q) = %v, %v; want %v", test.in, got, err, test.want)
+		}
+	}
+}
+
+// near reports whether each channel of a is within 0x20 of that of b.
+func near(a, b color.Color) bool {
+	na := color.NRGBAModel.Convert(a).(color.NRGBA)
+	nb := color.NRGBAModel.Convert(b).(color.NRGBA)
+	d := func(x, y uint8) bool {
+		return abs(int(x)-int(y)) < 0x20
+	}
+	return d(na.R, nb.R) && d(na.G, nb.G) && d(na.B, nb.B) && d(na.A, nb.A)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func TestGIF(t *testing.T) {
+	const ms = time.Millisecond
+
+	o := GIFOptions{
+		Frames: []Frame{
+			{Delay: 1000 * ms},
+			{Blink: true, Delay: 120 * ms},
+			{Eyes: "020-Eyes/looking_left", Delay: 805 * ms},
+			{Delay: 20 * ms},
+		},
+		Width:   130,
+		Colours: 16,
+	}
+
+	var buf bytes.Buffer
+	if err := GIF(&buf, testGopher(testEyes), newTestArtwork(), o); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int{100, 12, 81, 2}; !reflect.DeepEqual(g.Delay, want) {
+		t.Errorf("delays %v; want %v", g.Delay, want)
+	}
+	if g.LoopCount != 0 {
+		t.Errorf("loop count %v; want 0, forever", g.LoopCount)
+	}
+	if len(g.Image) != 4 {
+		t.Fatalf("%v frames; want 4", len(g.Image))
+	}
+
+	// at a tenth of the size, the top of the eyes, which is body when they
+	// are closed
+	at := image.Pt(65, 34)
+
+	tests := []struct {
+		eyes color.Color
+	}{
+		{eyesColour(testEyes)},
+		{bodyColour},
+		{eyesColour("020-Eyes/looking_left")},
+		{eyesColour(testEyes)},
+	}
+
+	for i, test := range tests {
+		m := g.Image[i]
+		if b := m.Bounds(); b != image.Rect(0, 0, 130, 139) {
+			t.Errorf("frame %v has bounds %v; want 130x139", i, b)
+		}
+		if len(m.Palette) > 16 {
+			t.Errorf("frame %v has %v colours; want at most 16", i, len(m.Palette))
+		}
+		if got := m.At(at.X, at.Y); !near(got, test.eyes) {
+			t.Errorf("frame %v is %v at %v; want %v", i, got, at, test.eyes)
+		}
+		if got := m.At(65, 100); !near(got, bodyColour) {
+			t.Errorf("frame %v is %v at the body; want %v", i, got, bodyColour)
+		}
+		if _, _, _, a := m.At(0, 0).RGBA(); a != 0 {
+			t.Errorf("frame %v is opaque at its corner; want transparent", i)
+		}
+	}
+}
+
+func TestGIFNoEyes(t *testing.T) {
+	o := GIFOptions{
+		Frames: []Frame{{Delay: time.Second}, {Eyes: "020-Eyes/looking_left", Delay: time.Second}},
+		Width:  130,
+	}
+
+	var buf bytes.Buffer
+	if err := GIF(&buf, testGopher(""), newTestArtwork(), o); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a gopher without eyes is given those of a frame that has some
+	want := []color.Color{bodyColour, eyesColour("020-Eyes/looking_left")}
+	for i, m := range g.Image {
+		if got := m.At(65, 40); !near(got, want[i]) {
+			t.Errorf("frame %v is %v within the eyes; want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestGIFErrors(t *testing.T) {
+	frames := []Frame{{Delay: time.Second}}
+
+	tests := []struct {
+		name string
+		o    GIFOptions
+	}{
+		{"no frames", GIFOptions{}},
+		{"short delay", GIFOptions{Frames: []Frame{{Delay: 10 * time.Millisecond}}}},
+		{"negative width", GIFOptions{Frames: frames, Width: -1}},
+		{"too wide", GIFOptions{Frames: frames, Width: gopher.Width + 1}},
+		{"too many colours", GIFOptions{Frames: frames, Colours: 257}},
+	}
+
+	for _, test := range tests {
+		if err := GIF(&bytes.Buffer{}, testGopher(testEyes), newTestArtwork(), test.o); err == nil {
+			t.Errorf("%v: GIF gave no error", test.name)
+		}
+	}
+
+	// eyes for which there is no artwork
+	a := newTestArtwork()
+	delete(a, "020-Eyes/looking_left")
+	o := GIFOptions{Frames: []Frame{{Eyes: "020-Eyes/looking_left", Delay: time.Second}}}
+	if err := GIF(&bytes.Buffer{}, testGopher(testEyes), a, o); err == nil {
+		t.Errorf("GIF with missing artwork gave no error")
+	}
+}
+
+func TestCloseEyes(t *testing.T) {
+	l := layer(eyesRect, bodyColour)
+	closed := closeEyes(l)
+
+	if got := closed.Bounds(); got != l.Bounds() {
+		t.Errorf("closed eyes have bounds %v; want %v", got, l.Bounds())
+	}
+
+	// 12% of the 200 pixel high eyes, about their middle
+	want := image.Rect(eyesRect.Min.X, 388, eyesRect.Max.X, 412)
+	if got := render.OpaqueBounds(closed.(*image.NRGBA)); got != want {
+		t.Errorf("closed eyes cover %v; want %v", got, want)
+	}
+
+	empty := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	if got := closeEyes(empty); got != image.Image(empty) {
+		t.Errorf("closing transparent eyes gave a new image")
+	}
+}
@@ -0,0 +1,156 @@
+package export
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+)
+
+// MosaicMember is a gopher of a mosaic, with the name shown beneath it.
+type MosaicMember struct {
+	Name   string
+	Gopher *gopher.Gopher
+}
+
+// MosaicOptions describe the layout of a mosaic drawn by Mosaic.
+type MosaicOptions struct {
+	// Columns is the number of gophers in each row, zero for as many as
+	// make the mosaic roughly square.
+	Columns int
+
+	// Width is the width in pixels of each gopher.
+	Width int
+
+	// Spacing is the gap in pixels between gophers, and around the edge of
+	// the mosaic.
+	Spacing int
+
+	// Background is the colour of the mosaic, nil for transparent.
+	Background color.Color
+
+	// Captions shows the name of each member beneath its gopher, in the
+	// colour CaptionColour or, if that is nil, in dark or light as suits the
+	// background.
+	Captions      bool
+	CaptionColour color.Color
+}
+
+// DefaultMosaicOptions are the options of a captioned team photo on white.
+var DefaultMosaicOptions = MosaicOptions{
+	Width:      256,
+	Spacing:    16,
+	Background: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	Captions:   true,
+}
+
+// MosaicSize returns the width and height in pixels of the mosaic of n
+// gophers laid out as described by o, and the number of columns it has.
+func MosaicSize(n int, o MosaicOptions) (width, height, columns int) {
+	if n == 0 {
+		return 0, 0, 0
+	}
+
+	cols := o.Columns
+	if cols <= 0 {
+		cols = int(math.Ceil(math.Sqrt(float64(n))))
+	}
+	if cols > n {
+		cols = n
+	}
+	rows := (n + cols - 1) / cols
+
+	cellW, cellH := mosaicCell(o)
+
+	return cols*cellW + (cols+1)*o.Spacing, rows*cellH + (rows+1)*o.Spacing, cols
+}
+
+// mosaicCell returns the size of the area of a mosaic taken by each gopher
+// and its caption.
+func mosaicCell(o MosaicOptions) (width, height int) {
+	height = o.Width * gopher.Height / gopher.Width
+	if o.Captions {
+		height += captionHeight(o)
+	}
+	return o.Width, height
+}
+
+func captionHeight(o MosaicOptions) int {
+	return o.Width / 8
+}
+
+// Mosaic draws the gophers of ms in a grid, in rows from left to right, with
+// any incomplete last row centred. Each gopher is composed as by
+// render.Compose, so appears with its own background and effects.
+func Mosaic(ms []MosaicMember, a render.Artwork, o MosaicOptions) (*image.NRGBA, error) {
+	if len(ms) == 0 {
+		return nil, fmt.Errorf("no gophers")
+	}
+	if o.Width <= 0 {
+		return nil, fmt.Errorf("invalid width %v", o.Width)
+	}
+	if o.Spacing < 0 {
+		return nil, fmt.Errorf("invalid spacing %v", o.Spacing)
+	}
+
+	width, height, cols := MosaicSize(len(ms), o)
+	cellW, cellH := mosaicCell(o)
+	gopherH := o.Width * gopher.Height / gopher.Width
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if o.Background != nil {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(o.Background), image.Point{}, draw.Src)
+	}
+
+	caption := o.CaptionColour
+	if caption == nil {
+		caption = captionColour(o.Background)
+	}
+
+	for i, m := range ms {
+		row, col := i/cols, i%cols
+
+		// centre the last row if it is incomplete
+		inRow := cols
+		if rest := len(ms) - row*cols; rest < cols {
+			inRow = rest
+		}
+		left := (width - inRow*cellW - (inRow-1)*o.Spacing) / 2
+
+		p := image.Pt(left+col*(cellW+o.Spacing), o.Spacing+row*(cellH+o.Spacing))
+
+		g, err := render.Compose(m.Gopher, a)
+		if err != nil {
+			return nil, fmt.Errorf("failed to draw gopher of %q: %v", m.Name, err)
+		}
+		g = render.Resize(g, o.Width, gopherH)
+		draw.Draw(dst, g.Bounds().Add(p), g, image.Point{}, draw.Over)
+
+		if o.Captions && m.Name != "" {
+			c := render.Caption(m.Name, gopher.FontBold, cellW, captionHeight(o), caption)
+			draw.Draw(dst, c.Bounds().Add(p.Add(image.Pt(0, gopherH))), c, image.Point{}, draw.Over)
+		}
+	}
+
+	return dst, nil
+}
+
+// captionColour returns the colour of captions drawn on bg: dark on light
+// or transparent backgrounds, and light on dark ones.
+func captionColour(bg color.Color) color.Color {
+	dark := color.NRGBA{R: 0x22, G: 0x22, B: 0x22, A: 0xff}
+	if bg == nil {
+		return dark
+	}
+
+	r, g, b, a := bg.RGBA()
+	if a < 0x8000 || 0.299*float64(r)+0.587*float64(g)+0.114*float64(b) >= 0x8000 {
+		return dark
+	}
+
+	return color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+}
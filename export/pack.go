@@ -0,0 +1,95 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"image"
+	"io"
+)
+
+// Manifest describes the images of a pack, a zip archive of images such as
+// that written by Expressions or Stickers.
+type Manifest struct {
+	Name   string
+	Size   int
+	Images []ManifestImage
+
+	// Recipe is the recipe of the gopher from which the pack was made, if
+	// all of its images were made from the one gopher.
+	Recipe json.RawMessage `json:",omitempty"`
+}
+
+// ManifestImage describes an image of a pack.
+type ManifestImage struct {
+	File string
+
+	// Name is the short name of the image, suitable as the name of an
+	// emoji or sticker.
+	Name string
+
+	// Option is the option of the catalogue that distinguishes the image
+	// from others made from the same gopher.
+	Option string `json:",omitempty"`
+
+	// Recipe is the recipe of the gopher shown by the image, if the images
+	// of the pack were made from different gophers.
+	Recipe json.RawMessage `json:",omitempty"`
+}
+
+// ManifestFile is the name of the manifest within a pack.
+const ManifestFile = "manifest.json"
+
+// pack writes the images of a pack, followed by its manifest.
+type pack struct {
+	zw  *zip.Writer
+	man Manifest
+}
+
+func newPack(w io.Writer, man Manifest) *pack {
+	return &pack{
+		zw:  zip.NewWriter(w),
+		man: man,
+	}
+}
+
+// add writes m as a PNG named after im, recording im in the manifest. The
+// PNG carries the recipe of im, or failing that that of the pack.
+func (p *pack) add(im ManifestImage, m image.Image) error {
+	im.File = im.Name + ".png"
+
+	f, err := p.zw.Create(im.File)
+	if err != nil {
+		return err
+	}
+
+	recipe := im.Recipe
+	if recipe == nil {
+		recipe = p.man.Recipe
+	}
+
+	if err := encodePNG(f, m, RecipeKeyword, recipe); err != nil {
+		return err
+	}
+
+	p.man.Images = append(p.man.Images, im)
+
+	return nil
+}
+
+// close writes the manifest and completes the archive.
+func (p *pack) close() error {
+	f, err := p.zw.Create(ManifestFile)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(p.man, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+
+	return p.zw.Close()
+}
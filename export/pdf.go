@@ -0,0 +1,188 @@
+package export
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/pdf"
+	"myitcv.io/gopherize.me/render"
+)
+
+// Sheet is a paper size, in millimetres, on which gophers can be laid out
+// for printing.
+type Sheet struct {
+	Name          string
+	Width, Height float64
+}
+
+// Sheets are the paper sizes known to PDF.
+var Sheets = []Sheet{
+	{Name: "A4", Width: 210, Height: 297},
+	{Name: "Letter", Width: 215.9, Height: 279.4},
+}
+
+// LookupSheet returns the sheet with the given name, ignoring case.
+func LookupSheet(name string) (Sheet, error) {
+	for _, s := range Sheets {
+		if strings.EqualFold(s.Name, name) {
+			return s, nil
+		}
+	}
+	return Sheet{}, fmt.Errorf("unknown sheet %q", name)
+}
+
+// sheetMargin is the minimum margin, in millimetres, left around the gophers
+// on a sheet; sheetGutter is the space between them.
+const (
+	sheetMargin = 10
+	sheetGutter = 4
+)
+
+// PDFOptions describe the physical layout of a PDF.
+type PDFOptions struct {
+	// Size is the width in millimetres of the trimmed gopher; its height
+	// follows from the aspect ratio of the artwork.
+	Size float64
+
+	// Bleed is the distance in millimetres by which the background of the
+	// gopher extends beyond the cut.
+	Bleed float64
+
+	// CutLines draws a hairline along each cut.
+	CutLines bool
+
+	// Sheet, if set, lays out Columns x Rows copies of the gopher on a page
+	// of that size. A zero Columns or Rows fits as many as the sheet allows.
+	Sheet         *Sheet
+	Columns, Rows int
+}
+
+// cutColour is the colour of cut lines, a registration magenta that printers
+// recognise as not being part of the artwork.
+var cutColour = color.NRGBA{R: 0xff, B: 0xff, A: 0xff}
+
+// PDF writes g as a print-ready PDF document, for stickers and badges, laid
+// out as described by o.
+//
+// Without a sheet the page is the size of the gopher plus its bleed, with
+// the trim and bleed boxes set accordingly. The gopher is drawn at the
+// trim size; only the background extends into the bleed.
+func PDF(w io.Writer, g *gopher.Gopher, a render.Artwork, o PDFOptions) error {
+	if o.Size <= 0 {
+		return fmt.Errorf("invalid size %vmm", o.Size)
+	}
+	if o.Bleed < 0 {
+		return fmt.Errorf("invalid bleed %vmm", o.Bleed)
+	}
+	if o.Columns < 0 || o.Rows < 0 {
+		return fmt.Errorf("invalid layout %vx%v", o.Columns, o.Rows)
+	}
+
+	var bg color.Color
+	if g.Background != "" {
+		c, err := gopher.ParseColour(g.Background)
+		if err != nil {
+			return err
+		}
+		bg = c
+	}
+
+	// the background is drawn separately, so that it can extend into the
+	// bleed
+	fg := g.Copy()
+	fg.Background = ""
+
+	m, err := render.Compose(fg, a)
+	if err != nil {
+		return err
+	}
+
+	trimW := pdf.MM(o.Size)
+	trimH := trimW * gopher.Height / gopher.Width
+	bleed := pdf.MM(o.Bleed)
+
+	cellW, cellH := trimW+2*bleed, trimH+2*bleed
+
+	var page *pdf.Page
+	var cells []pdf.Rect
+
+	if o.Sheet == nil {
+		page = pdf.NewPage(cellW, cellH)
+		cells = []pdf.Rect{{W: cellW, H: cellH}}
+
+		trim := cells[0].Inset(bleed)
+		page.TrimBox = &trim
+		page.BleedBox = &cells[0]
+	} else {
+		pageW, pageH := pdf.MM(o.Sheet.Width), pdf.MM(o.Sheet.Height)
+		margin, gutter := pdf.MM(sheetMargin), pdf.MM(sheetGutter)
+
+		fit := func(n int, avail, cell float64) int {
+			if n == 0 {
+				n = int((avail - 2*margin + gutter) / (cell + gutter))
+			}
+			return n
+		}
+
+		cols := fit(o.Columns, pageW, cellW)
+		rows := fit(o.Rows, pageH, cellH)
+
+		usedW := float64(cols)*cellW + float64(cols-1)*gutter
+		usedH := float64(rows)*cellH + float64(rows-1)*gutter
+
+		if cols < 1 || rows < 1 || usedW > pageW-2*margin || usedH > pageH-2*margin {
+			return fmt.Errorf("%vx%v gophers of %vmm do not fit on %v", maxInt(cols, 1), maxInt(rows, 1), o.Size, o.Sheet.Name)
+		}
+
+		page = pdf.NewPage(pageW, pageH)
+
+		x0, y0 := (pageW-usedW)/2, (pageH-usedH)/2
+
+		// cells are laid out from the top left, as they are read
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				cells = append(cells, pdf.Rect{
+					X: x0 + float64(c)*(cellW+gutter),
+					Y: y0 + usedH - float64(r+1)*cellH - float64(r)*gutter,
+					W: cellW,
+					H: cellH,
+				})
+			}
+		}
+	}
+
+	pw := pdf.NewWriter(w)
+
+	img, err := pw.Image(m)
+	if err != nil {
+		return err
+	}
+
+	for _, cell := range cells {
+		trim := cell.Inset(bleed)
+
+		if bg != nil {
+			page.FillRect(cell, bg)
+		}
+		page.DrawImage(img, trim)
+		if o.CutLines {
+			page.StrokeRect(trim, cutColour, 0.25)
+		}
+	}
+
+	if err := pw.AddPage(page); err != nil {
+		return err
+	}
+
+	return pw.Close()
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,262 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+// RecipeKeyword is the keyword of the PNG text chunk in which PNG and the
+// other exports of PNG images record the recipe of the gopher shown.
+const RecipeKeyword = "gopherize.me"
+
+// SceneKeyword is the keyword of the PNG text chunk in which ScenePNG
+// records the scene shown.
+const SceneKeyword = "gopherize.me/scene"
+
+// MaxRecipeSize is the largest recipe or scene, in bytes, read from a PNG
+// image; recipes with uploads carry their images.
+const MaxRecipeSize = 8 << 20
+
+// ErrNoRecipe is returned by ReadRecipe for a PNG image without a recipe.
+var ErrNoRecipe = errors.New("image has no gopherize.me recipe")
+
+// ErrNoScene is returned by ReadScene for a PNG image without a scene.
+var ErrNoScene = errors.New("image has no gopherize.me scene")
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// PNG writes m as a PNG image that carries the recipe of g, from which it
+// can be read back by ReadPNG.
+func PNG(w io.Writer, m image.Image, g *gopher.Gopher) error {
+	recipe, err := gopher.MarshalRecipe(g)
+	if err != nil {
+		return err
+	}
+
+	return encodePNG(w, m, RecipeKeyword, recipe)
+}
+
+// ScenePNG writes m as a PNG image that carries the scene s, from which it
+// can be read back by ReadScene.
+func ScenePNG(w io.Writer, m image.Image, s *gopher.Scene) error {
+	b, err := gopher.MarshalScene(s)
+	if err != nil {
+		return err
+	}
+
+	return encodePNG(w, m, SceneKeyword, b)
+}
+
+// encodePNG writes m as a PNG image, with text in a compressed iTXt chunk
+// with the given keyword if it is not empty. The chunk follows the image
+// header, so that the text is found without reading the image data.
+func encodePNG(w io.Writer, m image.Image, keyword string, text []byte) error {
+	if len(text) == 0 {
+		return png.Encode(w, m)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		return err
+	}
+
+	// the signature is followed by the header chunk of 13 bytes of data
+	// plus 12 of length, type and checksum
+	b := buf.Bytes()
+	head := len(pngSignature) + 13 + 12
+
+	// keyword, null separator, compression flag and method, and empty
+	// language tag and translated keyword
+	var data bytes.Buffer
+	data.WriteString(keyword)
+	data.Write([]byte{0, 1, 0, 0, 0})
+
+	zw := zlib.NewWriter(&data)
+	if _, err := zw.Write(text); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(b[:head]); err != nil {
+		return err
+	}
+	if err := writeChunk(w, "iTXt", data.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(b[head:])
+
+	return err
+}
+
+func writeChunk(w io.Writer, typ string, data []byte) error {
+	var hdr [8]byte
+	binary.BigEndian.PutUint32(hdr[:4], uint32(len(data)))
+	copy(hdr[4:], typ)
+
+	crc := crc32.NewIEEE()
+	crc.Write(hdr[4:])
+	crc.Write(data)
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+
+	for _, b := range [][]byte{hdr[:], data, sum[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadRecipe returns the recipe recorded in the PNG image read from r, in
+// either a tEXt or an iTXt chunk with the keyword RecipeKeyword. It returns
+// ErrNoRecipe if there is no such chunk.
+func ReadRecipe(r io.Reader) ([]byte, error) {
+	b, err := readText(r, RecipeKeyword)
+	if err == errNoText {
+		return nil, ErrNoRecipe
+	}
+	return b, err
+}
+
+// errNoText is returned by readText when there is no chunk with the keyword
+// sought.
+var errNoText = errors.New("no text chunk")
+
+// readText returns the text of the first tEXt or iTXt chunk with the given
+// keyword in the PNG image read from r.
+func readText(r io.Reader, keyword string) ([]byte, error) {
+	sig := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, sig); err != nil || !bytes.Equal(sig, pngSignature) {
+		return nil, fmt.Errorf("not a PNG image")
+	}
+
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, fmt.Errorf("failed to read PNG chunk: %v", err)
+		}
+
+		n := binary.BigEndian.Uint32(hdr[:4])
+		typ := string(hdr[4:])
+
+		if typ == "IEND" {
+			return nil, errNoText
+		}
+
+		if typ != "tEXt" && typ != "iTXt" {
+			if _, err := io.CopyN(ioutil.Discard, r, int64(n)+4); err != nil {
+				return nil, fmt.Errorf("failed to read PNG chunk %v: %v", typ, err)
+			}
+			continue
+		}
+
+		if n > MaxRecipeSize {
+			return nil, fmt.Errorf("PNG chunk %v is %v bytes; at most %v allowed", typ, n, MaxRecipeSize)
+		}
+
+		data := make([]byte, int(n)+4)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("failed to read PNG chunk %v: %v", typ, err)
+		}
+		data, sum := data[:n], data[n:]
+
+		crc := crc32.NewIEEE()
+		crc.Write(hdr[4:])
+		crc.Write(data)
+		if crc.Sum32() != binary.BigEndian.Uint32(sum) {
+			return nil, fmt.Errorf("corrupt PNG chunk %v", typ)
+		}
+
+		i := bytes.IndexByte(data, 0)
+		if i == -1 || string(data[:i]) != keyword {
+			continue
+		}
+		text := data[i+1:]
+
+		if typ == "tEXt" {
+			return text, nil
+		}
+
+		return iTXtText(text)
+	}
+}
+
+// iTXtText returns the text of an iTXt chunk, given its data following the
+// keyword.
+func iTXtText(b []byte) ([]byte, error) {
+	bad := fmt.Errorf("invalid iTXt chunk")
+
+	if len(b) < 2 {
+		return nil, bad
+	}
+	compressed := b[0] == 1
+	b = b[2:]
+
+	// skip the language tag and translated keyword
+	for i := 0; i < 2; i++ {
+		j := bytes.IndexByte(b, 0)
+		if j == -1 {
+			return nil, bad
+		}
+		b = b[j+1:]
+	}
+
+	if !compressed {
+		return b, nil
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress iTXt chunk: %v", err)
+	}
+	defer zr.Close()
+
+	res, err := ioutil.ReadAll(io.LimitReader(zr, MaxRecipeSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress iTXt chunk: %v", err)
+	}
+	if len(res) > MaxRecipeSize {
+		return nil, fmt.Errorf("iTXt chunk decompresses to more than %v bytes", MaxRecipeSize)
+	}
+
+	return res, nil
+}
+
+// ReadPNG returns the gopher whose recipe is recorded in the PNG image read
+// from r, validated against c.
+func ReadPNG(r io.Reader, c *gopher.Config) (*gopher.Gopher, error) {
+	b, err := ReadRecipe(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return gopher.UnmarshalRecipe(b, c)
+}
+
+// ReadScene returns the scene recorded in the PNG image read from r by
+// ScenePNG, validated against c. It returns ErrNoScene if there is none.
+func ReadScene(r io.Reader, c *gopher.Config) (*gopher.Scene, error) {
+	b, err := readText(r, SceneKeyword)
+	if err == errNoText {
+		return nil, ErrNoScene
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return gopher.UnmarshalScene(b, c)
+}
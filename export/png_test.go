@@ -0,0 +1,118 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"reflect"
+	"testing"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+// testPNG returns a PNG image made of the given chunks, each a type followed
+// by its data, after the signature and before IEND.
+func testPNG(t *testing.T, chunks ...[]byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	for _, c := range chunks {
+		if err := writeChunk(&buf, string(c[:4]), c[4:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeChunk(&buf, "IEND", nil)
+	return buf.Bytes()
+}
+
+func TestReadRecipeRoundTrip(t *testing.T) {
+	recipe := []byte(`{"Parts":[["010-Body/blue_gopher"]]}`)
+
+	var buf bytes.Buffer
+	if err := encodePNG(&buf, image.NewNRGBA(image.Rect(0, 0, 1, 1)), RecipeKeyword, recipe); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadRecipe(&buf)
+	if err != nil {
+		t.Fatalf("ReadRecipe: %v", err)
+	}
+	if !bytes.Equal(got, recipe) {
+		t.Errorf("ReadRecipe = %q; want %q", got, recipe)
+	}
+}
+
+func TestReadRecipeTEXt(t *testing.T) {
+	b := testPNG(t, []byte("tEXtother\x00x"), []byte("tEXt"+RecipeKeyword+"\x00{}"))
+
+	got, err := ReadRecipe(bytes.NewReader(b))
+	if err != nil || string(got) != "{}" {
+		t.Errorf("ReadRecipe = %q, %v; want {}", got, err)
+	}
+}
+
+func TestReadRecipeNone(t *testing.T) {
+	b := testPNG(t, []byte("tEXtother\x00x"))
+
+	if _, err := ReadRecipe(bytes.NewReader(b)); err != ErrNoRecipe {
+		t.Errorf("ReadRecipe gave error %v; want ErrNoRecipe", err)
+	}
+}
+
+func TestReadRecipeChunkTooLarge(t *testing.T) {
+	// a chunk header claiming almost 4GB, with no data
+	var b []byte
+	b = append(b, pngSignature...)
+	b = append(b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[len(b)-4:], 0xfffffff0)
+	b = append(b, "iTXt"...)
+
+	if _, err := ReadRecipe(bytes.NewReader(b)); err == nil {
+		t.Errorf("no error reading an oversized chunk")
+	}
+}
+
+func TestReadRecipeDecompressedTooLarge(t *testing.T) {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(make([]byte, MaxRecipeSize+1))
+	zw.Close()
+
+	chunk := append([]byte("iTXt"+RecipeKeyword+"\x00\x01\x00\x00\x00"), z.Bytes()...)
+	b := testPNG(t, chunk)
+
+	if _, err := ReadRecipe(bytes.NewReader(b)); err == nil {
+		t.Errorf("no error reading a chunk that decompresses to more than MaxRecipeSize")
+	}
+}
+
+func TestReadSceneRoundTrip(t *testing.T) {
+	g, err := gopher.ParseRecipeText([]byte("body=blue_gopher eyes=eyes"), gopher.DefaultConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &gopher.Scene{
+		Width:   100,
+		Height:  50,
+		Gophers: []gopher.Placement{{Gopher: g, X: 50, Y: 25, Scale: 0.1}},
+	}
+
+	var buf bytes.Buffer
+	if err := ScenePNG(&buf, image.NewNRGBA(image.Rect(0, 0, 1, 1)), s); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	got, err := ReadScene(bytes.NewReader(b), gopher.DefaultConfig)
+	if err != nil {
+		t.Fatalf("ReadScene: %v", err)
+	}
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("ReadScene = %#v; want %#v", got, s)
+	}
+
+	// a scene is not a recipe
+	if _, err := ReadRecipe(bytes.NewReader(b)); err != ErrNoRecipe {
+		t.Errorf("ReadRecipe of a scene gave error %v; want ErrNoRecipe", err)
+	}
+}
@@ -0,0 +1,159 @@
+package export
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// quantize returns a palette of at most n colours for the opaque pixels of
+// ms, chosen by median cut, followed by a transparent colour if any pixel of
+// ms is transparent. Pixels are treated as transparent if their alpha is
+// below half.
+func quantize(ms []*image.NRGBA, n int) color.Palette {
+	hist := make(map[color.NRGBA]int)
+	transparent := false
+
+	for _, m := range ms {
+		for i := 0; i < len(m.Pix); i += 4 {
+			if m.Pix[i+3] < 0x80 {
+				transparent = true
+				continue
+			}
+			hist[color.NRGBA{R: m.Pix[i], G: m.Pix[i+1], B: m.Pix[i+2], A: 0xff}]++
+		}
+	}
+
+	if transparent {
+		n--
+	}
+
+	all := make([]weighted, 0, len(hist))
+	for c, w := range hist {
+		all = append(all, weighted{c: c, w: w})
+	}
+
+	// map iteration order is random; sort so that the palette, and hence
+	// the image, is the same every time
+	sort.Slice(all, func(i, j int) bool {
+		return key(all[i].c) < key(all[j].c)
+	})
+
+	boxes := []box{all}
+
+	for len(boxes) < n {
+		// split the box with the widest range of any channel
+		bi, widest := -1, 0
+		for i, b := range boxes {
+			if len(b) < 2 {
+				continue
+			}
+			if _, r := b.channel(); r > widest {
+				bi, widest = i, r
+			}
+		}
+		if bi == -1 {
+			break
+		}
+
+		lo, hi := boxes[bi].split()
+		boxes[bi] = lo
+		boxes = append(boxes, hi)
+	}
+
+	var res color.Palette
+	for _, b := range boxes {
+		if len(b) > 0 {
+			res = append(res, b.mean())
+		}
+	}
+	if transparent {
+		res = append(res, color.NRGBA{})
+	}
+
+	return res
+}
+
+// weighted is a colour and the number of pixels of that colour.
+type weighted struct {
+	c color.NRGBA
+	w int
+}
+
+func key(c color.NRGBA) int {
+	return int(c.R)<<16 | int(c.G)<<8 | int(c.B)
+}
+
+func channel(c color.NRGBA, ch int) int {
+	switch ch {
+	case 0:
+		return int(c.R)
+	case 1:
+		return int(c.G)
+	}
+	return int(c.B)
+}
+
+// box is a set of colours that will be represented by a single colour of a
+// palette.
+type box []weighted
+
+// channel returns the channel with the widest range of values in b, and that
+// range.
+func (b box) channel() (int, int) {
+	ch, width := 0, -1
+	for c := 0; c < 3; c++ {
+		lo, hi := 0xff, 0
+		for _, w := range b {
+			v := channel(w.c, c)
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+		if hi-lo > width {
+			ch, width = c, hi-lo
+		}
+	}
+	return ch, width
+}
+
+// split divides b at the weighted median of its widest channel.
+func (b box) split() (box, box) {
+	ch, _ := b.channel()
+
+	sort.SliceStable(b, func(i, j int) bool {
+		return channel(b[i].c, ch) < channel(b[j].c, ch)
+	})
+
+	total := 0
+	for _, w := range b {
+		total += w.w
+	}
+
+	i, sum := 1, b[0].w
+	for i < len(b)-1 && sum+b[i].w <= total/2 {
+		sum += b[i].w
+		i++
+	}
+
+	return b[:i:i], b[i:]
+}
+
+func (b box) mean() color.NRGBA {
+	var r, g, bl, n int
+	for _, w := range b {
+		r += int(w.c.R) * w.w
+		g += int(w.c.G) * w.w
+		bl += int(w.c.B) * w.w
+		n += w.w
+	}
+	return color.NRGBA{
+		R: uint8((r + n/2) / n),
+		G: uint8((g + n/2) / n),
+		B: uint8((bl + n/2) / n),
+		A: 0xff,
+	}
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+// pixels returns an image a pixel high of the colours cs.
+func pixels(cs ...color.NRGBA) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, len(cs), 1))
+	for i, c := range cs {
+		m.SetNRGBA(i, 0, c)
+	}
+	return m
+}
+
+func TestQuantize(t *testing.T) {
+	var (
+		black     = color.NRGBA{A: 0xff}
+		darkRed   = color.NRGBA{R: 10, A: 0xff}
+		nearWhite = color.NRGBA{R: 250, G: 0xff, B: 0xff, A: 0xff}
+		white     = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+		clear     = color.NRGBA{}
+	)
+
+	tests := []struct {
+		name string
+		ms   []*image.NRGBA
+		n    int
+		want color.Palette
+	}{
+		{
+			"few colours",
+			[]*image.NRGBA{pixels(white, black, white)},
+			4,
+			color.Palette{black, white},
+		},
+		{
+			"transparent last",
+			[]*image.NRGBA{pixels(white, clear), pixels(black)},
+			4,
+			color.Palette{black, white, clear},
+		},
+		{
+			// alpha below half counts as transparent, and above as opaque
+			"alpha",
+			[]*image.NRGBA{pixels(color.NRGBA{R: 0xff, A: 0x7f}, color.NRGBA{G: 0xff, A: 0x80})},
+			4,
+			color.Palette{color.NRGBA{G: 0xff, A: 0xff}, clear},
+		},
+		{
+			"split",
+			[]*image.NRGBA{pixels(black, white, darkRed, nearWhite)},
+			2,
+			color.Palette{color.NRGBA{R: 5, A: 0xff}, color.NRGBA{R: 253, G: 0xff, B: 0xff, A: 0xff}},
+		},
+		{
+			// the transparent colour takes one of the n
+			"split with transparent",
+			[]*image.NRGBA{pixels(black, white, darkRed, nearWhite, clear)},
+			3,
+			color.Palette{color.NRGBA{R: 5, A: 0xff}, color.NRGBA{R: 253, G: 0xff, B: 0xff, A: 0xff}, clear},
+		},
+		{
+			// each colour counts by the number of its pixels
+			"weighted",
+			[]*image.NRGBA{pixels(black, black, black, white)},
+			1,
+			color.Palette{color.NRGBA{R: 64, G: 64, B: 64, A: 0xff}},
+		},
+		{
+			"one colour",
+			[]*image.NRGBA{pixels(black, darkRed, white)},
+			1,
+			color.Palette{color.NRGBA{R: 88, G: 85, B: 85, A: 0xff}},
+		},
+		{
+			"transparent only",
+			[]*image.NRGBA{pixels(clear)},
+			4,
+			color.Palette{clear},
+		},
+	}
+
+	for _, test := range tests {
+		got := quantize(test.ms, test.n)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: quantize = %v; want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestQuantizeStable(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for i := 0; i < len(m.Pix); i += 4 {
+		m.Pix[i], m.Pix[i+1], m.Pix[i+2], m.Pix[i+3] = uint8(i), uint8(i/7), uint8(i/13), 0xff
+	}
+
+	want := quantize([]*image.NRGBA{m}, 16)
+	if len(want) != 16 {
+		t.Errorf("quantize gave %v colours; want 16", len(want))
+	}
+	for i := 0; i < 5; i++ {
+		if got := quantize([]*image.NRGBA{m}, 16); !reflect.DeepEqual(got, want) {
+			t.Fatalf("quantize gave %v, then %v", want, got)
+		}
+	}
+}
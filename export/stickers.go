@@ -0,0 +1,154 @@
+package export
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"path"
+
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+)
+
+// StickerOptions describe the stickers written by Sticker and Stickers.
+type StickerOptions struct {
+	// Size is the width and height of a sticker in pixels.
+	Size int
+
+	// Outline is the width in pixels of the stroke around the gopher, zero
+	// for none, and OutlineColour its colour.
+	Outline       float64
+	OutlineColour color.NRGBA
+
+	// Shadow draws a soft drop shadow beneath the gopher.
+	Shadow bool
+}
+
+// DefaultStickerOptions are the options of stickers sized for messaging
+// apps, with the white outline typical of stickers.
+var DefaultStickerOptions = StickerOptions{
+	Size:          512,
+	Outline:       10,
+	OutlineColour: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	Shadow:        true,
+}
+
+// Sticker returns a sticker of g, with its eyes replaced by the option eyes
+// if that is not "". The sticker is transparent, ignoring the background of
+// g, with the gopher cropped tightly and centred, leaving just enough room
+// for its outline and shadow.
+func Sticker(g *gopher.Gopher, a render.Artwork, eyes string, o StickerOptions) (*image.NRGBA, error) {
+	if o.Size <= 0 {
+		return nil, fmt.Errorf("invalid size %v", o.Size)
+	}
+	if o.Outline < 0 {
+		return nil, fmt.Errorf("invalid outline %v", o.Outline)
+	}
+
+	fg := g.Copy()
+	fg.Background = ""
+
+	m, err := eyesFrame(fg, a, Frame{Eyes: eyes})
+	if err != nil {
+		return nil, err
+	}
+
+	b := render.OpaqueBounds(m)
+	if b.Empty() {
+		return nil, fmt.Errorf("gopher has no visible layers")
+	}
+
+	// the shadow extends in the direction of its offset by the offset plus
+	// the reach of its blur
+	offset, blur := o.Size/128, o.Size/64
+	pad := int(math.Ceil(o.Outline)) + 1
+	if o.Shadow {
+		pad += offset + 3*blur
+	}
+
+	inner := o.Size - 2*pad
+	if inner < 1 {
+		return nil, fmt.Errorf("size %v is too small for the outline and shadow", o.Size)
+	}
+
+	res := image.NewNRGBA(image.Rect(0, 0, o.Size, o.Size))
+	scaled := cropResize(m, square(b, 0), inner, nil)
+	draw.Draw(res, scaled.Bounds().Add(image.Pt(pad, pad)), scaled, image.Point{}, draw.Src)
+
+	if o.Outline > 0 {
+		res = render.Outline(res, o.Outline, o.OutlineColour)
+	}
+	if o.Shadow {
+		res = render.Shadow(res, offset, offset, blur, render.ShadowColour)
+	}
+
+	return res, nil
+}
+
+// StickerSource is the gopher, and optionally the eyes, of a sticker of a
+// pack.
+type StickerSource struct {
+	Name   string
+	Gopher *gopher.Gopher
+	Eyes   string
+}
+
+// EyeVariants returns a sticker source for g in each option of the Eyes
+// category, named after the option and prefixed by name.
+func EyeVariants(name string, g *gopher.Gopher) []StickerSource {
+	var res []StickerSource
+	for _, f := range cycleFrames(gopher.DefaultConfig, 0) {
+		res = append(res, StickerSource{
+			Name:   name + "_" + path.Base(f.Eyes),
+			Gopher: g,
+			Eyes:   f.Eyes,
+		})
+	}
+	return res
+}
+
+// Stickers writes a zip archive of the stickers of srcs, together with a
+// manifest that records the recipe of each.
+func Stickers(w io.Writer, name string, srcs []StickerSource, a render.Artwork, o StickerOptions) error {
+	if len(srcs) == 0 {
+		return fmt.Errorf("no stickers")
+	}
+
+	pk := newPack(w, Manifest{
+		Name: name,
+		Size: o.Size,
+	})
+
+	seen := make(map[string]bool)
+
+	for _, s := range srcs {
+		if seen[s.Name] {
+			return fmt.Errorf("duplicate sticker name %q", s.Name)
+		}
+		seen[s.Name] = true
+
+		m, err := Sticker(s.Gopher, a, s.Eyes, o)
+		if err != nil {
+			return fmt.Errorf("sticker %v: %v", s.Name, err)
+		}
+
+		recipe, err := gopher.MarshalRecipe(s.Gopher)
+		if err != nil {
+			return err
+		}
+
+		im := ManifestImage{
+			Name:   s.Name,
+			Option: s.Eyes,
+			Recipe: recipe,
+		}
+		if err := pk.add(im, m); err != nil {
+			return err
+		}
+	}
+
+	return pk.close()
+}
@@ -0,0 +1,199 @@
+package export
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"path"
+	"reflect"
+	"testing"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+func TestSticker(t *testing.T) {
+	tests := []struct {
+		name string
+		eyes string
+		o    StickerOptions
+		want map[image.Point]color.NRGBA
+	}{
+		{
+			// the body, 700x1000, is scaled to fit the 104 pixels left by
+			// the padding of 12 for the outline and shadow, centred
+			"outline and shadow",
+			"",
+			StickerOptions{Size: 128, Outline: 4, OutlineColour: white, Shadow: true},
+			map[image.Point]color.NRGBA{
+				{64, 60}:  bodyColour,
+				{64, 33}:  eyesColour(testEyes),
+				{25, 60}:  white,
+				{2, 2}:    {},
+				{125, 60}: {},
+			},
+		},
+		{
+			"eyes",
+			"020-Eyes/looking_left",
+			StickerOptions{Size: 128, Outline: 4, OutlineColour: white, Shadow: true},
+			map[image.Point]color.NRGBA{
+				{64, 33}: eyesColour("020-Eyes/looking_left"),
+			},
+		},
+		{
+			// a padding of just one pixel
+			"plain",
+			"",
+			StickerOptions{Size: 128},
+			map[image.Point]color.NRGBA{
+				{64, 60}:  bodyColour,
+				{64, 30}:  eyesColour(testEyes),
+				{21, 60}:  bodyColour,
+				{17, 60}:  {},
+				{64, 126}: bodyColour,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		g := testGopher(testEyes)
+		g.Background = "#00ff00"
+
+		m, err := Sticker(g, newTestArtwork(), test.eyes, test.o)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		if b := m.Bounds(); b != image.Rect(0, 0, test.o.Size, test.o.Size) {
+			t.Errorf("%v: sticker is %v; want %vx%v", test.name, b, test.o.Size, test.o.Size)
+		}
+		for p, want := range test.want {
+			if got := m.NRGBAAt(p.X, p.Y); !near(got, want) {
+				t.Errorf("%v: sticker is %v at %v; want %v", test.name, got, p, want)
+			}
+		}
+	}
+}
+
+func TestStickerErrors(t *testing.T) {
+	blank := testArtwork{}
+	for o := range newTestArtwork() {
+		blank[o] = image.NewNRGBA(image.Rect(0, 0, gopher.Width, gopher.Height))
+	}
+
+	tests := []struct {
+		name string
+		a    testArtwork
+		o    StickerOptions
+	}{
+		{"no size", newTestArtwork(), StickerOptions{}},
+		{"negative outline", newTestArtwork(), StickerOptions{Size: 128, Outline: -1}},
+		{"too small", newTestArtwork(), StickerOptions{Size: 20, Outline: 10}},
+		{"invisible", blank, StickerOptions{Size: 128}},
+		{"no artwork", testArtwork{}, StickerOptions{Size: 128}},
+	}
+
+	for _, test := range tests {
+		if _, err := Sticker(testGopher(testEyes), test.a, "", test.o); err == nil {
+			t.Errorf("%v: Sticker gave no error", test.name)
+		}
+	}
+}
+
+func TestEyeVariants(t *testing.T) {
+	g := testGopher(testEyes)
+
+	var got []StickerSource
+	for _, s := range EyeVariants("gopher", g) {
+		if s.Gopher != g {
+			t.Errorf("variant %v is of another gopher", s.Name)
+		}
+		s.Gopher = nil
+		got = append(got, s)
+	}
+
+	var want []StickerSource
+	for _, o := range eyesOptions() {
+		want = append(want, StickerSource{Name: "gopher_" + path.Base(o), Eyes: o})
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EyeVariants = %v; want %v", got, want)
+	}
+}
+
+func TestStickers(t *testing.T) {
+	g1, g2 := testGopher(testEyes), testGopher("")
+	srcs := []StickerSource{
+		{Name: "one", Gopher: g1},
+		{Name: "two", Gopher: g2, Eyes: "020-Eyes/looking_left"},
+	}
+	o := StickerOptions{Size: 64}
+
+	var buf bytes.Buffer
+	if err := Stickers(&buf, "pack", srcs, newTestArtwork(), o); err != nil {
+		t.Fatal(err)
+	}
+
+	man, images := readPack(t, buf.Bytes())
+	if man.Name != "pack" || man.Size != 64 || man.Recipe != nil {
+		t.Errorf("manifest %v, %v, %s; want pack, 64 and no recipe of its own", man.Name, man.Size, man.Recipe)
+	}
+	if len(man.Images) != len(srcs) {
+		t.Fatalf("%v images; want %v", len(man.Images), len(srcs))
+	}
+
+	for i, im := range man.Images {
+		s := srcs[i]
+		if im.Name != s.Name || im.File != s.Name+".png" || im.Option != s.Eyes {
+			t.Errorf("image %+v; want %v showing %q", im, s.Name, s.Eyes)
+		}
+		if im.Recipe == nil {
+			t.Errorf("image %v has no recipe", im.Name)
+		}
+
+		want, err := Sticker(s.Gopher, newTestArtwork(), s.Eyes, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m := images[im.File]; m == nil || !reflect.DeepEqual(toNRGBA(m), want) {
+			t.Errorf("image %v is not the sticker of %v", im.File, s.Name)
+		}
+	}
+}
+
+func TestStickersErrors(t *testing.T) {
+	g := testGopher(testEyes)
+	o := StickerOptions{Size: 64}
+
+	tests := []struct {
+		name string
+		srcs []StickerSource
+	}{
+		{"none", nil},
+		{"duplicate", []StickerSource{{Name: "a", Gopher: g}, {Name: "a", Gopher: g, Eyes: "020-Eyes/looking_left"}}},
+		{"invalid", []StickerSource{{Name: "a", Gopher: g, Eyes: "020-Eyes/no_such_eyes"}}},
+	}
+
+	for _, test := range tests {
+		if err := Stickers(&bytes.Buffer{}, "pack", test.srcs, newTestArtwork(), o); err == nil {
+			t.Errorf("%v: Stickers gave no error", test.name)
+		}
+	}
+}
+
+// toNRGBA returns m as an NRGBA image.
+func toNRGBA(m image.Image) *image.NRGBA {
+	if n, ok := m.(*image.NRGBA); ok {
+		return n
+	}
+	b := m.Bounds()
+	res := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			res.Set(x, y, m.At(x, y))
+		}
+	}
+	return res
+}
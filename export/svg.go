@@ -0,0 +1,173 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// Package export writes gophers in the formats used outside of gopherize.me:
+// images, documents and archives. All formats are produced from the layers
+// drawn by the render package.
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+)
+
+// SVG writes g as an SVG document. Each visible layer is embedded as a PNG
+// image within its own named group, marked as a layer for Inkscape, so that
+// the layers remain separate when the document is edited. Transforms and the
+// background of g are preserved. Effects apply to the gopher as a whole, so
+// a gopher with effects is embedded as a single image above its background.
+func SVG(w io.Writer, g *gopher.Gopher, a render.Artwork) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" width="%[1]v" height="%[2]v" viewBox="0 0 %[1]v %[2]v">
+`, gopher.Width, gopher.Height)
+
+	if g.Background != "" {
+		if _, err := gopher.ParseColour(g.Background); err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, `  <g id="background" inkscape:groupmode="layer" inkscape:label="Background">
+    <rect width="%v" height="%v" fill="%v"/>
+  </g>
+`, gopher.Width, gopher.Height, g.Background)
+	}
+
+	if err := svgLayers(bw, g, a, "", "  "); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(bw, "</svg>")
+
+	return bw.Flush()
+}
+
+// SceneSVG writes s as an SVG document. Each gopher is a layer, placed by a
+// transform, holding the layers of the gopher as written by SVG.
+func SceneSVG(w io.Writer, s *gopher.Scene, a render.Artwork) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" width="%[1]v" height="%[2]v" viewBox="0 0 %[1]v %[2]v">
+`, s.Width, s.Height)
+
+	if s.Background != "" {
+		if _, err := gopher.ParseColour(s.Background); err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, `  <g id="background" inkscape:groupmode="layer" inkscape:label="Background">
+    <rect width="%v" height="%v" fill="%v"/>
+  </g>
+`, s.Width, s.Height, s.Background)
+	}
+
+	for i, p := range s.Gophers {
+		g := p.Gopher.Copy()
+		g.Background = ""
+
+		x, y, w, _ := p.Rect()
+
+		fmt.Fprintf(bw, `  <g id="gopher%[1]v" inkscape:groupmode="layer" inkscape:label="Gopher %[1]v"%[2]v>
+`, i+1, svgMatrix(x, y, w, p.FlipH))
+
+		if err := svgLayers(bw, g, a, fmt.Sprintf("gopher%v-", i+1), "    "); err != nil {
+			return err
+		}
+
+		fmt.Fprintln(bw, "  </g>")
+	}
+
+	fmt.Fprintln(bw, "</svg>")
+
+	return bw.Flush()
+}
+
+// svgLayers writes the layers of g, other than its background, as groups
+// indented by indent and with IDs prefixed by id.
+func svgLayers(bw *bufio.Writer, g *gopher.Gopher, a render.Artwork, id, indent string) error {
+	if !g.Effects.IsZero() {
+		// effects apply to the gopher as a whole, so its layers cannot be
+		// kept apart
+		fg := g.Copy()
+		fg.Background = ""
+
+		m, err := render.Compose(fg, a)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, m); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(bw, `%[1]v<g id="%[2]vgopher" inkscape:groupmode="layer" inkscape:label="Gopher">
+%[1]v  <image width="%[3]v" height="%[4]v" xlink:href="data:image/png;base64,%[5]v"/>
+%[1]v</g>
+`, indent, id, gopher.Width, gopher.Height, base64.StdEncoding.EncodeToString(buf.Bytes()))
+
+		return nil
+	}
+
+	for i, p := range g.Layers() {
+		data, err := render.LayerPNG(g, p, a)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(bw, `%[1]v<g id="%[2]vlayer%[3]v" inkscape:groupmode="layer" inkscape:label="%[4]v">
+%[1]v  <image width="%[5]v" height="%[6]v"%[7]v xlink:href="data:image/png;base64,%[8]v"/>
+%[1]v</g>
+`, indent, id, i+1, escape(g.Label(p)), gopher.Width, gopher.Height, svgTransform(g.Transform(p)), base64.StdEncoding.EncodeToString(data))
+	}
+
+	return nil
+}
+
+// svgTransform returns the transform attribute that places a layer as
+// described by t, or "" for the identity transform.
+func svgTransform(t gopher.Transform) string {
+	if t.IsIdentity() {
+		return ""
+	}
+
+	x, y, w, _ := t.Rect()
+
+	return svgMatrix(x, y, w, t.FlipH)
+}
+
+// svgMatrix returns the transform attribute that places a gopher sized
+// canvas with its top left corner at (x, y) and width w, mirrored within
+// that width if flip is set.
+func svgMatrix(x, y, w float64, flip bool) string {
+	s := w / gopher.Width
+
+	sx := s
+	if flip {
+		sx, x = -s, x+w
+	}
+
+	return fmt.Sprintf(` transform="matrix(%v 0 0 %v %v %v)"`, num(sx), num(s), num(x), num(y))
+}
+
+// num formats v to no more than three decimal places.
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+func escape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
@@ -0,0 +1,271 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+// svgDoc is as much of an SVG document written by SVG as is checked.
+type svgDoc struct {
+	Width  string     `xml:"width,attr"`
+	Height string     `xml:"height,attr"`
+	Groups []svgGroup `xml:"g"`
+}
+
+type svgGroup struct {
+	ID        string     `xml:"id,attr"`
+	Mode      string     `xml:"http://www.inkscape.org/namespaces/inkscape groupmode,attr"`
+	Label     string     `xml:"http://www.inkscape.org/namespaces/inkscape label,attr"`
+	Transform string     `xml:"transform,attr"`
+	Rect      *svgRect   `xml:"rect"`
+	Image     *svgImage  `xml:"image"`
+	Groups    []svgGroup `xml:"g"`
+}
+
+type svgRect struct {
+	Fill string `xml:"fill,attr"`
+}
+
+type svgImage struct {
+	Transform string `xml:"transform,attr"`
+	Href      string `xml:"http://www.w3.org/1999/xlink href,attr"`
+}
+
+func parseSVG(t *testing.T, b []byte) svgDoc {
+	var d svgDoc
+	if err := xml.Unmarshal(b, &d); err != nil {
+		t.Fatalf("failed to parse SVG: %v\n%s", err, b)
+	}
+	return d
+}
+
+// decode returns the PNG image embedded by im.
+func (im *svgImage) decode(t *testing.T) image.Image {
+	const prefix = "data:image/png;base64,"
+	if !strings.HasPrefix(im.Href, prefix) {
+		t.Fatalf("image is not an embedded PNG: %.40q", im.Href)
+	}
+	b, err := base64.StdEncoding.DecodeString(im.Href[len(prefix):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSVG(t *testing.T) {
+	a := newTestArtwork()
+
+	g := testGopher(testEyes)
+	g.Background = "#00ff00"
+	g.SetTransform(testEyes, gopher.Transform{Scale: 0.5, FlipH: true})
+
+	var buf bytes.Buffer
+	if err := SVG(&buf, g, a); err != nil {
+		t.Fatal(err)
+	}
+	d := parseSVG(t, buf.Bytes())
+
+	if d.Width != "1300" || d.Height != "1392" {
+		t.Errorf("SVG is %vx%v; want 1300x1392", d.Width, d.Height)
+	}
+	if len(d.Groups) != 3 {
+		t.Fatalf("SVG has %v groups; want background, body and eyes", len(d.Groups))
+	}
+
+	bg := d.Groups[0]
+	if bg.ID != "background" || bg.Rect == nil || bg.Rect.Fill != "#00ff00" {
+		t.Errorf("first group %+v; want the background", bg)
+	}
+
+	tests := []struct {
+		g         svgGroup
+		id, label string
+		transform string
+		at        image.Point
+		colour    color.NRGBA
+	}{
+		{d.Groups[1], "layer1", "Body: blue gopher", "", bodyRect.Min, bodyColour},
+		{d.Groups[2], "layer2", "Eyes: eyes", "matrix(-0.5 0 0 0.5 975 348)", eyesRect.Min, eyesColour(testEyes)},
+	}
+
+	for _, test := range tests {
+		if test.g.ID != test.id || test.g.Label != test.label || test.g.Mode != "layer" {
+			t.Errorf("group %q labelled %q, mode %q; want %q labelled %q, mode layer", test.g.ID, test.g.Label, test.g.Mode, test.id, test.label)
+		}
+		if test.g.Image == nil {
+			t.Errorf("group %q has no image", test.g.ID)
+			continue
+		}
+		if test.g.Image.Transform != test.transform {
+			t.Errorf("image of group %q has transform %q; want %q", test.g.ID, test.g.Image.Transform, test.transform)
+		}
+
+		// layers are embedded untransformed and without the background
+		m := test.g.Image.decode(t)
+		if got := color.NRGBAModel.Convert(m.At(test.at.X, test.at.Y)); got != test.colour {
+			t.Errorf("image of group %q is %v at %v; want %v", test.g.ID, got, test.at, test.colour)
+		}
+		if got := color.NRGBAModel.Convert(m.At(0, 0)); got != (color.NRGBA{}) {
+			t.Errorf("image of group %q is %v at its corner; want transparent", test.g.ID, got)
+		}
+	}
+}
+
+func TestSVGNoBackground(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SVG(&buf, testGopher(""), newTestArtwork()); err != nil {
+		t.Fatal(err)
+	}
+	d := parseSVG(t, buf.Bytes())
+
+	if len(d.Groups) != 1 || d.Groups[0].ID != "layer1" {
+		t.Errorf("SVG of a gopher with no background nor eyes has groups %+v; want just the body", d.Groups)
+	}
+}
+
+func TestSVGErrors(t *testing.T) {
+	g := testGopher(testEyes)
+	g.Background = "green"
+	if err := SVG(&bytes.Buffer{}, g, newTestArtwork()); err == nil {
+		t.Errorf("no error writing a gopher with an invalid background")
+	}
+
+	if err := SVG(&bytes.Buffer{}, testGopher(testEyes), testArtwork{}); err == nil {
+		t.Errorf("no error writing a gopher with no artwork")
+	}
+}
+
+func TestSVGTransform(t *testing.T) {
+	tests := []struct {
+		t    gopher.Transform
+		want string
+	}{
+		{gopher.Transform{}, ""},
+		{gopher.Transform{Scale: 1}, ""},
+		{gopher.Transform{X: 10, Y: -20}, ` transform="matrix(1 0 0 1 10 -20)"`},
+		{gopher.Transform{Scale: 2}, ` transform="matrix(2 0 0 2 -650 -696)"`},
+		{gopher.Transform{FlipH: true}, ` transform="matrix(-1 0 0 1 1300 0)"`},
+		{gopher.Transform{Scale: 0.5, X: 1}, ` transform="matrix(0.5 0 0 0.5 326 348)"`},
+	}
+
+	for _, test := range tests {
+		if got := svgTransform(test.t); got != test.want {
+			t.Errorf("svgTransform(%+v) = %q; want %q", test.t, got, test.want)
+		}
+	}
+}
+
+func TestSVGNum(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0"},
+		{-3, "-3"},
+		{0.1 + 0.2, "0.3"},
+		{1.23456, "1.235"},
+		{1e7, "10000000"},
+	}
+
+	for _, test := range tests {
+		if got := num(test.in); got != test.want {
+			t.Errorf("num(%v) = %q; want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestSVGEffects(t *testing.T) {
+	g := testGopher(testEyes)
+	g.Background = "#00ff00"
+	g.Effects = &gopher.Effects{Outline: 4}
+
+	var buf bytes.Buffer
+	if err := SVG(&buf, g, newTestArtwork()); err != nil {
+		t.Fatal(err)
+	}
+	d := parseSVG(t, buf.Bytes())
+
+	// the layers of a gopher with effects are drawn as one, above its
+	// background
+	if len(d.Groups) != 2 || d.Groups[0].ID != "background" || d.Groups[1].ID != "gopher" || d.Groups[1].Image == nil {
+		t.Fatalf("SVG of a gopher with effects has groups %+v; want the background and the gopher", d.Groups)
+	}
+
+	m := d.Groups[1].Image.decode(t)
+	for _, c := range []struct {
+		at     image.Point
+		colour color.NRGBA
+	}{
+		{bodyRect.Min, bodyColour},
+		{eyesRect.Min, eyesColour(testEyes)},
+		{bodyRect.Min.Sub(image.Pt(2, 2)), color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+		{image.Pt(0, 0), color.NRGBA{}},
+	} {
+		if got := color.NRGBAModel.Convert(m.At(c.at.X, c.at.Y)); got != c.colour {
+			t.Errorf("gopher is %v at %v; want %v", got, c.at, c.colour)
+		}
+	}
+}
+
+func TestSceneSVG(t *testing.T) {
+	s := &gopher.Scene{
+		Width:      2000,
+		Height:     1000,
+		Background: "#ff0000",
+		Gophers: []gopher.Placement{
+			{Gopher: testGopher(testEyes), X: 500, Y: 500, Scale: 0.5},
+			{Gopher: testGopher(""), X: 1500, Y: 500, Scale: 0.5, FlipH: true},
+		},
+	}
+	s.Gophers[0].Gopher.Background = "#00ff00"
+
+	var buf bytes.Buffer
+	if err := SceneSVG(&buf, s, newTestArtwork()); err != nil {
+		t.Fatal(err)
+	}
+	d := parseSVG(t, buf.Bytes())
+
+	if d.Width != "2000" || d.Height != "1000" {
+		t.Errorf("SVG is %vx%v; want 2000x1000", d.Width, d.Height)
+	}
+	if len(d.Groups) != 3 || d.Groups[0].Rect == nil || d.Groups[0].Rect.Fill != "#ff0000" {
+		t.Fatalf("SVG has groups %+v; want the background of the scene and two gophers", d.Groups)
+	}
+
+	tests := []struct {
+		g         svgGroup
+		id        string
+		transform string
+		layers    []string
+	}{
+		{d.Groups[1], "gopher1", "matrix(0.5 0 0 0.5 175 152)", []string{"gopher1-layer1", "gopher1-layer2"}},
+		{d.Groups[2], "gopher2", "matrix(-0.5 0 0 0.5 1825 152)", []string{"gopher2-layer1"}},
+	}
+
+	for _, test := range tests {
+		if test.g.ID != test.id || test.g.Transform != test.transform {
+			t.Errorf("group %q has transform %q; want %q with %q", test.g.ID, test.g.Transform, test.id, test.transform)
+		}
+
+		// the backgrounds of the gophers are left out
+		var ids []string
+		for _, l := range test.g.Groups {
+			ids = append(ids, l.ID)
+		}
+		if strings.Join(ids, " ") != strings.Join(test.layers, " ") {
+			t.Errorf("group %q holds %q; want %q", test.id, ids, test.layers)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package gopher
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// The colour filters that can be applied to a gopher.
+const (
+	FilterGrayscale = "grayscale"
+	FilterDuotone   = "duotone"
+)
+
+// Filters lists the colour filters that can be applied to a gopher.
+var Filters = []string{FilterGrayscale, FilterDuotone}
+
+// Limits on the values of Effects.
+const (
+	MaxOutline   = 40
+	MinPixelSize = 2
+	MaxPixelSize = 64
+)
+
+// Effects are applied to a gopher once its layers have been drawn, in the
+// order of the fields below, before its background is drawn beneath it.
+// Lengths are in pixels of the full size canvas.
+type Effects struct {
+	// Outline is the width of a stroke around the opaque area of the gopher;
+	// 0 means none.
+	Outline int `json:",omitempty"`
+
+	// OutlineColour is the colour of the outline in the form #rrggbb; ""
+	// means white.
+	OutlineColour string `json:",omitempty"`
+
+	// Shadow draws a soft drop shadow beneath the gopher.
+	Shadow bool `json:",omitempty"`
+
+	// Filter is one of Filters; "" means none.
+	Filter string `json:",omitempty"`
+
+	// Dark and Light are the colours, in the form #rrggbb, to which the
+	// darkest and lightest parts of the gopher are mapped by the duotone
+	// filter; "" means black and white respectively.
+	Dark  string `json:",omitempty"`
+	Light string `json:",omitempty"`
+
+	// PixelSize, if not 0, redraws the gopher as pixel art, in square
+	// pixels of this size.
+	PixelSize int `json:",omitempty"`
+}
+
+// IsZero reports whether e has no effect.
+func (e *Effects) IsZero() bool {
+	return e == nil || *e == Effects{}
+}
+
+// OutlineRGBA returns the colour of the outline.
+func (e Effects) OutlineRGBA() color.NRGBA {
+	if e.OutlineColour == "" {
+		return color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	}
+	c, _ := ParseColour(e.OutlineColour)
+	return c
+}
+
+// DuotoneRGBA returns the colours of the duotone filter.
+func (e Effects) DuotoneRGBA() (dark, light color.NRGBA) {
+	dark, _ = ParseColour(e.Dark)
+
+	light = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	if e.Light != "" {
+		light, _ = ParseColour(e.Light)
+	}
+
+	return dark, light
+}
+
+// Validate checks that the values of e are within their limits.
+func (e Effects) Validate() error {
+	if e.Outline < 0 || e.Outline > MaxOutline {
+		return fmt.Errorf("outline %v out of range [0, %v]", e.Outline, MaxOutline)
+	}
+	if e.Filter != "" && !contains(Filters, e.Filter) {
+		return fmt.Errorf("unknown filter %q", e.Filter)
+	}
+	if e.PixelSize != 0 && (e.PixelSize < MinPixelSize || e.PixelSize > MaxPixelSize) {
+		return fmt.Errorf("pixel size %v out of range [%v, %v]", e.PixelSize, MinPixelSize, MaxPixelSize)
+	}
+
+	for _, c := range []string{e.OutlineColour, e.Dark, e.Light} {
+		if c == "" {
+			continue
+		}
+		if _, err := ParseColour(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
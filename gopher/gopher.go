@@ -0,0 +1,211 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// Package gopher defines the model of a gopher that is shared between the
+// browser client and any Go renderer: the catalogue of artwork, the parts
+// selected for a gopher and the transforms applied to them.
+package gopher
+
+//go:generate go run mkcatalogue.go
+
+import (
+	"math/big"
+	"math/rand"
+	"strings"
+)
+
+// Width and Height are the dimensions, in pixels, of the canvas on which all
+// artwork layers are drawn.
+const (
+	Width  = 1300
+	Height = 1392
+)
+
+// Gopher is a gopher made of options chosen from the categories of a Config.
+type Gopher struct {
+	// Parts holds the selected options for each category of the Config
+	// used to create the gopher, in the order of Config.Categories. A
+	// category may have several options selected (see Category.Max), or
+	// none.
+	Parts [][]string
+
+	// Transforms holds the transform for a selected option, keyed by option.
+	// Parts without an entry are drawn untransformed.
+	Transforms map[string]Transform `json:",omitempty"`
+
+	// Order, if set, overrides the default z-order of parts, bottom layer
+	// first. See Stack.
+	Order []string `json:",omitempty"`
+
+	// Hidden holds the parts that are selected but not drawn.
+	Hidden map[string]bool `json:",omitempty"`
+
+	// Uploads holds the images supplied by the user that are drawn as
+	// additional layers, by default above all the parts from the catalogue.
+	Uploads []Upload `json:",omitempty"`
+
+	// Texts holds the text layers of the gopher, by default drawn above all
+	// other parts.
+	Texts []Text `json:",omitempty"`
+
+	// Background is the colour, in the form #rrggbb, drawn behind all
+	// layers; "" means transparent.
+	Background string `json:",omitempty"`
+
+	// Effects, if set, are applied to the gopher once its layers have been
+	// drawn.
+	Effects *Effects `json:",omitempty"`
+}
+
+// Transform returns the transform to apply to the part p.
+func (g *Gopher) Transform(p string) Transform {
+	return g.Transforms[p]
+}
+
+// Copy returns a copy of g that can be modified without affecting g.
+func (g *Gopher) Copy() *Gopher {
+	res := &Gopher{
+		Parts:      make([][]string, len(g.Parts)),
+		Background: g.Background,
+	}
+
+	for i, ps := range g.Parts {
+		res.Parts[i] = append([]string(nil), ps...)
+	}
+
+	for p, t := range g.Transforms {
+		res.SetTransform(p, t)
+	}
+
+	if g.Order != nil {
+		res.Order = make([]string, len(g.Order))
+		copy(res.Order, g.Order)
+	}
+
+	for p, h := range g.Hidden {
+		res.SetHidden(p, h)
+	}
+
+	res.Uploads = append([]Upload(nil), g.Uploads...)
+	res.Texts = append([]Text(nil), g.Texts...)
+
+	if g.Effects != nil {
+		e := *g.Effects
+		res.Effects = &e
+	}
+
+	return res
+}
+
+// Label returns a human readable name for the part p of g.
+func (g *Gopher) Label(p string) string {
+	if u, ok := g.Upload(p); ok {
+		return "Upload " + u.Name
+	}
+	if t, ok := g.Text(p); ok {
+		return "Text " + t.Text
+	}
+
+	// options are of the form 020-Eyes/eyes
+	p = strings.Replace(p, "_", " ", -1)
+	if i := strings.Index(p, "-"); i != -1 {
+		p = p[i+1:]
+	}
+	return strings.Replace(p, "/", ": ", 1)
+}
+
+// SetTransform sets the transform for part p; the identity transform removes
+// any existing entry.
+func (g *Gopher) SetTransform(p string, t Transform) {
+	if t.IsIdentity() {
+		delete(g.Transforms, p)
+		return
+	}
+
+	if g.Transforms == nil {
+		g.Transforms = make(map[string]Transform)
+	}
+
+	g.Transforms[p] = t.Clamp()
+}
+
+// Config is a catalogue of artwork from which gophers are made.
+type Config struct {
+	Categories []*Category
+}
+
+// Category is a named set of options, each the name of a piece of artwork.
+type Category struct {
+	Name    string
+	Options []string
+
+	// Max is the maximum number of options that can be selected at once. A
+	// value of 0 is the same as 1.
+	Max int `json:",omitempty"`
+}
+
+// Category returns the index and definition of the category called name, or
+// -1 and nil if c has no such category.
+func (c *Config) Category(name string) (int, *Category) {
+	for i, cat := range c.Categories {
+		if cat.Name == name {
+			return i, cat
+		}
+	}
+	return -1, nil
+}
+
+// MaxSelections returns the maximum number of options that can be selected
+// for c at once.
+func (c *Category) MaxSelections() int {
+	if c.Max < 1 {
+		return 1
+	}
+	return c.Max
+}
+
+// Combinations returns the number of distinct gophers that can be made from
+// the options of c, ignoring transforms and layer order.
+func (c *Config) Combinations() *big.Int {
+	res := big.NewInt(1)
+
+	for _, cat := range c.Categories {
+		var n int
+		for _, o := range cat.Options {
+			if o != "" {
+				n++
+			}
+		}
+
+		// the number of ways of choosing between 1 and MaxSelections() of
+		// the n options, plus one for choosing nothing where that is allowed
+		ways := new(big.Int)
+		for k := 1; k <= cat.MaxSelections() && k <= n; k++ {
+			ways.Add(ways, new(big.Int).Binomial(int64(n), int64(k)))
+		}
+		if cat.Has("") {
+			ways.Add(ways, big.NewInt(1))
+		}
+
+		res.Mul(res, ways)
+	}
+
+	return res
+}
+
+// Random returns a gopher with an option of each category of c chosen by r,
+// or none where "" is chosen. The same sequence from r gives the same
+// gopher, so a seeded r gives a reproducible gopher.
+func (c *Config) Random(r *rand.Rand) *Gopher {
+	var parts [][]string
+
+	for _, cat := range c.Categories {
+		var ps []string
+		if p := cat.Options[r.Intn(len(cat.Options))]; p != "" {
+			ps = append(ps, p)
+		}
+		parts = append(parts, ps)
+	}
+
+	return &Gopher{Parts: parts}
+}
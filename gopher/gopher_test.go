@@ -0,0 +1,111 @@
+package gopher
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestCombinations(t *testing.T) {
+	tests := []struct {
+		name string
+		c    *Config
+		want int64
+	}{
+		// 2 bodies, times 2 eyes or none, times none, 1 or 2 of 3 extras
+		{"test", testConfig, 2 * 3 * (1 + 3 + 3)},
+		{"empty", &Config{}, 1},
+		{"single", &Config{Categories: []*Category{{Name: "A", Options: []string{"a"}}}}, 1},
+		{"max beyond options", &Config{Categories: []*Category{{Name: "A", Options: []string{"", "a", "b"}, Max: 5}}}, 1 + 2 + 1},
+	}
+
+	for _, test := range tests {
+		if got := test.c.Combinations(); got.Int64() != test.want {
+			t.Errorf("%v: Combinations() = %v; want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMaxSelections(t *testing.T) {
+	for max, want := range map[int]int{-1: 1, 0: 1, 1: 1, 3: 3} {
+		if got := (&Category{Max: max}).MaxSelections(); got != want {
+			t.Errorf("MaxSelections() with Max %v = %v; want %v", max, got, want)
+		}
+	}
+}
+
+func TestLabel(t *testing.T) {
+	g := &Gopher{
+		Uploads: []Upload{{ID: "u", Name: "me.png"}},
+		Texts:   []Text{{ID: "1", Text: "Gordon"}},
+	}
+
+	tests := []struct {
+		p, want string
+	}{
+		{"020-Eyes/goofy_eyes", "Eyes: goofy eyes"},
+		{"050-Facial_Hair/beard", "Facial Hair: beard"},
+		{"upload/u", "Upload me.png"},
+		{"text/1", "Text Gordon"},
+	}
+
+	for _, test := range tests {
+		if got := g.Label(test.p); got != test.want {
+			t.Errorf("Label(%q) = %q; want %q", test.p, got, test.want)
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	g := &Gopher{
+		Parts:      [][]string{{"body/a"}, {"eyes/a"}, {"extras/a"}},
+		Transforms: map[string]Transform{"eyes/a": {X: 1, Scale: 1}},
+		Order:      []string{"eyes/a", "body/a"},
+		Hidden:     map[string]bool{"extras/a": true},
+		Uploads:    []Upload{{ID: "u"}},
+		Texts:      []Text{{ID: "1", Text: "Gordon"}},
+		Background: "#ffffff",
+		Effects:    &Effects{Shadow: true},
+	}
+
+	c := g.Copy()
+	if !reflect.DeepEqual(c, g) {
+		t.Fatalf("Copy() = %#v; want %#v", c, g)
+	}
+
+	c.Parts[0][0] = "body/b"
+	c.SetTransform("eyes/a", Transform{})
+	c.Order[0] = "extras/a"
+	c.SetHidden("extras/a", false)
+	c.Uploads[0].ID = "v"
+	c.Texts[0].Text = "Gladys"
+	c.Effects.Shadow = false
+
+	want := &Gopher{
+		Parts:      [][]string{{"body/a"}, {"eyes/a"}, {"extras/a"}},
+		Transforms: map[string]Transform{"eyes/a": {X: 1, Scale: 1}},
+		Order:      []string{"eyes/a", "body/a"},
+		Hidden:     map[string]bool{"extras/a": true},
+		Uploads:    []Upload{{ID: "u"}},
+		Texts:      []Text{{ID: "1", Text: "Gordon"}},
+		Background: "#ffffff",
+		Effects:    &Effects{Shadow: true},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("modifying a copy changed the original to %#v", g)
+	}
+}
+
+func TestRandom(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		g := testConfig.Random(rand.New(rand.NewSource(seed)))
+		if err := testConfig.Validate(g); err != nil {
+			t.Errorf("Random with seed %v gave invalid gopher: %v", seed, err)
+		}
+
+		again := testConfig.Random(rand.New(rand.NewSource(seed)))
+		if !reflect.DeepEqual(again, g) {
+			t.Errorf("Random with seed %v gave %v then %v", seed, g.Parts, again.Parts)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package gopher
+
+// Stack returns the selected parts of g in the order in which they are
+// drawn, bottom layer first, including those that are hidden. By default
+// parts are stacked in the order of the categories of the gopher's Config,
+// followed by any uploads and then any texts; Order overrides this. A part
+// that is not listed in Order is placed directly below the first listed part
+// of a later category, uploads and texts being treated as belonging to a
+// final category.
+func (g *Gopher) Stack() []string {
+	var res []string
+
+	for _, p := range g.Order {
+		if g.Selected(p) {
+			res = append(res, p)
+		}
+	}
+
+	listed := make(map[string]bool)
+	for _, p := range res {
+		listed[p] = true
+	}
+
+	for i, ps := range g.Parts {
+	Parts:
+		for _, p := range ps {
+			if listed[p] {
+				continue
+			}
+
+			for j, q := range res {
+				if g.rank(q) > i {
+					res = append(res[:j], append([]string{p}, res[j:]...)...)
+					continue Parts
+				}
+			}
+
+			res = append(res, p)
+		}
+	}
+
+	for _, p := range g.extraParts() {
+		if !listed[p] {
+			res = append(res, p)
+		}
+	}
+
+	return res
+}
+
+// Layers returns the visible parts of g in the order in which they should be
+// drawn, bottom layer first. All renderers draw a gopher's layers in this
+// order.
+func (g *Gopher) Layers() []string {
+	var res []string
+
+	for _, p := range g.Stack() {
+		if !g.Hidden[p] {
+			res = append(res, p)
+		}
+	}
+
+	return res
+}
+
+// MoveLayer moves the layer at index from of g.Stack() to index to, fixing
+// the order of all layers.
+func (g *Gopher) MoveLayer(from, to int) {
+	s := g.Stack()
+
+	if from < 0 || from >= len(s) || to < 0 || to >= len(s) {
+		return
+	}
+
+	p := s[from]
+	s = append(s[:from], s[from+1:]...)
+	s = append(s[:to], append([]string{p}, s[to:]...)...)
+
+	g.Order = s
+}
+
+// SetHidden hides or shows the part p.
+func (g *Gopher) SetHidden(p string, hide bool) {
+	if !hide {
+		delete(g.Hidden, p)
+		return
+	}
+
+	if g.Hidden == nil {
+		g.Hidden = make(map[string]bool)
+	}
+
+	g.Hidden[p] = true
+}
+
+// Choose selects the option v for the category at index i, a category that
+// permits at most max options to be selected at once. In a single selection
+// category v replaces the current selection and takes its place in the layer
+// order. In a multiple selection category choosing a selected option
+// deselects it, and choosing one option too many deselects the earliest
+// selection. Choosing "" clears the category.
+func (g *Gopher) Choose(i int, v string, max int) {
+	old := g.Parts[i]
+
+	switch {
+	case v == "":
+		for _, p := range old {
+			g.Remove(p)
+		}
+	case max <= 1:
+		if len(old) == 1 && old[0] == v {
+			return
+		}
+
+		g.Parts[i] = []string{v}
+
+		var order []string
+		for _, p := range g.Order {
+			if g.Selected(p) {
+				order = append(order, p)
+			} else if len(old) > 0 && p == old[0] {
+				order = append(order, v)
+			}
+		}
+		g.Order = order
+
+		for _, p := range old {
+			g.forget(p)
+		}
+	case g.CategoryOf(v) == i:
+		g.Remove(v)
+	default:
+		g.Parts[i] = append(old[:len(old):len(old)], v)
+
+		for len(g.Parts[i]) > max {
+			g.Remove(g.Parts[i][0])
+		}
+	}
+}
+
+// Remove deselects the part p, which may be an upload or a text.
+func (g *Gopher) Remove(p string) {
+	if !g.Selected(p) {
+		return
+	}
+
+	switch i := g.CategoryOf(p); {
+	case i != -1:
+		var ps []string
+		for _, v := range g.Parts[i] {
+			if v != p {
+				ps = append(ps, v)
+			}
+		}
+		g.Parts[i] = ps
+	case IsUpload(p):
+		var us []Upload
+		for _, u := range g.Uploads {
+			if u.Part() != p {
+				us = append(us, u)
+			}
+		}
+		g.Uploads = us
+	case IsText(p):
+		var ts []Text
+		for _, t := range g.Texts {
+			if t.Part() != p {
+				ts = append(ts, t)
+			}
+		}
+		g.Texts = ts
+	}
+
+	var order []string
+	for _, v := range g.Order {
+		if v != p {
+			order = append(order, v)
+		}
+	}
+	g.Order = order
+
+	g.forget(p)
+}
+
+// forget drops the per-part state held for p.
+func (g *Gopher) forget(p string) {
+	g.SetTransform(p, Transform{})
+	g.SetHidden(p, false)
+}
+
+// CategoryOf returns the index of the category for which p is selected, or -1
+// if p is not selected.
+func (g *Gopher) CategoryOf(p string) int {
+	if p == "" {
+		return -1
+	}
+
+	for i, ps := range g.Parts {
+		for _, v := range ps {
+			if v == p {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// Selected reports whether p is one of the parts of g, including uploads and
+// texts.
+func (g *Gopher) Selected(p string) bool {
+	for _, v := range g.extraParts() {
+		if v == p {
+			return true
+		}
+	}
+	return g.CategoryOf(p) != -1
+}
+
+// extraParts returns the parts of g that do not come from the catalogue: its
+// uploads and then its texts.
+func (g *Gopher) extraParts() []string {
+	var res []string
+	for _, u := range g.Uploads {
+		res = append(res, u.Part())
+	}
+	for _, t := range g.Texts {
+		res = append(res, t.Part())
+	}
+	return res
+}
+
+// rank returns the position of the category of the part p in the default
+// layer order.
+func (g *Gopher) rank(p string) int {
+	if i := g.CategoryOf(p); i != -1 {
+		return i
+	}
+	return len(g.Parts)
+}
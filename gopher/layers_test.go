@@ -0,0 +1,219 @@
+package gopher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStack(t *testing.T) {
+	tests := []struct {
+		name  string
+		g     *Gopher
+		stack []string
+	}{
+		{
+			"default",
+			&Gopher{Parts: [][]string{{"body/a"}, {"eyes/a"}, {"extras/a", "extras/b"}}},
+			[]string{"body/a", "eyes/a", "extras/a", "extras/b"},
+		},
+		{
+			"ordered",
+			&Gopher{
+				Parts: [][]string{{"body/a"}, {"eyes/a"}, {"extras/a"}},
+				Order: []string{"extras/a", "eyes/a", "body/a"},
+			},
+			[]string{"extras/a", "eyes/a", "body/a"},
+		},
+		{
+			// eyes/a goes directly below extras/a, the first listed part of
+			// a later category
+			"partly ordered",
+			&Gopher{
+				Parts: [][]string{{"body/a"}, {"eyes/a"}, {"extras/a"}},
+				Order: []string{"extras/a", "body/a"},
+			},
+			[]string{"eyes/a", "extras/a", "body/a"},
+		},
+		{
+			"order of unselected parts",
+			&Gopher{
+				Parts: [][]string{{"body/a"}, nil, nil},
+				Order: []string{"eyes/a", "body/a"},
+			},
+			[]string{"body/a"},
+		},
+		{
+			"uploads and texts",
+			&Gopher{
+				Parts:   [][]string{{"body/a"}, {"eyes/a"}, nil},
+				Uploads: []Upload{{ID: "u"}},
+				Texts:   []Text{{ID: "1"}},
+			},
+			[]string{"body/a", "eyes/a", "upload/u", "text/1"},
+		},
+		{
+			// eyes/a goes below text/1, which ranks above every category
+			"ordered uploads",
+			&Gopher{
+				Parts:   [][]string{{"body/a"}, {"eyes/a"}, nil},
+				Uploads: []Upload{{ID: "u"}},
+				Texts:   []Text{{ID: "1"}},
+				Order:   []string{"text/1", "body/a"},
+			},
+			[]string{"eyes/a", "text/1", "body/a", "upload/u"},
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.g.Stack(); !reflect.DeepEqual(got, test.stack) {
+			t.Errorf("%v: Stack() = %q; want %q", test.name, got, test.stack)
+		}
+	}
+}
+
+func TestLayers(t *testing.T) {
+	g := &Gopher{Parts: [][]string{{"body/a"}, {"eyes/a"}, {"extras/a"}}}
+
+	g.SetHidden("eyes/a", true)
+	if got, want := g.Layers(), []string{"body/a", "extras/a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Layers() with eyes hidden = %q; want %q", got, want)
+	}
+	if got, want := g.Stack(), []string{"body/a", "eyes/a", "extras/a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stack() with eyes hidden = %q; want %q", got, want)
+	}
+
+	g.SetHidden("eyes/a", false)
+	if len(g.Hidden) != 0 {
+		t.Errorf("showing the eyes left Hidden %v", g.Hidden)
+	}
+}
+
+func TestMoveLayer(t *testing.T) {
+	tests := []struct {
+		from, to int
+		want     []string
+	}{
+		{0, 2, []string{"eyes/a", "extras/a", "body/a"}},
+		{2, 0, []string{"extras/a", "body/a", "eyes/a"}},
+		{1, 1, []string{"body/a", "eyes/a", "extras/a"}},
+		{0, 3, nil},
+		{-1, 0, nil},
+	}
+
+	for _, test := range tests {
+		g := &Gopher{Parts: [][]string{{"body/a"}, {"eyes/a"}, {"extras/a"}}}
+		g.MoveLayer(test.from, test.to)
+
+		if !reflect.DeepEqual(g.Order, test.want) {
+			t.Errorf("MoveLayer(%v, %v) gave Order %q; want %q", test.from, test.to, g.Order, test.want)
+		}
+	}
+}
+
+func TestChoose(t *testing.T) {
+	tests := []struct {
+		name  string
+		g     *Gopher
+		i     int
+		v     string
+		max   int
+		parts [][]string
+		order []string
+	}{
+		{
+			"replace",
+			&Gopher{Parts: [][]string{{"body/a"}, {"eyes/a"}, nil}},
+			0, "body/b", 1,
+			[][]string{{"body/b"}, {"eyes/a"}, nil},
+			nil,
+		},
+		{
+			// the new option takes the place of the old in the layer order
+			"replace ordered",
+			&Gopher{
+				Parts: [][]string{{"body/a"}, {"eyes/a"}, nil},
+				Order: []string{"eyes/a", "body/a"},
+			},
+			0, "body/b", 1,
+			[][]string{{"body/b"}, {"eyes/a"}, nil},
+			[]string{"eyes/a", "body/b"},
+		},
+		{
+			"clear",
+			&Gopher{Parts: [][]string{{"body/a"}, {"eyes/a"}, {"extras/a", "extras/b"}}},
+			2, "", 2,
+			[][]string{{"body/a"}, {"eyes/a"}, nil},
+			nil,
+		},
+		{
+			"add",
+			&Gopher{Parts: [][]string{{"body/a"}, nil, {"extras/a"}}},
+			2, "extras/b", 2,
+			[][]string{{"body/a"}, nil, {"extras/a", "extras/b"}},
+			nil,
+		},
+		{
+			"deselect",
+			&Gopher{Parts: [][]string{{"body/a"}, nil, {"extras/a", "extras/b"}}},
+			2, "extras/a", 2,
+			[][]string{{"body/a"}, nil, {"extras/b"}},
+			nil,
+		},
+		{
+			// one too many deselects the earliest
+			"add one too many",
+			&Gopher{Parts: [][]string{{"body/a"}, nil, {"extras/a", "extras/b"}}},
+			2, "extras/c", 2,
+			[][]string{{"body/a"}, nil, {"extras/b", "extras/c"}},
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		test.g.Choose(test.i, test.v, test.max)
+
+		if !reflect.DeepEqual(test.g.Parts, test.parts) {
+			t.Errorf("%v: Parts = %q; want %q", test.name, test.g.Parts, test.parts)
+		}
+		if !reflect.DeepEqual(test.g.Order, test.order) {
+			t.Errorf("%v: Order = %q; want %q", test.name, test.g.Order, test.order)
+		}
+	}
+}
+
+func TestChooseForgets(t *testing.T) {
+	g := &Gopher{Parts: [][]string{{"body/a"}, nil, nil}}
+	g.SetTransform("body/a", Transform{X: 1})
+	g.SetHidden("body/a", true)
+
+	g.Choose(0, "body/b", 1)
+
+	if len(g.Transforms) != 0 || len(g.Hidden) != 0 {
+		t.Errorf("replaced option kept its transform %v or hidden state %v", g.Transforms, g.Hidden)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	g := &Gopher{
+		Parts:   [][]string{{"body/a"}, {"eyes/a"}, {"extras/a", "extras/b"}},
+		Order:   []string{"extras/b", "upload/u", "text/1", "extras/a"},
+		Uploads: []Upload{{ID: "u"}},
+		Texts:   []Text{{ID: "1"}},
+	}
+	g.SetTransform("extras/b", Transform{X: 1})
+	g.SetTransform("upload/u", Transform{X: 1})
+
+	for _, p := range []string{"extras/b", "upload/u", "text/1", "eyes/b"} {
+		g.Remove(p)
+	}
+
+	if want := [][]string{{"body/a"}, {"eyes/a"}, {"extras/a"}}; !reflect.DeepEqual(g.Parts, want) {
+		t.Errorf("after Remove Parts = %q; want %q", g.Parts, want)
+	}
+	if want := []string{"extras/a"}; !reflect.DeepEqual(g.Order, want) {
+		t.Errorf("after Remove Order = %q; want %q", g.Order, want)
+	}
+	if len(g.Uploads) != 0 || len(g.Texts) != 0 || len(g.Transforms) != 0 {
+		t.Errorf("after Remove gopher kept uploads %v, texts %v or transforms %v", g.Uploads, g.Texts, g.Transforms)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+//go:build ignore
+// +build ignore
+
+// mkcatalogue writes catalogue.go, the catalogue of artwork DefaultConfig,
+// from the artwork directory.
+//
+// Each directory NNN-Name of the artwork directory is a category called
+// Name, with underscores read as spaces, and categories are in the order of
+// their numbers NNN. The options of a category are its images, each an
+// image name.png and its thumbnail name_thumbnail.png, in order of name; an
+// option that lacks one of the two is kept, with a warning. Every category
+// but those of requiredCategories has a first option of "" for none.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// requiredCategories are the categories of which an option must always be
+// chosen.
+var requiredCategories = map[string]bool{
+	"Body": true,
+	"Eyes": true,
+}
+
+// maxSelections gives the maximum number of options that can be chosen at
+// once of the categories that permit more than one.
+var maxSelections = map[string]int{
+	"Extras": 3,
+}
+
+const thumbnailSuffix = "_thumbnail.png"
+
+var (
+	fArtwork = flag.String("artwork", "../artwork", "the artwork directory")
+	fOut     = flag.String("o", "catalogue.go", "the file to write")
+)
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "mkcatalogue: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	fis, err := ioutil.ReadDir(*fArtwork)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "// Code generated by mkcatalogue.go from the artwork directory. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package gopher\n\n")
+	fmt.Fprintf(&buf, "// DefaultConfig is the catalogue of artwork that ships with gopherize.me\n")
+	fmt.Fprintf(&buf, "var DefaultConfig = &Config{\n")
+	fmt.Fprintf(&buf, "Categories: []*Category{\n")
+
+	// ReadDir sorts by name, so by number
+	for _, fi := range fis {
+		dir := fi.Name()
+		i := strings.IndexByte(dir, '-')
+		if !fi.IsDir() || i == -1 {
+			continue
+		}
+		name := strings.Replace(dir[i+1:], "_", " ", -1)
+
+		opts, err := options(dir)
+		if err != nil {
+			return err
+		}
+		if len(opts) == 0 {
+			return fmt.Errorf("category %v has no images", dir)
+		}
+		if !requiredCategories[name] {
+			opts = append([]string{""}, opts...)
+		}
+
+		fmt.Fprintf(&buf, "&Category{\n")
+		fmt.Fprintf(&buf, "Name: %q,\n", name)
+		if m := maxSelections[name]; m > 1 {
+			fmt.Fprintf(&buf, "Max: %v,\n", m)
+		}
+		fmt.Fprintf(&buf, "Options: []string{\n")
+		for _, o := range opts {
+			fmt.Fprintf(&buf, "%q,\n", o)
+		}
+		fmt.Fprintf(&buf, "},\n")
+		fmt.Fprintf(&buf, "},\n")
+	}
+
+	fmt.Fprintf(&buf, "},\n")
+	fmt.Fprintf(&buf, "}\n")
+
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format catalogue: %v", err)
+	}
+
+	if old, err := ioutil.ReadFile(*fOut); err == nil && bytes.Equal(old, b) {
+		return nil
+	}
+
+	return ioutil.WriteFile(*fOut, b, 0666)
+}
+
+// options returns the options of the category directory dir, in order,
+// warning of any that lack either an image or a thumbnail.
+func options(dir string) ([]string, error) {
+	fis, err := ioutil.ReadDir(filepath.Join(*fArtwork, dir))
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[string]bool)
+	thumbs := make(map[string]bool)
+	for _, fi := range fis {
+		switch n := fi.Name(); {
+		case strings.HasSuffix(n, thumbnailSuffix):
+			thumbs[strings.TrimSuffix(n, thumbnailSuffix)] = true
+		case strings.HasSuffix(n, ".png"):
+			images[strings.TrimSuffix(n, ".png")] = true
+		}
+	}
+
+	var res []string
+	for n := range images {
+		if !thumbs[n] {
+			fmt.Fprintf(os.Stderr, "mkcatalogue: %v/%v has no thumbnail\n", dir, n)
+		}
+		res = append(res, dir+"/"+n)
+	}
+	for n := range thumbs {
+		if !images[n] {
+			fmt.Fprintf(os.Stderr, "mkcatalogue: %v/%v has a thumbnail but no image\n", dir, n)
+			res = append(res, dir+"/"+n)
+		}
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
@@ -0,0 +1,169 @@
+package gopher
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// RecipeVersion is the version of the recipe format written by
+// MarshalRecipe.
+const RecipeVersion = 1
+
+// recipe is the serialised form of a Gopher. It is versioned so that saved
+// gophers continue to load as the model evolves.
+type recipe struct {
+	Version int
+	*Gopher
+}
+
+// MarshalRecipe returns the JSON recipe for g.
+func MarshalRecipe(g *Gopher) ([]byte, error) {
+	return json.Marshal(recipe{Version: RecipeVersion, Gopher: g})
+}
+
+// UnmarshalRecipe parses a JSON recipe previously written by MarshalRecipe,
+// validating it against c.
+func UnmarshalRecipe(b []byte, c *Config) (*Gopher, error) {
+	r := recipe{Gopher: new(Gopher)}
+
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse recipe: %v", err)
+	}
+
+	if r.Version < 1 || r.Version > RecipeVersion {
+		return nil, fmt.Errorf("unsupported recipe version %v", r.Version)
+	}
+
+	g := r.Gopher
+
+	if err := c.Validate(g); err != nil {
+		return nil, err
+	}
+
+	for p, t := range g.Transforms {
+		g.SetTransform(p, t)
+	}
+
+	return g, nil
+}
+
+// EncodeRecipe returns the recipe for g in a form suitable for use in a URL.
+func EncodeRecipe(g *Gopher) (string, error) {
+	b, err := MarshalRecipe(g)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeRecipe is the inverse of EncodeRecipe.
+func DecodeRecipe(s string, c *Config) (*Gopher, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode recipe: %v", err)
+	}
+
+	return UnmarshalRecipe(b, c)
+}
+
+// Validate checks that g only refers to options found in c.
+func (c *Config) Validate(g *Gopher) error {
+	if len(g.Parts) != len(c.Categories) {
+		return fmt.Errorf("gopher has %v parts; expected %v", len(g.Parts), len(c.Categories))
+	}
+
+	if g.Background != "" {
+		if _, err := ParseColour(g.Background); err != nil {
+			return fmt.Errorf("background: %v", err)
+		}
+	}
+
+	if g.Effects != nil {
+		if err := g.Effects.Validate(); err != nil {
+			return fmt.Errorf("effects: %v", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+
+	for i, ps := range g.Parts {
+		cat := c.Categories[i]
+
+		if len(ps) == 0 && !cat.Has("") {
+			return fmt.Errorf("no option selected for category %v", cat.Name)
+		}
+		if len(ps) > cat.MaxSelections() {
+			return fmt.Errorf("%v options selected for category %v; at most %v allowed", len(ps), cat.Name, cat.MaxSelections())
+		}
+
+		for _, p := range ps {
+			if p == "" || !cat.Has(p) {
+				return fmt.Errorf("unknown option %q for category %v", p, cat.Name)
+			}
+			if seen[p] {
+				return fmt.Errorf("option %q selected more than once", p)
+			}
+			seen[p] = true
+		}
+	}
+
+	if len(g.Uploads) > MaxUploads {
+		return fmt.Errorf("gopher has %v uploads; at most %v allowed", len(g.Uploads), MaxUploads)
+	}
+	for _, u := range g.Uploads {
+		if err := u.Validate(); err != nil {
+			return err
+		}
+		if seen[u.Part()] {
+			return fmt.Errorf("upload %v included more than once", u.ID)
+		}
+		seen[u.Part()] = true
+	}
+
+	for _, t := range g.Texts {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+		if seen[t.Part()] {
+			return fmt.Errorf("text %v included more than once", t.ID)
+		}
+		seen[t.Part()] = true
+	}
+
+	for p := range g.Transforms {
+		if !g.Selected(p) {
+			return fmt.Errorf("transform for part %q that is not selected", p)
+		}
+	}
+
+	seen = make(map[string]bool)
+	for _, p := range g.Order {
+		if !g.Selected(p) {
+			return fmt.Errorf("layer order refers to part %q that is not selected", p)
+		}
+		if seen[p] {
+			return fmt.Errorf("layer order lists part %q more than once", p)
+		}
+		seen[p] = true
+	}
+
+	for p := range g.Hidden {
+		if !g.Selected(p) {
+			return fmt.Errorf("part %q is hidden but not selected", p)
+		}
+	}
+
+	return nil
+}
+
+// Has reports whether o is one of the options of c.
+func (c *Category) Has(o string) bool {
+	for _, v := range c.Options {
+		if v == o {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package gopher
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testConfig is a catalogue of a required category, an optional one and one
+// of which several options may be chosen.
+var testConfig = &Config{
+	Categories: []*Category{
+		{Name: "Body", Options: []string{"body/a", "body/b"}},
+		{Name: "Eyes", Options: []string{"", "eyes/a", "eyes/b"}},
+		{Name: "Extras", Options: []string{"", "extras/a", "extras/b", "extras/c"}, Max: 2},
+	},
+}
+
+func TestRecipeRoundTrip(t *testing.T) {
+	g := &Gopher{
+		Parts:      [][]string{{"body/a"}, nil, {"extras/c", "extras/a"}},
+		Transforms: map[string]Transform{"extras/a": {FlipH: true, X: 10, Scale: 1.5}},
+		Order:      []string{"extras/c", "body/a"},
+		Hidden:     map[string]bool{"extras/a": true},
+		Background: "#ffcc00",
+	}
+
+	b, err := MarshalRecipe(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalRecipe(b, testConfig)
+	if err != nil {
+		t.Fatalf("UnmarshalRecipe(%s): %v", b, err)
+	}
+	if !reflect.DeepEqual(got, g) {
+		t.Errorf("UnmarshalRecipe(%s) = %#v; want %#v", b, got, g)
+	}
+
+	s, err := EncodeRecipe(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = DecodeRecipe(s, testConfig)
+	if err != nil {
+		t.Fatalf("DecodeRecipe(%q): %v", s, err)
+	}
+	if !reflect.DeepEqual(got, g) {
+		t.Errorf("DecodeRecipe(%q) = %#v; want %#v", s, got, g)
+	}
+}
+
+func TestUnmarshalRecipe(t *testing.T) {
+	// transforms are clamped as they are read, and identities dropped
+	b := `{"Version": 1, "Parts": [["body/a"], ["eyes/a"], []], "Transforms": {"body/a": {"X": 1000}, "eyes/a": {}}}`
+
+	g, err := UnmarshalRecipe([]byte(b), testConfig)
+	if err != nil {
+		t.Fatalf("UnmarshalRecipe(%s): %v", b, err)
+	}
+	if want := map[string]Transform{"body/a": {X: MaxOffset, Scale: 1}}; !reflect.DeepEqual(g.Transforms, want) {
+		t.Errorf("Transforms = %v; want %v", g.Transforms, want)
+	}
+}
+
+func TestUnmarshalRecipeErrors(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{`, "failed to parse recipe"},
+		{`{"Parts": [["body/a"], [], []]}`, "unsupported recipe version 0"},
+		{`{"Version": 2, "Parts": [["body/a"], [], []]}`, "unsupported recipe version 2"},
+		{`{"Version": 1, "Parts": ["body/a", "", ""]}`, "failed to parse recipe"},
+		{`{"Version": 1, "Parts": [["body/a"]]}`, "gopher has 1 parts; expected 3"},
+	}
+
+	for _, test := range tests {
+		_, err := UnmarshalRecipe([]byte(test.in), testConfig)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("UnmarshalRecipe(%s) gave error %v; want %q", test.in, err, test.want)
+		}
+	}
+
+	if _, err := DecodeRecipe("not base64!", testConfig); err == nil {
+		t.Errorf("DecodeRecipe of invalid base64 gave no error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Gopher {
+		return &Gopher{Parts: [][]string{{"body/a"}, {"eyes/a"}, {"extras/a"}}}
+	}
+
+	tests := []struct {
+		name string
+		edit func(g *Gopher)
+		want string
+	}{
+		{"valid", func(g *Gopher) {}, ""},
+		{"no optional parts", func(g *Gopher) { g.Parts[1], g.Parts[2] = nil, nil }, ""},
+		{"two extras", func(g *Gopher) { g.Parts[2] = []string{"extras/a", "extras/b"} }, ""},
+		{"too few parts", func(g *Gopher) { g.Parts = g.Parts[:2] }, "gopher has 2 parts; expected 3"},
+		{"no body", func(g *Gopher) { g.Parts[0] = nil }, "no option selected for category Body"},
+		{"two bodies", func(g *Gopher) { g.Parts[0] = []string{"body/a", "body/b"} }, "2 options selected for category Body; at most 1 allowed"},
+		{"three extras", func(g *Gopher) { g.Parts[2] = []string{"extras/a", "extras/b", "extras/c"} }, "3 options selected for category Extras; at most 2 allowed"},
+		{"unknown option", func(g *Gopher) { g.Parts[1] = []string{"eyes/c"} }, `unknown option "eyes/c" for category Eyes`},
+		{"option of another category", func(g *Gopher) { g.Parts[1] = []string{"body/b"} }, `unknown option "body/b" for category Eyes`},
+		{"empty option", func(g *Gopher) { g.Parts[1] = []string{""} }, `unknown option "" for category Eyes`},
+		{"repeated option", func(g *Gopher) { g.Parts[2] = []string{"extras/a", "extras/a"} }, `option "extras/a" selected more than once`},
+		{"transform", func(g *Gopher) { g.SetTransform("eyes/a", Transform{X: 1}) }, ""},
+		{"transform of unselected part", func(g *Gopher) { g.SetTransform("eyes/b", Transform{X: 1}) }, `transform for part "eyes/b" that is not selected`},
+		{"order", func(g *Gopher) { g.Order = []string{"eyes/a", "body/a"} }, ""},
+		{"order of unselected part", func(g *Gopher) { g.Order = []string{"eyes/b"} }, `layer order refers to part "eyes/b" that is not selected`},
+		{"order repeated", func(g *Gopher) { g.Order = []string{"eyes/a", "eyes/a"} }, `layer order lists part "eyes/a" more than once`},
+		{"hidden", func(g *Gopher) { g.SetHidden("eyes/a", true) }, ""},
+		{"hidden unselected part", func(g *Gopher) { g.SetHidden("eyes/b", true) }, `part "eyes/b" is hidden but not selected`},
+		{"upload without data", func(g *Gopher) { g.AddUpload(Upload{ID: "u"}) }, ""},
+		{"upload without ID", func(g *Gopher) { g.Uploads = []Upload{{}} }, "upload has no ID"},
+		{"upload repeated", func(g *Gopher) { g.Uploads = []Upload{{ID: "u"}, {ID: "u"}} }, "upload u included more than once"},
+		{"too many uploads", func(g *Gopher) {
+			for i := 0; i <= MaxUploads; i++ {
+				g.AddUpload(Upload{ID: string('a' + rune(i))})
+			}
+		}, "gopher has 6 uploads; at most 5 allowed"},
+		{"text", func(g *Gopher) { g.SetText(Text{Text: "Gordon"}) }, ""},
+		{"text repeated", func(g *Gopher) { g.Texts = []Text{{ID: "1"}, {ID: "1"}} }, "text 1 included more than once"},
+		{"invalid text", func(g *Gopher) { g.SetText(Text{Text: "Gordon", Font: "comic"}) }, `text 1 has unknown font "comic"`},
+		{"background", func(g *Gopher) { g.Background = "#00ff00" }, ""},
+		{"invalid background", func(g *Gopher) { g.Background = "green" }, "background: invalid colour"},
+		{"effects", func(g *Gopher) { g.Effects = &Effects{Shadow: true} }, ""},
+		{"invalid effects", func(g *Gopher) { g.Effects = &Effects{Outline: -1} }, "effects: "},
+	}
+
+	for _, test := range tests {
+		g := valid()
+		test.edit(g)
+
+		err := testConfig.Validate(g)
+		switch {
+		case test.want == "" && err != nil:
+			t.Errorf("%v: Validate gave error %v", test.name, err)
+		case test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)):
+			t.Errorf("%v: Validate gave error %v; want %q", test.name, err, test.want)
+		}
+	}
+}
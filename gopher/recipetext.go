@@ -0,0 +1,602 @@
+package gopher
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The text recipe format is a human editable alternative to the JSON recipe,
+// suited to files that are checked in and reviewed. A text recipe is a list
+// of key=value fields separated by white space, with comments running from a
+// # that starts a field to the end of the line:
+//
+//	# our team gopher
+//	body=blue_gopher
+//	eyes=goofy_eyes
+//	hair=man_bun:flip:x=-20
+//	extras=coffee,laptop
+//	background=#ffcc00
+//
+// The key of a part is the name of its category in lower case, with spaces
+// replaced by underscores, or any unambiguous prefix of it, for example hats
+// for hats_and_hair_accessories. Its value is a comma separated list of
+// options, by the last element of their name, or none. Each option may be
+// followed by modifiers, separated by colons: flip, hidden, scale=s, x=n and
+// y=n. A key may be repeated to select several options of a category.
+//
+// The other keys are background, and the effects outline, outline_colour,
+// shadow, filter, dark, light and pixel_size, whose values are as in the JSON
+// recipe. Uploads, texts and a custom layer order cannot be expressed.
+
+// The keys of the text recipe format other than those of categories.
+const (
+	keyBackground    = "background"
+	keyOutline       = "outline"
+	keyOutlineColour = "outline_colour"
+	keyShadow        = "shadow"
+	keyFilter        = "filter"
+	keyDark          = "dark"
+	keyLight         = "light"
+	keyPixelSize     = "pixel_size"
+)
+
+var textKeys = []string{
+	keyBackground,
+	keyOutline,
+	keyOutlineColour,
+	keyShadow,
+	keyFilter,
+	keyDark,
+	keyLight,
+	keyPixelSize,
+}
+
+// The modifiers of an option in the text recipe format.
+const (
+	modFlip   = "flip"
+	modHidden = "hidden"
+	modScale  = "scale"
+	modX      = "x"
+	modY      = "y"
+)
+
+var textModifiers = []string{modFlip, modHidden, modScale, modX, modY}
+
+// noneOption is the value of a category for which no option is selected.
+const noneOption = "none"
+
+// RecipeTextError is an error in a text recipe, at the given line and
+// column, both counted from 1, or at no particular place if Line is 0.
+type RecipeTextError struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e *RecipeTextError) Error() string {
+	if e.Line == 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("%v:%v: %v", e.Line, e.Col, e.Msg)
+}
+
+// RecipeTextErrors is the list of errors found in a text recipe, in the
+// order in which they appear.
+type RecipeTextErrors []*RecipeTextError
+
+func (l RecipeTextErrors) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%v (and %v more errors)", l[0], len(l)-1)
+}
+
+// CategoryKey returns the key of the category called name in the text recipe
+// format.
+func CategoryKey(name string) string {
+	return strings.Replace(strings.ToLower(name), " ", "_", -1)
+}
+
+// OptionKey returns the name by which the option o is given in the text
+// recipe format.
+func OptionKey(o string) string {
+	if o == "" {
+		return noneOption
+	}
+	return path.Base(o)
+}
+
+// textParser holds the state of ParseRecipeText.
+type textParser struct {
+	c    *Config
+	g    *Gopher
+	errs RecipeTextErrors
+
+	// line and col are the position of the field being parsed
+	line, col int
+}
+
+// errorf records an error at the position of the field being parsed, offset
+// by off bytes.
+func (p *textParser) errorf(off int, format string, args ...interface{}) {
+	p.errs = append(p.errs, &RecipeTextError{
+		Line: p.line,
+		Col:  p.col + off,
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+// ParseRecipeText parses the text recipe b, resolving the names of options
+// against c. All the errors found are reported, as RecipeTextErrors.
+func ParseRecipeText(b []byte, c *Config) (*Gopher, error) {
+	p := &textParser{
+		c: c,
+		g: &Gopher{Parts: make([][]string, len(c.Categories))},
+	}
+
+	for i, l := range strings.Split(string(b), "\n") {
+		p.line = i + 1
+
+		for off := 0; off < len(l); {
+			if isSpace(l[off]) {
+				off++
+				continue
+			}
+
+			if l[off] == '#' {
+				break
+			}
+
+			end := off
+			for end < len(l) && !isSpace(l[end]) {
+				end++
+			}
+
+			p.col = off + 1
+			p.field(l[off:end])
+			off = end
+		}
+	}
+
+	p.line, p.col = 0, 0
+
+	for i, cat := range c.Categories {
+		if len(p.g.Parts[i]) == 0 && !cat.Has("") {
+			p.errorf(0, "no option given for %v", CategoryKey(cat.Name))
+		}
+	}
+
+	if len(p.errs) > 0 {
+		return nil, p.errs
+	}
+
+	// anything missed above is still reported, albeit without a position
+	if err := c.Validate(p.g); err != nil {
+		return nil, RecipeTextErrors{{Msg: err.Error()}}
+	}
+
+	return p.g, nil
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r'
+}
+
+// field parses the field f.
+func (p *textParser) field(f string) {
+	eq := strings.IndexByte(f, '=')
+	if eq == -1 {
+		p.errorf(0, "expected key=value; found %q", f)
+		return
+	}
+
+	key, val := f[:eq], f[eq+1:]
+	voff := eq + 1
+
+	switch {
+	case key == "":
+		p.errorf(0, "no key given for %q", val)
+		return
+	case val == "":
+		p.errorf(voff, "no value given for %v", key)
+		return
+	}
+
+	if key == keyBackground {
+		if _, err := ParseColour(val); err != nil {
+			p.errorf(voff, "%v", err)
+			return
+		}
+		p.g.Background = val
+		return
+	}
+
+	if contains(textKeys, key) {
+		p.effect(key, val, voff)
+		return
+	}
+
+	switch i, matches := p.category(key); {
+	case i != -1:
+		p.parts(i, val, voff)
+		return
+	case len(matches) > 1:
+		p.errorf(0, "ambiguous key %q could be any of %v", key, strings.Join(matches, ", "))
+		return
+	}
+
+	keys := append([]string(nil), textKeys...)
+	for _, cat := range p.c.Categories {
+		keys = append(keys, CategoryKey(cat.Name))
+	}
+	p.errorf(0, "unknown key %q%v", key, didYouMean(key, keys))
+}
+
+// category returns the index of the category whose key is, or is uniquely
+// prefixed by, key, or -1 together with the keys of the categories it
+// prefixes.
+func (p *textParser) category(key string) (int, []string) {
+	res := -1
+	var matches []string
+
+	for i, cat := range p.c.Categories {
+		k := CategoryKey(cat.Name)
+		if k == key {
+			return i, nil
+		}
+		if key != "" && strings.HasPrefix(k, key) {
+			res = i
+			matches = append(matches, k)
+		}
+	}
+
+	if len(matches) != 1 {
+		return -1, matches
+	}
+
+	return res, nil
+}
+
+// parts parses the options val, found at offset off, of the category i.
+func (p *textParser) parts(i int, val string, off int) {
+	cat := p.c.Categories[i]
+	key := CategoryKey(cat.Name)
+
+	for _, item := range strings.Split(val, ",") {
+		ioff := off
+		off += len(item) + 1
+
+		mods := strings.Split(item, ":")
+		name := mods[0]
+
+		if name == noneOption {
+			if !cat.Has("") {
+				p.errorf(ioff, "an option must be given for %v", key)
+			} else if len(mods) > 1 {
+				p.errorf(ioff, "%v cannot have modifiers", noneOption)
+			} else if val != noneOption || len(p.g.Parts[i]) > 0 {
+				p.errorf(ioff, "%v cannot be combined with options of %v", noneOption, key)
+			}
+			continue
+		}
+
+		o, ok := p.option(cat, name, ioff)
+		if !ok {
+			continue
+		}
+
+		if p.g.Selected(o) {
+			p.errorf(ioff, "option %v given more than once", name)
+			continue
+		}
+		if len(p.g.Parts[i]) == cat.MaxSelections() {
+			p.errorf(ioff, "at most %v options may be given for %v", cat.MaxSelections(), key)
+			continue
+		}
+
+		p.g.Parts[i] = append(p.g.Parts[i], o)
+
+		moff := ioff + len(name) + 1
+		for _, m := range mods[1:] {
+			p.modifier(o, m, moff)
+			moff += len(m) + 1
+		}
+	}
+}
+
+// option returns the option of cat given by name, found at offset off.
+func (p *textParser) option(cat *Category, name string, off int) (string, bool) {
+	var names []string
+
+	for _, o := range cat.Options {
+		if o == "" {
+			continue
+		}
+		if o == name || OptionKey(o) == name {
+			return o, true
+		}
+		names = append(names, OptionKey(o))
+	}
+
+	key := CategoryKey(cat.Name)
+
+	// the option may have been given for the wrong category
+	for _, other := range p.c.Categories {
+		if other == cat {
+			continue
+		}
+		for _, o := range other.Options {
+			if o != "" && OptionKey(o) == name {
+				p.errorf(off, "%v is not an option of %v but of %v", name, key, CategoryKey(other.Name))
+				return "", false
+			}
+		}
+	}
+
+	p.errorf(off, "unknown option %q for %v%v", name, key, didYouMean(name, names))
+	return "", false
+}
+
+// modifier applies the modifier m, found at offset off, to the option o.
+func (p *textParser) modifier(o string, m string, off int) {
+	name, val := m, ""
+	hasVal := false
+	if i := strings.IndexByte(m, '='); i != -1 {
+		name, val, hasVal = m[:i], m[i+1:], true
+	}
+
+	switch name {
+	case modFlip, modHidden:
+		if hasVal {
+			p.errorf(off, "modifier %v takes no value", name)
+			return
+		}
+	case modScale, modX, modY:
+		if !hasVal {
+			p.errorf(off, "modifier %v requires a value, as in %v=1", name, name)
+			return
+		}
+	default:
+		p.errorf(off, "unknown modifier %q%v", name, didYouMean(name, textModifiers))
+		return
+	}
+
+	t := p.g.Transform(o)
+
+	switch name {
+	case modFlip:
+		t.FlipH = true
+	case modHidden:
+		p.g.SetHidden(o, true)
+		return
+	case modScale:
+		s, err := strconv.ParseFloat(val, 64)
+		if err != nil || s < MinScale || s > MaxScale {
+			p.errorf(off, "invalid scale %q; expected a number in [%v, %v]", val, MinScale, MaxScale)
+			return
+		}
+		t.Scale = s
+	case modX, modY:
+		v, err := strconv.Atoi(val)
+		if err != nil || v < -MaxOffset || v > MaxOffset {
+			p.errorf(off, "invalid offset %q; expected a whole number in [%v, %v]", val, -MaxOffset, MaxOffset)
+			return
+		}
+		if name == modX {
+			t.X = v
+		} else {
+			t.Y = v
+		}
+	}
+
+	p.g.SetTransform(o, t)
+}
+
+// effect sets the effect key to val, found at offset off.
+func (p *textParser) effect(key, val string, off int) {
+	var e Effects
+	if p.g.Effects != nil {
+		e = *p.g.Effects
+	}
+
+	atoi := func() int {
+		v, err := strconv.Atoi(val)
+		if err != nil {
+			// a value that is certain to fail validation
+			return -1
+		}
+		return v
+	}
+
+	switch key {
+	case keyOutline:
+		e.Outline = atoi()
+	case keyOutlineColour:
+		e.OutlineColour = val
+	case keyShadow:
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			p.errorf(off, "invalid %v %q; expected true or false", key, val)
+			return
+		}
+		e.Shadow = v
+	case keyFilter:
+		if !contains(Filters, val) {
+			p.errorf(off, "unknown filter %q%v", val, didYouMean(val, Filters))
+			return
+		}
+		e.Filter = val
+	case keyDark:
+		e.Dark = val
+	case keyLight:
+		e.Light = val
+	case keyPixelSize:
+		e.PixelSize = atoi()
+	}
+
+	if err := e.Validate(); err != nil {
+		p.errorf(off, "invalid %v %q: %v", key, val, err)
+		return
+	}
+
+	p.g.Effects = nil
+	if !e.IsZero() {
+		p.g.Effects = &e
+	}
+}
+
+// FormatRecipeText returns g, whose options are those of c, in the canonical
+// text recipe format: one field per line, parts in the order of the
+// categories of c, followed by the background and effects. It fails for
+// gophers that cannot be expressed in the format.
+func FormatRecipeText(g *Gopher, c *Config) ([]byte, error) {
+	if err := c.Validate(g); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(g.Uploads) > 0:
+		return nil, fmt.Errorf("uploads cannot be written in a text recipe")
+	case len(g.Texts) > 0:
+		return nil, fmt.Errorf("texts cannot be written in a text recipe")
+	case len(g.Order) > 0:
+		return nil, fmt.Errorf("a layer order cannot be written in a text recipe")
+	}
+
+	var buf bytes.Buffer
+
+	for i, cat := range c.Categories {
+		ps := g.Parts[i]
+		if len(ps) == 0 {
+			continue
+		}
+
+		var items []string
+		for _, o := range ps {
+			items = append(items, formatOption(g, o))
+		}
+
+		fmt.Fprintf(&buf, "%v=%v\n", CategoryKey(cat.Name), strings.Join(items, ","))
+	}
+
+	if g.Background != "" {
+		fmt.Fprintf(&buf, "%v=%v\n", keyBackground, g.Background)
+	}
+
+	if e := g.Effects; !e.IsZero() {
+		field := func(key string, v interface{}, omit bool) {
+			if !omit {
+				fmt.Fprintf(&buf, "%v=%v\n", key, v)
+			}
+		}
+
+		field(keyOutline, e.Outline, e.Outline == 0)
+		field(keyOutlineColour, e.OutlineColour, e.OutlineColour == "")
+		field(keyShadow, e.Shadow, !e.Shadow)
+		field(keyFilter, e.Filter, e.Filter == "")
+		field(keyDark, e.Dark, e.Dark == "")
+		field(keyLight, e.Light, e.Light == "")
+		field(keyPixelSize, e.PixelSize, e.PixelSize == 0)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// formatOption returns the option o of g followed by its modifiers.
+func formatOption(g *Gopher, o string) string {
+	res := OptionKey(o)
+
+	t := g.Transform(o).Clamp()
+	if t.FlipH {
+		res += ":" + modFlip
+	}
+	if t.Scale != 1 {
+		res += ":" + modScale + "=" + strconv.FormatFloat(t.Scale, 'f', -1, 64)
+	}
+	if t.X != 0 {
+		res += ":" + modX + "=" + strconv.Itoa(t.X)
+	}
+	if t.Y != 0 {
+		res += ":" + modY + "=" + strconv.Itoa(t.Y)
+	}
+	if g.Hidden[o] {
+		res += ":" + modHidden
+	}
+
+	return res
+}
+
+// didYouMean returns a suggestion of the candidates closest to s, of the form
+// `; did you mean "x"?`, or "" if none is close enough to be likely.
+func didYouMean(s string, candidates []string) string {
+	// allow roughly one edit for every three characters
+	max := utf8.RuneCountInString(s)/3 + 1
+
+	type match struct {
+		c string
+		d int
+	}
+	var ms []match
+
+	for _, c := range candidates {
+		if d := editDistance(strings.ToLower(s), strings.ToLower(c)); d <= max {
+			ms = append(ms, match{c, d})
+		}
+	}
+
+	if len(ms) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(ms, func(i, j int) bool {
+		return ms[i].d < ms[j].d
+	})
+
+	res := fmt.Sprintf("; did you mean %q", ms[0].c)
+	if len(ms) > 1 && ms[1].d == ms[0].d {
+		res += fmt.Sprintf(" or %q", ms[1].c)
+	}
+
+	return res + "?"
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minOf(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func minOf(v int, vs ...int) int {
+	for _, w := range vs {
+		if w < v {
+			v = w
+		}
+	}
+	return v
+}
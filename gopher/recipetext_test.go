@@ -0,0 +1,266 @@
+package gopher
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRecipeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *Gopher
+	}{
+		{
+			in: "body=blue_gopher eyes=eyes",
+			want: &Gopher{Parts: [][]string{
+				{"010-Body/blue_gopher"}, {"020-Eyes/eyes"}, nil, nil, nil, nil, nil, nil,
+			}},
+		},
+		{
+			in: `
+# prefixes of keys, full option names, none and repeated keys
+body=010-Body/pink_gopher
+eyes=crazy_eyes   # trailing comment
+glasses=none
+extras=bowtie
+extras=camera,cellphone
+`,
+			want: &Gopher{Parts: [][]string{
+				{"010-Body/pink_gopher"}, {"020-Eyes/crazy_eyes"}, nil, nil, nil, nil, nil,
+				{"027-Extras/bowtie", "027-Extras/camera", "027-Extras/cellphone"},
+			}},
+		},
+		{
+			in: "body=blue_gopher eyes=eyes hats=unicorn_horn_pink:flip:scale=1.5:x=-20:y=10 glasses=blue_lenses:hidden",
+			want: &Gopher{
+				Parts: [][]string{
+					{"010-Body/blue_gopher"}, {"020-Eyes/eyes"}, nil, nil, nil,
+					{"024-Glasses/blue_lenses"}, {"025-Hats_and_Hair_Accessories/unicorn_horn_pink"}, nil,
+				},
+				Transforms: map[string]Transform{
+					"025-Hats_and_Hair_Accessories/unicorn_horn_pink": {FlipH: true, Scale: 1.5, X: -20, Y: 10},
+				},
+				Hidden: map[string]bool{"024-Glasses/blue_lenses": true},
+			},
+		},
+		{
+			in: "body=blue_gopher\teyes=eyes\r\nbackground=#ffcc00 filter=duotone dark=#000000 light=#ffffff shadow=true",
+			want: &Gopher{
+				Parts: [][]string{
+					{"010-Body/blue_gopher"}, {"020-Eyes/eyes"}, nil, nil, nil, nil, nil, nil,
+				},
+				Background: "#ffcc00",
+				Effects:    &Effects{Filter: FilterDuotone, Dark: "#000000", Light: "#ffffff", Shadow: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		g, err := ParseRecipeText([]byte(test.in), DefaultConfig)
+		if err != nil {
+			t.Errorf("ParseRecipeText(%q): %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(g, test.want) {
+			t.Errorf("ParseRecipeText(%q) = %#v; want %#v", test.in, g, test.want)
+		}
+	}
+}
+
+func TestParseRecipeTextErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"body=blue_gopher", []string{"no option given for eyes"}},
+		{"body=blue_gopher eyes=eyes nonsense", []string{`1:28: expected key=value; found "nonsense"`}},
+		{"body=blue_gopher eyes=eyes =x", []string{`1:28: no key given for "x"`}},
+		{"body=blue_gopher eyes=eyes hair=", []string{"1:33: no value given for hair"}},
+		{"body=blue_gopher eyes=eyes h=x", []string{`1:28: ambiguous key "h" could be any of hair, hats_and_hair_accessories`}},
+		{"body=blue_gopher eyes=eyes\nglases=blue_lenses", []string{`2:1: unknown key "glases"; did you mean "glasses"?`}},
+		{"body=blue_gopher eyes=eyes\nbackgruond=#ffffff", []string{`2:1: unknown key "backgruond"; did you mean "background"?`}},
+		{"body=blue_gopher eyes=eyes glasses=blue_lense", []string{`1:36: unknown option "blue_lense" for glasses; did you mean "blue_lenses"?`}},
+		{"body=blue_gopher eyes=eyes glasses=zzzzzzzzzz", []string{`1:36: unknown option "zzzzzzzzzz" for glasses`}},
+		{"body=blue_gopher eyes=eyes glasses=bowtie", []string{"1:36: bowtie is not an option of glasses but of extras"}},
+		{"body=none eyes=eyes", []string{"1:6: an option must be given for body", "no option given for body"}},
+		{"body=blue_gopher eyes=eyes glasses=none:flip", []string{"1:36: none cannot have modifiers"}},
+		{"body=blue_gopher eyes=eyes extras=bowtie,none", []string{"1:42: none cannot be combined with options of extras"}},
+		{"body=blue_gopher eyes=eyes eyes=eyes", []string{"1:33: option eyes given more than once"}},
+		{"body=blue_gopher eyes=eyes,goofy_eyes", []string{"1:28: at most 1 options may be given for eyes"}},
+		{"body=blue_gopher eyes=eyes extras=bowtie,camera,cellphone,captain_america", []string{"1:59: at most 3 options may be given for extras"}},
+		{"body=blue_gopher:flp eyes=eyes", []string{`1:18: unknown modifier "flp"; did you mean "flip"?`}},
+		{"body=blue_gopher:flip=1 eyes=eyes", []string{"1:18: modifier flip takes no value"}},
+		{"body=blue_gopher:scale eyes=eyes", []string{"1:18: modifier scale requires a value, as in scale=1"}},
+		{"body=blue_gopher:scale=3 eyes=eyes", []string{`1:18: invalid scale "3"; expected a number in [0.5, 2]`}},
+		{"body=blue_gopher:x=1000 eyes=eyes", []string{`1:18: invalid offset "1000"; expected a whole number in [-400, 400]`}},
+		{"body=blue_gopher eyes=eyes shadow=maybe", []string{`1:35: invalid shadow "maybe"; expected true or false`}},
+		{"body=blue_gopher eyes=eyes filter=greyscale", []string{`1:35: unknown filter "greyscale"; did you mean "grayscale"?`}},
+		{"body=blue_gopher eyes=eyes background=yellow", []string{"1:39: "}},
+		{
+			// every error is reported, in order
+			"body=blue_gopher:flp\nglases=x",
+			[]string{`1:18: unknown modifier "flp"`, `2:1: unknown key "glases"`, "no option given for eyes"},
+		},
+	}
+
+	for _, test := range tests {
+		_, err := ParseRecipeText([]byte(test.in), DefaultConfig)
+		errs, ok := err.(RecipeTextErrors)
+		if !ok {
+			t.Errorf("ParseRecipeText(%q) gave error %v; want RecipeTextErrors", test.in, err)
+			continue
+		}
+		if len(errs) != len(test.want) {
+			t.Errorf("ParseRecipeText(%q) gave errors %q; want %q", test.in, errs, test.want)
+			continue
+		}
+		for i, e := range errs {
+			if !strings.HasPrefix(e.Error(), test.want[i]) {
+				t.Errorf("ParseRecipeText(%q) error %v is %q; want %q", test.in, i, e, test.want[i])
+			}
+		}
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	candidates := []string{"glasses", "hair", "hats", "extras"}
+
+	tests := []struct {
+		in, want string
+	}{
+		{"glases", `; did you mean "glasses"?`},
+		{"GLASSES", `; did you mean "glasses"?`},
+		{"har", `; did you mean "hair"?`},
+		{"hax", `; did you mean "hair" or "hats"?`},
+		{"extra", `; did you mean "extras"?`},
+		{"shoes", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := didYouMean(test.in, candidates); got != test.want {
+			t.Errorf("didYouMean(%q) = %q; want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"flip", "flp", 1},
+		{"café", "cafe", 1},
+	}
+
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %v; want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestFormatRecipeText(t *testing.T) {
+	g := &Gopher{
+		Parts: [][]string{
+			{"010-Body/blue_gopher"}, {"020-Eyes/eyes"}, nil, nil, nil,
+			{"024-Glasses/blue_lenses"}, nil, {"027-Extras/bowtie", "027-Extras/camera"},
+		},
+		Transforms: map[string]Transform{
+			"027-Extras/bowtie": {FlipH: true, Scale: 1.25, X: 5, Y: -5},
+		},
+		Hidden:     map[string]bool{"024-Glasses/blue_lenses": true},
+		Background: "#ffcc00",
+		Effects:    &Effects{Outline: 4, OutlineColour: "#ffffff"},
+	}
+
+	want := `body=blue_gopher
+eyes=eyes
+glasses=blue_lenses:hidden
+extras=bowtie:flip:scale=1.25:x=5:y=-5,camera
+background=#ffcc00
+outline=4
+outline_colour=#ffffff
+`
+
+	got, err := FormatRecipeText(g, DefaultConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("FormatRecipeText gave\n%s\nwant\n%s", got, want)
+	}
+
+	for _, g := range []*Gopher{
+		{Parts: g.Parts, Uploads: []Upload{{ID: "x"}}},
+		{Parts: g.Parts, Texts: []Text{{Text: "x"}}},
+		{Parts: g.Parts, Order: []string{"010-Body/blue_gopher"}},
+	} {
+		if _, err := FormatRecipeText(g, DefaultConfig); err == nil {
+			t.Errorf("FormatRecipeText(%#v) gave no error", g)
+		}
+	}
+}
+
+// TestRecipeTextRoundTrip checks that every option of the catalogue, with
+// modifiers, survives being formatted and parsed again.
+func TestRecipeTextRoundTrip(t *testing.T) {
+	c := DefaultConfig
+
+	base := func() *Gopher {
+		g := &Gopher{Parts: make([][]string, len(c.Categories))}
+		for i, cat := range c.Categories {
+			if !cat.Has("") {
+				g.Parts[i] = []string{cat.Options[0]}
+			}
+		}
+		return g
+	}
+
+	var gophers []*Gopher
+	for i, cat := range c.Categories {
+		for _, o := range cat.Options {
+			if o == "" {
+				continue
+			}
+
+			g := base()
+			g.Parts[i] = []string{o}
+			gophers = append(gophers, g)
+
+			g = base()
+			g.Parts[i] = []string{o}
+			g.SetTransform(o, Transform{FlipH: true, Scale: 0.75, X: -MaxOffset, Y: 12})
+			g.SetHidden(o, true)
+			gophers = append(gophers, g)
+		}
+	}
+
+	g := base()
+	g.Background = "#102030"
+	g.Effects = &Effects{Filter: FilterGrayscale, Shadow: true, PixelSize: 8}
+	gophers = append(gophers, g)
+
+	for _, g := range gophers {
+		b, err := FormatRecipeText(g, c)
+		if err != nil {
+			t.Errorf("FormatRecipeText(%#v): %v", g, err)
+			continue
+		}
+
+		got, err := ParseRecipeText(b, c)
+		if err != nil {
+			t.Errorf("ParseRecipeText(%q): %v", b, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, g) {
+			t.Errorf("round trip of %q gave %#v; want %#v", b, got, g)
+		}
+	}
+}
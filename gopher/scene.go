@@ -0,0 +1,216 @@
+package gopher
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// SceneVersion is the version of the scene format written by MarshalScene.
+//
+// Version history:
+//
+//	1: initial version
+const SceneVersion = 1
+
+// Limits on scenes; see Scene.Validate.
+const (
+	MaxSceneSize    = 8000
+	MaxSceneGophers = 32
+	MinSceneScale   = 0.05
+	MaxSceneScale   = 4
+)
+
+// Scene is a number of gophers placed on a shared canvas, for example a
+// banner. Gophers are drawn in order, so later gophers appear in front of
+// earlier ones.
+type Scene struct {
+	// Width and Height are the size of the canvas in pixels.
+	Width  int
+	Height int
+
+	// Background is the colour of the canvas, "" for transparent. The
+	// backgrounds of the gophers themselves are not drawn.
+	Background string
+
+	Gophers []Placement
+}
+
+// NumUploads returns the number of uploads of all the gophers of s. Each
+// gopher carries its own copy of its uploads, so a scene is limited to
+// MaxUploads in total, just as a single gopher is.
+func (s *Scene) NumUploads() int {
+	n := 0
+	for _, p := range s.Gophers {
+		if p.Gopher != nil {
+			n += len(p.Gopher.Uploads)
+		}
+	}
+	return n
+}
+
+// Placement is a gopher of a scene and where it is placed.
+type Placement struct {
+	Gopher *Gopher
+
+	// X and Y are the position in pixels of the centre of the canvas of the
+	// gopher within the scene.
+	X float64
+	Y float64
+
+	// Scale is the width of the gopher relative to its own canvas; zero is
+	// treated as 1.
+	Scale float64
+
+	// FlipH mirrors the gopher about its vertical centre line.
+	FlipH bool
+}
+
+// Rect returns the position (x, y) of the top left corner and the size (w, h)
+// of the canvas of the placed gopher within the scene.
+func (p Placement) Rect() (x, y, w, h float64) {
+	s := p.Scale
+	if s == 0 {
+		s = 1
+	}
+
+	w = Width * s
+	h = Height * s
+
+	return p.X - w/2, p.Y - h/2, w, h
+}
+
+// Copy returns a copy of s that shares nothing with it.
+func (s *Scene) Copy() *Scene {
+	res := *s
+	res.Gophers = make([]Placement, len(s.Gophers))
+	for i, p := range s.Gophers {
+		p.Gopher = p.Gopher.Copy()
+		res.Gophers[i] = p
+	}
+	return &res
+}
+
+// Validate checks that the size, background and placements of s are within
+// limits, and that its gophers only refer to options found in c.
+func (s *Scene) Validate(c *Config) error {
+	if s.Width < 1 || s.Width > MaxSceneSize || s.Height < 1 || s.Height > MaxSceneSize {
+		return fmt.Errorf("invalid scene size %vx%v; at most %vx%v allowed", s.Width, s.Height, MaxSceneSize, MaxSceneSize)
+	}
+
+	if s.Background != "" {
+		if _, err := ParseColour(s.Background); err != nil {
+			return fmt.Errorf("background: %v", err)
+		}
+	}
+
+	if len(s.Gophers) > MaxSceneGophers {
+		return fmt.Errorf("scene has %v gophers; at most %v allowed", len(s.Gophers), MaxSceneGophers)
+	}
+	if n := s.NumUploads(); n > MaxUploads {
+		return fmt.Errorf("scene has %v uploads; at most %v allowed", n, MaxUploads)
+	}
+
+	for i, p := range s.Gophers {
+		if p.Gopher == nil {
+			return fmt.Errorf("gopher %v: no recipe", i+1)
+		}
+		if math.IsNaN(p.X) || math.IsInf(p.X, 0) || math.IsNaN(p.Y) || math.IsInf(p.Y, 0) {
+			return fmt.Errorf("gopher %v: invalid position", i+1)
+		}
+		if p.Scale != 0 && (p.Scale < MinSceneScale || p.Scale > MaxSceneScale) {
+			return fmt.Errorf("gopher %v: scale %v out of range %v to %v", i+1, p.Scale, MinSceneScale, MaxSceneScale)
+		}
+		if err := c.Validate(p.Gopher); err != nil {
+			return fmt.Errorf("gopher %v: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// scene is the serialised form of a Scene, in which each gopher is held as
+// its own recipe, so that it loads as the recipe format evolves.
+type scene struct {
+	Version    int
+	Width      int
+	Height     int
+	Background string `json:",omitempty"`
+	Gophers    []placement
+}
+
+type placement struct {
+	Recipe json.RawMessage
+	X      float64
+	Y      float64
+	Scale  float64 `json:",omitempty"`
+	FlipH  bool    `json:",omitempty"`
+}
+
+// MarshalScene returns the JSON document for s.
+func MarshalScene(s *Scene) ([]byte, error) {
+	res := scene{
+		Version:    SceneVersion,
+		Width:      s.Width,
+		Height:     s.Height,
+		Background: s.Background,
+		Gophers:    []placement{},
+	}
+
+	for _, p := range s.Gophers {
+		r, err := MarshalRecipe(p.Gopher)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Gophers = append(res.Gophers, placement{
+			Recipe: r,
+			X:      p.X,
+			Y:      p.Y,
+			Scale:  p.Scale,
+			FlipH:  p.FlipH,
+		})
+	}
+
+	return json.Marshal(res)
+}
+
+// UnmarshalScene parses a JSON document previously written by MarshalScene,
+// validating it against c.
+func UnmarshalScene(b []byte, c *Config) (*Scene, error) {
+	var sc scene
+	if err := json.Unmarshal(b, &sc); err != nil {
+		return nil, fmt.Errorf("failed to parse scene: %v", err)
+	}
+
+	if sc.Version != SceneVersion {
+		return nil, fmt.Errorf("unsupported scene version %v", sc.Version)
+	}
+
+	s := &Scene{
+		Width:      sc.Width,
+		Height:     sc.Height,
+		Background: sc.Background,
+	}
+
+	for i, p := range sc.Gophers {
+		g, err := UnmarshalRecipe(p.Recipe, c)
+		if err != nil {
+			return nil, fmt.Errorf("gopher %v: %v", i+1, err)
+		}
+
+		s.Gophers = append(s.Gophers, Placement{
+			Gopher: g,
+			X:      p.X,
+			Y:      p.Y,
+			Scale:  p.Scale,
+			FlipH:  p.FlipH,
+		})
+	}
+
+	if err := s.Validate(c); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
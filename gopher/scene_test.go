@@ -0,0 +1,142 @@
+package gopher
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testSceneGopher(t *testing.T, recipe string) *Gopher {
+	g, err := ParseRecipeText([]byte(recipe), DefaultConfig)
+	if err != nil {
+		t.Fatalf("ParseRecipeText(%q): %v", recipe, err)
+	}
+	return g
+}
+
+func testScene(t *testing.T) *Scene {
+	return &Scene{
+		Width:      1600,
+		Height:     900,
+		Background: "#ffcc00",
+		Gophers: []Placement{
+			{Gopher: testSceneGopher(t, "body=blue_gopher eyes=eyes background=#000000"), X: 400, Y: 450, Scale: 0.5},
+			{Gopher: testSceneGopher(t, "body=pink_gopher eyes=goofy_eyes hair=black_hair:flip"), X: 1200, Y: 450.5, FlipH: true},
+		},
+	}
+}
+
+func TestSceneRoundTrip(t *testing.T) {
+	s := testScene(t)
+
+	b, err := MarshalScene(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := UnmarshalScene(b, DefaultConfig)
+	if err != nil {
+		t.Fatalf("UnmarshalScene(%s): %v", b, err)
+	}
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("round trip gave %#v; want %#v", got, s)
+	}
+}
+
+func TestUnmarshalSceneErrors(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{`, "failed to parse scene"},
+		{`{"Version": 2, "Width": 10, "Height": 10}`, "unsupported scene version 2"},
+		{`{"Version": 1, "Width": 10, "Height": 10, "Gophers": [{"Recipe": {"Parts": [["010-Body/no_such_gopher"]]}}]}`, "gopher 1: "},
+		{`{"Version": 1, "Width": 0, "Height": 10}`, "invalid scene size 0x10"},
+	}
+
+	for _, test := range tests {
+		_, err := UnmarshalScene([]byte(test.in), DefaultConfig)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("UnmarshalScene(%s) gave error %v; want %q", test.in, err, test.want)
+		}
+	}
+}
+
+func TestSceneValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *Scene)
+		want   string
+	}{
+		{"valid", func(s *Scene) {}, ""},
+		{"no placements", func(s *Scene) { s.Gophers = nil }, ""},
+		{"transparent", func(s *Scene) { s.Background = "" }, ""},
+		{"too wide", func(s *Scene) { s.Width = MaxSceneSize + 1 }, "invalid scene size"},
+		{"no height", func(s *Scene) { s.Height = 0 }, "invalid scene size"},
+		{"background", func(s *Scene) { s.Background = "yellow" }, "background: "},
+		{"too many", func(s *Scene) {
+			for len(s.Gophers) <= MaxSceneGophers {
+				s.Gophers = append(s.Gophers, s.Gophers[0])
+			}
+		}, "at most 32 allowed"},
+		{"uploads", func(s *Scene) {
+			for i := 0; i < MaxUploads; i++ {
+				s.Gophers[i%2].Gopher.AddUpload(Upload{ID: string('a' + rune(i))})
+			}
+		}, ""},
+		{"too many uploads", func(s *Scene) {
+			for i := 0; i <= MaxUploads; i++ {
+				s.Gophers[i%2].Gopher.AddUpload(Upload{ID: string('a' + rune(i))})
+			}
+		}, "scene has 6 uploads; at most 5 allowed"},
+		{"no recipe", func(s *Scene) { s.Gophers[1].Gopher = nil }, "gopher 2: no recipe"},
+		{"NaN", func(s *Scene) { s.Gophers[0].X = math.NaN() }, "gopher 1: invalid position"},
+		{"infinite", func(s *Scene) { s.Gophers[0].Y = math.Inf(-1) }, "gopher 1: invalid position"},
+		{"small", func(s *Scene) { s.Gophers[0].Scale = MinSceneScale / 2 }, "gopher 1: scale"},
+		{"large", func(s *Scene) { s.Gophers[0].Scale = MaxSceneScale * 2 }, "gopher 1: scale"},
+		{"recipe", func(s *Scene) { s.Gophers[1].Gopher.Parts[0] = nil }, "gopher 2: "},
+	}
+
+	for _, test := range tests {
+		s := testScene(t)
+		test.modify(s)
+
+		err := s.Validate(DefaultConfig)
+		switch {
+		case test.want == "" && err != nil:
+			t.Errorf("%v: %v", test.name, err)
+		case test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)):
+			t.Errorf("%v: got error %v; want %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestPlacementRect(t *testing.T) {
+	tests := []struct {
+		p          Placement
+		x, y, w, h float64
+	}{
+		{Placement{X: 650, Y: 696}, 0, 0, Width, Height},
+		{Placement{X: 100, Y: 100, Scale: 0.5}, 100 - Width/4, 100 - Height/4, Width / 2, Height / 2},
+		{Placement{Scale: 2, FlipH: true}, -Width, -Height, 2 * Width, 2 * Height},
+	}
+
+	for _, test := range tests {
+		x, y, w, h := test.p.Rect()
+		if x != test.x || y != test.y || w != test.w || h != test.h {
+			t.Errorf("%+v.Rect() = %v, %v, %v, %v; want %v, %v, %v, %v", test.p, x, y, w, h, test.x, test.y, test.w, test.h)
+		}
+	}
+}
+
+func TestSceneCopy(t *testing.T) {
+	s := testScene(t)
+	c := s.Copy()
+
+	c.Gophers[0].X = 0
+	c.Gophers[1].Gopher.Parts[0][0] = "010-Body/blue_gopher"
+
+	if s.Gophers[0].X != 400 || s.Gophers[1].Gopher.Parts[0][0] != "010-Body/pink_gopher" {
+		t.Errorf("changing a copy changed the original")
+	}
+}
@@ -0,0 +1,203 @@
+package gopher
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextPrefix is the prefix of the part name of a Text.
+const TextPrefix = "text/"
+
+// The fonts in which a Text can be drawn.
+const (
+	FontPixel = "pixel"
+	FontBold  = "bold"
+)
+
+// Fonts lists the fonts in which a Text can be drawn, the default first.
+var Fonts = []string{FontPixel, FontBold}
+
+// The positions at which a Text can be drawn.
+const (
+	PositionShirt  = "shirt"
+	PositionBanner = "banner"
+	PositionBubble = "bubble"
+)
+
+// Positions lists the positions at which a Text can be drawn, the default
+// first.
+var Positions = []string{PositionShirt, PositionBanner, PositionBubble}
+
+// Limits on the values of a Text. TextSizeStep is the height of a capital
+// letter of the fonts in their own pixels; sizes are multiples of it, so
+// that each pixel of a font is drawn as a whole square of pixels.
+const (
+	MaxTextLength = 40
+	MaxTextLines  = 3
+	MinTextSize   = 14
+	MaxTextSize   = 210
+	TextSizeStep  = 7
+)
+
+// Text is a text layer of a gopher, for example a name on its shirt or on a
+// banner below it.
+type Text struct {
+	// ID identifies the text within its gopher.
+	ID string
+
+	// Text is the text to draw; lines are separated by "\n".
+	Text string
+
+	// Font is one of Fonts; "" means the default.
+	Font string `json:",omitempty"`
+
+	// Position is one of Positions; "" means the default.
+	Position string `json:",omitempty"`
+
+	// Colour is the colour of the text in the form #rrggbb; "" means black.
+	Colour string `json:",omitempty"`
+
+	// Size is the height in pixels of a capital letter, a multiple of
+	// TextSizeStep; 0 means the text is sized to fit its position.
+	Size int `json:",omitempty"`
+}
+
+// Part returns the name by which t is referred to as a layer of a gopher.
+func (t Text) Part() string {
+	return TextPrefix + t.ID
+}
+
+// IsText reports whether the part p refers to a Text.
+func IsText(p string) bool {
+	return strings.HasPrefix(p, TextPrefix)
+}
+
+// Lines returns the lines of t.
+func (t Text) Lines() []string {
+	return strings.Split(t.Text, "\n")
+}
+
+// FontName returns the font in which t is drawn.
+func (t Text) FontName() string {
+	if t.Font == "" {
+		return Fonts[0]
+	}
+	return t.Font
+}
+
+// PositionName returns the position at which t is drawn.
+func (t Text) PositionName() string {
+	if t.Position == "" {
+		return Positions[0]
+	}
+	return t.Position
+}
+
+// RGBA returns the colour of t.
+func (t Text) RGBA() color.NRGBA {
+	c, err := ParseColour(t.Colour)
+	if err != nil {
+		return color.NRGBA{A: 0xff}
+	}
+	return c
+}
+
+// Validate checks that t is a well formed text layer.
+func (t Text) Validate() error {
+	if t.ID == "" {
+		return fmt.Errorf("text has no ID")
+	}
+	if n := utf8.RuneCountInString(t.Text); n > MaxTextLength {
+		return fmt.Errorf("text %v is %v characters long; at most %v allowed", t.ID, n, MaxTextLength)
+	}
+	if n := len(t.Lines()); n > MaxTextLines {
+		return fmt.Errorf("text %v has %v lines; at most %v allowed", t.ID, n, MaxTextLines)
+	}
+	if !contains(Fonts, t.FontName()) {
+		return fmt.Errorf("text %v has unknown font %q", t.ID, t.Font)
+	}
+	if !contains(Positions, t.PositionName()) {
+		return fmt.Errorf("text %v has unknown position %q", t.ID, t.Position)
+	}
+	if _, err := ParseColour(t.Colour); err != nil {
+		return fmt.Errorf("text %v: %v", t.ID, err)
+	}
+	if t.Size != 0 && (t.Size < MinTextSize || t.Size > MaxTextSize) {
+		return fmt.Errorf("text %v has size %v; must be between %v and %v", t.ID, t.Size, MinTextSize, MaxTextSize)
+	}
+	if t.Size%TextSizeStep != 0 {
+		return fmt.Errorf("text %v has size %v; must be a multiple of %v", t.ID, t.Size, TextSizeStep)
+	}
+
+	return nil
+}
+
+// Text returns the text that is drawn as the part p.
+func (g *Gopher) Text(p string) (Text, bool) {
+	for _, t := range g.Texts {
+		if t.Part() == p {
+			return t, true
+		}
+	}
+	return Text{}, false
+}
+
+// SetText replaces the text with the same ID as t, or adds t as the top layer
+// of g if there is none. A t without an ID is given one.
+func (g *Gopher) SetText(t Text) {
+	if t.ID == "" {
+		t.ID = g.newTextID()
+	}
+
+	ts := append([]Text(nil), g.Texts...)
+
+	for i, v := range ts {
+		if v.ID == t.ID {
+			ts[i] = t
+			g.Texts = ts
+			return
+		}
+	}
+
+	g.Texts = append(ts, t)
+}
+
+func (g *Gopher) newTextID() string {
+	var max int
+	for _, t := range g.Texts {
+		if n, err := strconv.Atoi(t.ID); err == nil && n > max {
+			max = n
+		}
+	}
+	return strconv.Itoa(max + 1)
+}
+
+// ParseColour parses a colour of the form #rrggbb; "" is black.
+func ParseColour(s string) (color.NRGBA, error) {
+	if s == "" {
+		return color.NRGBA{A: 0xff}, nil
+	}
+
+	if len(s) != 7 || s[0] != '#' {
+		return color.NRGBA{}, fmt.Errorf("invalid colour %q; expected #rrggbb", s)
+	}
+
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid colour %q; expected #rrggbb", s)
+	}
+
+	return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package gopher
+
+import "math"
+
+// Limits on the values of a Transform; see Transform.Clamp.
+const (
+	MinScale  = 0.5
+	MaxScale  = 2.0
+	MaxOffset = 400
+)
+
+// Transform describes how a layer is placed on the canvas. Layers are first
+// scaled about the centre of the canvas, then mirrored about their own
+// vertical centre line if FlipH is set, and finally moved by (X, Y) pixels.
+// The zero value is the identity transform.
+type Transform struct {
+	FlipH bool    `json:",omitempty"`
+	X     int     `json:",omitempty"`
+	Y     int     `json:",omitempty"`
+	Scale float64 `json:",omitempty"`
+}
+
+// IsIdentity reports whether t leaves a layer unchanged.
+func (t Transform) IsIdentity() bool {
+	t = t.Clamp()
+	return !t.FlipH && t.X == 0 && t.Y == 0 && t.Scale == 1
+}
+
+// Clamp returns t with its values brought within the limits MinScale,
+// MaxScale and MaxOffset. A zero Scale is treated as 1, and Scale is rounded
+// to two decimal places.
+func (t Transform) Clamp() Transform {
+	t.Scale = math.Round(t.Scale*100) / 100
+
+	switch {
+	case t.Scale == 0:
+		t.Scale = 1
+	case t.Scale < MinScale:
+		t.Scale = MinScale
+	case t.Scale > MaxScale:
+		t.Scale = MaxScale
+	}
+
+	t.X = clampInt(t.X, -MaxOffset, MaxOffset)
+	t.Y = clampInt(t.Y, -MaxOffset, MaxOffset)
+
+	return t
+}
+
+// Rect returns the position (x, y) of the top left corner and the size (w, h)
+// of a transformed layer on the canvas. Renderers place the layer image in
+// this rectangle, mirrored horizontally within it if FlipH is set.
+func (t Transform) Rect() (x, y, w, h float64) {
+	t = t.Clamp()
+
+	w = Width * t.Scale
+	h = Height * t.Scale
+	x = (Width-w)/2 + float64(t.X)
+	y = (Height-h)/2 + float64(t.Y)
+
+	return x, y, w, h
+}
+
+// Source maps the canvas point (cx, cy) to the point of the untransformed
+// layer that is drawn there. The result may lie outside the layer.
+func (t Transform) Source(cx, cy float64) (lx, ly float64) {
+	x, y, w, _ := t.Rect()
+	s := w / Width
+
+	lx = (cx - x) / s
+	ly = (cy - y) / s
+
+	if t.FlipH {
+		lx = Width - lx
+	}
+
+	return lx, ly
+}
+
+func clampInt(v, min, max int) int {
+	switch {
+	case v < min:
+		return min
+	case v > max:
+		return max
+	}
+	return v
+}
@@ -0,0 +1,102 @@
+package gopher
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTransformClamp(t *testing.T) {
+	tests := []struct {
+		in, want Transform
+	}{
+		{Transform{}, Transform{Scale: 1}},
+		{Transform{FlipH: true, X: 10, Y: -10, Scale: 1.5}, Transform{FlipH: true, X: 10, Y: -10, Scale: 1.5}},
+		{Transform{Scale: 0.1}, Transform{Scale: MinScale}},
+		{Transform{Scale: 3}, Transform{Scale: MaxScale}},
+		{Transform{Scale: 1.234}, Transform{Scale: 1.23}},
+		{Transform{Scale: 0.004}, Transform{Scale: 1}},
+		{Transform{X: 1000, Y: -1000}, Transform{X: MaxOffset, Y: -MaxOffset, Scale: 1}},
+	}
+
+	for _, test := range tests {
+		if got := test.in.Clamp(); got != test.want {
+			t.Errorf("%+v.Clamp() = %+v; want %+v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestTransformIsIdentity(t *testing.T) {
+	tests := []struct {
+		t    Transform
+		want bool
+	}{
+		{Transform{}, true},
+		{Transform{Scale: 1}, true},
+		{Transform{Scale: 1.001}, true},
+		{Transform{Scale: 1.01}, false},
+		{Transform{FlipH: true}, false},
+		{Transform{X: 1}, false},
+		{Transform{Y: -1}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.t.IsIdentity(); got != test.want {
+			t.Errorf("%+v.IsIdentity() = %v; want %v", test.t, got, test.want)
+		}
+	}
+}
+
+func TestTransformRect(t *testing.T) {
+	tests := []struct {
+		t          Transform
+		x, y, w, h float64
+	}{
+		{Transform{}, 0, 0, Width, Height},
+		{Transform{X: 10, Y: -20}, 10, -20, Width, Height},
+		{Transform{Scale: 0.5}, Width / 4, Height / 4, Width / 2, Height / 2},
+		{Transform{Scale: 2, X: 1}, -Width/2 + 1, -Height / 2, Width * 2, Height * 2},
+		{Transform{FlipH: true}, 0, 0, Width, Height},
+	}
+
+	for _, test := range tests {
+		x, y, w, h := test.t.Rect()
+		if x != test.x || y != test.y || w != test.w || h != test.h {
+			t.Errorf("%+v.Rect() = %v, %v, %v, %v; want %v, %v, %v, %v", test.t, x, y, w, h, test.x, test.y, test.w, test.h)
+		}
+	}
+}
+
+func TestTransformSource(t *testing.T) {
+	tests := []struct {
+		t              Transform
+		cx, cy, lx, ly float64
+	}{
+		{Transform{}, 100, 200, 100, 200},
+		{Transform{X: 10, Y: 20}, 100, 200, 90, 180},
+		{Transform{FlipH: true}, 100, 200, Width - 100, 200},
+		{Transform{Scale: 0.5}, Width / 2, Height / 2, Width / 2, Height / 2},
+		{Transform{Scale: 0.5}, Width / 4, Height / 4, 0, 0},
+		{Transform{Scale: 2}, 0, 0, Width / 4, Height / 4},
+	}
+
+	for _, test := range tests {
+		lx, ly := test.t.Source(test.cx, test.cy)
+		if math.Abs(lx-test.lx) > 1e-9 || math.Abs(ly-test.ly) > 1e-9 {
+			t.Errorf("%+v.Source(%v, %v) = %v, %v; want %v, %v", test.t, test.cx, test.cy, lx, ly, test.lx, test.ly)
+		}
+	}
+}
+
+func TestSetTransform(t *testing.T) {
+	g := &Gopher{}
+
+	g.SetTransform("010-Body/blue_gopher", Transform{X: 1000, Scale: 1.234})
+	if got, want := g.Transform("010-Body/blue_gopher"), (Transform{X: MaxOffset, Scale: 1.23}); got != want {
+		t.Errorf("Transform after SetTransform = %+v; want the clamped %+v", got, want)
+	}
+
+	g.SetTransform("010-Body/blue_gopher", Transform{Scale: 1})
+	if len(g.Transforms) != 0 {
+		t.Errorf("identity transform left entries %v", g.Transforms)
+	}
+}
@@ -0,0 +1,124 @@
+package gopher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image/png"
+	"strings"
+)
+
+// UploadPrefix is the prefix of the part name of an Upload.
+const UploadPrefix = "upload/"
+
+// The limits on uploads. Uploaded images are scaled to fit within
+// UploadBoxWidth x UploadBoxHeight pixels, centred on the canvas, before
+// being stored as a full canvas layer of at most MaxUploadSize bytes. A
+// gopher has at most MaxUploads, so that a recipe carrying the data of all
+// of them stays well within the size of recipe that is read back from an
+// exported image.
+const (
+	UploadBoxWidth  = 600
+	UploadBoxHeight = 600
+	MaxUploadSize   = 1 << 20
+	MaxUploads      = 5
+)
+
+// Upload is an image supplied by the user that is drawn as an additional
+// layer of a gopher. Uploads belong to the gopher that uses them; they are
+// never added to the catalogue.
+type Upload struct {
+	// ID identifies the upload; see UploadID.
+	ID string
+
+	// Name is the name of the file that was uploaded.
+	Name string `json:",omitempty"`
+
+	// Data is the PNG encoded layer, Width x Height pixels like the
+	// catalogue artwork. It is omitted where uploads are stored separately
+	// from a recipe, such as in the URL used by the client.
+	Data []byte `json:",omitempty"`
+}
+
+// Part returns the name by which u is referred to as a layer of a gopher.
+func (u Upload) Part() string {
+	return UploadPrefix + u.ID
+}
+
+// IsUpload reports whether the part p refers to an Upload.
+func IsUpload(p string) bool {
+	return strings.HasPrefix(p, UploadPrefix)
+}
+
+// UploadID returns the ID of an upload with the given layer data.
+func UploadID(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:8])
+}
+
+// FitUpload returns the rectangle, in canvas coordinates, into which an
+// uploaded image of w x h pixels is drawn to create its layer.
+func FitUpload(w, h int) (x, y, dw, dh float64) {
+	s := float64(UploadBoxWidth) / float64(w)
+	if sh := float64(UploadBoxHeight) / float64(h); sh < s {
+		s = sh
+	}
+
+	dw = float64(w) * s
+	dh = float64(h) * s
+
+	return (Width - dw) / 2, (Height - dh) / 2, dw, dh
+}
+
+// Upload returns the upload that is drawn as the part p.
+func (g *Gopher) Upload(p string) (Upload, bool) {
+	for _, u := range g.Uploads {
+		if u.Part() == p {
+			return u, true
+		}
+	}
+	return Upload{}, false
+}
+
+// AddUpload adds u as the top layer of g, unless g already has u.
+func (g *Gopher) AddUpload(u Upload) {
+	if _, ok := g.Upload(u.Part()); ok {
+		return
+	}
+
+	g.Uploads = append(g.Uploads[:len(g.Uploads):len(g.Uploads)], u)
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+// Validate checks that u is a well formed upload. The data of an upload need
+// not be present.
+func (u Upload) Validate() error {
+	if u.ID == "" {
+		return fmt.Errorf("upload has no ID")
+	}
+
+	if u.Data == nil {
+		return nil
+	}
+
+	if len(u.Data) > MaxUploadSize {
+		return fmt.Errorf("upload %v is %v bytes; at most %v allowed", u.ID, len(u.Data), MaxUploadSize)
+	}
+	if !bytes.HasPrefix(u.Data, pngHeader) {
+		return fmt.Errorf("upload %v is not a PNG image", u.ID)
+	}
+	c, err := png.DecodeConfig(bytes.NewReader(u.Data))
+	if err != nil {
+		return fmt.Errorf("upload %v is not a valid PNG image: %v", u.ID, err)
+	}
+	if c.Width != Width || c.Height != Height {
+		return fmt.Errorf("upload %v is %vx%v pixels; must be %vx%v", u.ID, c.Width, c.Height, Width, Height)
+	}
+	if id := UploadID(u.Data); id != u.ID {
+		return fmt.Errorf("upload %v has data for upload %v", u.ID, id)
+	}
+
+	return nil
+}
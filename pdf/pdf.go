@@ -0,0 +1,289 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// Package pdf is a minimal writer of PDF documents that contain images and
+// simple vector graphics, such as cut lines. It depends only on the standard
+// library.
+//
+// All lengths are in PDF points (1/72 inch) with the origin at the bottom
+// left of the page; see MM to convert from millimetres.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// MM returns the length in points of mm millimetres.
+func MM(mm float64) float64 {
+	return mm * 72 / 25.4
+}
+
+// Rect is a rectangle on a page.
+type Rect struct {
+	X, Y, W, H float64
+}
+
+// Inset returns r shrunk by d on each side; a negative d grows r.
+func (r Rect) Inset(d float64) Rect {
+	return Rect{X: r.X + d, Y: r.Y + d, W: r.W - 2*d, H: r.H - 2*d}
+}
+
+func (r Rect) String() string {
+	return fmt.Sprintf("[%v %v %v %v]", num(r.X), num(r.Y), num(r.X+r.W), num(r.Y+r.H))
+}
+
+// Writer writes a PDF document. Images are written as they are added, so
+// that large documents need not be held in memory; pages are written as they
+// are added and the document is completed by Close.
+type Writer struct {
+	w       *countWriter
+	offsets []int64
+	pages   []int
+	err     error
+}
+
+// Image is an image that has been written to a document, for use on any
+// number of its pages.
+type Image struct {
+	obj int
+
+	Width, Height int
+}
+
+// The object numbers of the catalog and page tree, which are written by
+// Close.
+const (
+	catalogObj = 1
+	pagesObj   = 2
+)
+
+// NewWriter returns a Writer that writes a document to w.
+func NewWriter(w io.Writer) *Writer {
+	res := &Writer{
+		w:       &countWriter{w: w},
+		offsets: make([]int64, pagesObj),
+	}
+
+	// the binary comment marks the file as containing binary data
+	res.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	return res
+}
+
+// Image writes m to the document. Any transparency of m is preserved using a
+// soft mask.
+func (w *Writer) Image(m image.Image) (Image, error) {
+	b := m.Bounds()
+
+	rgb := make([]byte, 0, b.Dx()*b.Dy()*3)
+	alpha := make([]byte, 0, b.Dx()*b.Dy())
+	opaque := true
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			if c.A != 0xff {
+				opaque = false
+			}
+		}
+	}
+
+	var smask string
+	if !opaque {
+		obj := w.stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %v /Height %v /ColorSpace /DeviceGray /BitsPerComponent 8", b.Dx(), b.Dy()), alpha)
+		smask = fmt.Sprintf(" /SMask %v 0 R", obj)
+	}
+
+	obj := w.stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %v /Height %v /ColorSpace /DeviceRGB /BitsPerComponent 8%v", b.Dx(), b.Dy(), smask), rgb)
+
+	return Image{obj: obj, Width: b.Dx(), Height: b.Dy()}, w.err
+}
+
+// Page is a page of a document, drawn using its methods before being added
+// to the document with Writer.AddPage.
+type Page struct {
+	// MediaBox is the size of the page; its origin must be (0, 0).
+	MediaBox Rect
+
+	// TrimBox and BleedBox, if set, describe the intended size of the page
+	// after trimming, and the area beyond that which printing may cover.
+	TrimBox  *Rect
+	BleedBox *Rect
+
+	content bytes.Buffer
+	images  map[int]bool
+}
+
+// NewPage returns an empty page of w x h points.
+func NewPage(w, h float64) *Page {
+	return &Page{
+		MediaBox: Rect{W: w, H: h},
+		images:   make(map[int]bool),
+	}
+}
+
+// DrawImage draws m, stretched to fill r.
+func (p *Page) DrawImage(m Image, r Rect) {
+	p.images[m.obj] = true
+	fmt.Fprintf(&p.content, "q %v 0 0 %v %v %v cm /Im%v Do Q\n", num(r.W), num(r.H), num(r.X), num(r.Y), m.obj)
+}
+
+// FillRect fills r with the colour c.
+func (p *Page) FillRect(r Rect, c color.Color) {
+	fmt.Fprintf(&p.content, "q %v rg %v %v %v %v re f Q\n", rgb(c), num(r.X), num(r.Y), num(r.W), num(r.H))
+}
+
+// StrokeRect draws the outline of r with a line of the given width and
+// colour, dashed according to dash if it is not empty.
+func (p *Page) StrokeRect(r Rect, c color.Color, width float64, dash ...float64) {
+	fmt.Fprintf(&p.content, "q %v RG %v w %v %v %v %v %v re S Q\n", rgb(c), num(width), dashOp(dash), num(r.X), num(r.Y), num(r.W), num(r.H))
+}
+
+// Line draws a straight line from (x1, y1) to (x2, y2) with the given width
+// and colour.
+func (p *Page) Line(x1, y1, x2, y2 float64, c color.Color, width float64) {
+	fmt.Fprintf(&p.content, "q %v RG %v w %v %v m %v %v l S Q\n", rgb(c), num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// AddPage writes p to the document.
+func (w *Writer) AddPage(p *Page) error {
+	content := w.stream("", p.content.Bytes())
+
+	var res bytes.Buffer
+	res.WriteString("<< /XObject <<")
+	for obj := range p.images {
+		fmt.Fprintf(&res, " /Im%v %v 0 R", obj, obj)
+	}
+	res.WriteString(" >> >>")
+
+	boxes := fmt.Sprintf("/MediaBox %v", p.MediaBox)
+	if p.TrimBox != nil {
+		boxes += fmt.Sprintf(" /TrimBox %v", *p.TrimBox)
+	}
+	if p.BleedBox != nil {
+		boxes += fmt.Sprintf(" /BleedBox %v", *p.BleedBox)
+	}
+
+	obj := w.object(fmt.Sprintf("<< /Type /Page /Parent %v 0 R %v /Resources %v /Contents %v 0 R >>", pagesObj, boxes, res.String(), content))
+	w.pages = append(w.pages, obj)
+
+	return w.err
+}
+
+// Close completes the document. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	kids := new(bytes.Buffer)
+	for _, p := range w.pages {
+		fmt.Fprintf(kids, " %v 0 R", p)
+	}
+
+	w.writeObject(pagesObj, fmt.Sprintf("<< /Type /Pages /Kids [%v ] /Count %v >>", kids.String(), len(w.pages)))
+	w.writeObject(catalogObj, fmt.Sprintf("<< /Type /Catalog /Pages %v 0 R >>", pagesObj))
+
+	xref := w.w.n
+	w.printf("xref\n0 %v\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, o := range w.offsets {
+		w.printf("%010d 00000 n \n", o)
+	}
+	w.printf("trailer\n<< /Size %v /Root %v 0 R >>\nstartxref\n%v\n%%%%EOF\n", len(w.offsets)+1, catalogObj, xref)
+
+	return w.err
+}
+
+// object writes a new object with the body s, returning its number.
+func (w *Writer) object(s string) int {
+	w.offsets = append(w.offsets, 0)
+	obj := len(w.offsets)
+	w.writeObject(obj, s)
+	return obj
+}
+
+func (w *Writer) writeObject(obj int, s string) {
+	w.offsets[obj-1] = w.w.n
+	w.printf("%v 0 obj\n%v\nendobj\n", obj, s)
+}
+
+// stream writes a new stream object whose dictionary holds the entries dict
+// and whose data, compressed, is b.
+func (w *Writer) stream(dict string, b []byte) int {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(b)
+	zw.Close()
+
+	if dict != "" {
+		dict += " "
+	}
+
+	w.offsets = append(w.offsets, 0)
+	obj := len(w.offsets)
+
+	w.offsets[obj-1] = w.w.n
+	w.printf("%v 0 obj\n<< %v/Filter /FlateDecode /Length %v >>\nstream\n", obj, dict, z.Len())
+	w.write(z.Bytes())
+	w.printf("\nendstream\nendobj\n")
+
+	return obj
+}
+
+func (w *Writer) printf(format string, args ...interface{}) {
+	w.write([]byte(fmt.Sprintf(format, args...)))
+}
+
+func (w *Writer) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.Write(b)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+func rgb(c color.Color) string {
+	r, g, b, _ := color.NRGBAModel.Convert(c).(color.NRGBA).RGBA()
+	return fmt.Sprintf("%v %v %v", num(float64(r)/0xffff), num(float64(g)/0xffff), num(float64(b)/0xffff))
+}
+
+func dashOp(dash []float64) string {
+	var b bytes.Buffer
+	b.WriteString("[")
+	for i, d := range dash {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(num(d))
+	}
+	b.WriteString("] 0 d")
+	return b.String()
+}
+
+// num formats v for use in a content stream, which does not permit exponents.
+func num(v float64) string {
+	s := fmt.Sprintf("%.3f", v)
+	for s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+	if s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
@@ -0,0 +1,309 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNum(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0"},
+		{1, "1"},
+		{-2, "-2"},
+		{0.5, "0.5"},
+		{1.25, "1.25"},
+		{1.2345, "1.234"},
+		{1e-9, "0"},
+		{-1e-9, "0"},
+		{1e9, "1000000000"},
+	}
+
+	for _, test := range tests {
+		if got := num(test.in); got != test.want {
+			t.Errorf("num(%v) = %q; want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestRect(t *testing.T) {
+	r := Rect{X: 10, Y: 20, W: 30, H: 40}
+
+	if got, want := r.String(), "[10 20 40 60]"; got != want {
+		t.Errorf("String = %q; want %q", got, want)
+	}
+	if got, want := r.Inset(5), (Rect{X: 15, Y: 25, W: 20, H: 30}); got != want {
+		t.Errorf("Inset(5) = %v; want %v", got, want)
+	}
+	if got, want := r.Inset(-5), (Rect{X: 5, Y: 15, W: 40, H: 50}); got != want {
+		t.Errorf("Inset(-5) = %v; want %v", got, want)
+	}
+	if got, want := num(MM(25.4)), "72"; got != want {
+		t.Errorf("MM(25.4) = %v; want %v", got, want)
+	}
+}
+
+func TestPageContent(t *testing.T) {
+	tests := []struct {
+		draw func(p *Page)
+		want string
+	}{
+		{
+			func(p *Page) { p.FillRect(Rect{X: 1, Y: 2, W: 3, H: 4}, color.White) },
+			"q 1 1 1 rg 1 2 3 4 re f Q\n",
+		},
+		{
+			func(p *Page) { p.StrokeRect(Rect{W: 3, H: 4}, color.Black, 0.5) },
+			"q 0 0 0 RG 0.5 w [] 0 d 0 0 3 4 re S Q\n",
+		},
+		{
+			func(p *Page) { p.StrokeRect(Rect{W: 3, H: 4}, color.Black, 0.5, 2, 1) },
+			"q 0 0 0 RG 0.5 w [2 1] 0 d 0 0 3 4 re S Q\n",
+		},
+		{
+			func(p *Page) { p.Line(0, 0, 10, 5, color.NRGBA{R: 0xff, A: 0xff}, 1) },
+			"q 1 0 0 RG 1 w 0 0 m 10 5 l S Q\n",
+		},
+		{
+			func(p *Page) { p.DrawImage(Image{obj: 7}, Rect{X: 1, Y: 2, W: 3, H: 4}) },
+			"q 3 0 0 4 1 2 cm /Im7 Do Q\n",
+		},
+	}
+
+	for i, test := range tests {
+		p := NewPage(100, 100)
+		test.draw(p)
+		if got := p.content.String(); got != test.want {
+			t.Errorf("%v: content %q; want %q", i, got, test.want)
+		}
+	}
+}
+
+// document is a PDF document written by a Writer, parsed just enough to check
+// its cross-reference table.
+type document struct {
+	objects map[int]string // the body of each object, by number
+}
+
+var (
+	objRE   = regexp.MustCompile(`(?s)(\d+) 0 obj\n(.*?)\nendobj\n`)
+	xrefRE  = regexp.MustCompile(`(?s)xref\n0 (\d+)\n0000000000 65535 f \n((?:\d{10} 00000 n \n)*)trailer\n<< /Size (\d+) /Root 1 0 R >>\nstartxref\n(\d+)\n%%EOF\n$`)
+	entryRE = regexp.MustCompile(`(\d{10}) 00000 n \n`)
+)
+
+// parse checks that the cross-reference table of the document b gives the
+// offset of each of its objects, in order, and returns them.
+func parse(t *testing.T, b []byte) document {
+	if !bytes.HasPrefix(b, []byte("%PDF-1.4\n")) {
+		t.Fatalf("document starts %q; want a PDF header", b[:10])
+	}
+
+	m := xrefRE.FindSubmatch(b)
+	if m == nil {
+		t.Fatalf("no cross-reference table and trailer at the end of %q", b)
+	}
+	n, _ := strconv.Atoi(string(m[1]))
+	size, _ := strconv.Atoi(string(m[3]))
+	startxref, _ := strconv.Atoi(string(m[4]))
+
+	if n != size {
+		t.Errorf("xref has %v entries, but the trailer gives /Size %v", n, size)
+	}
+	if !bytes.HasPrefix(b[startxref:], []byte("xref\n")) {
+		t.Errorf("startxref %v does not point at the xref table", startxref)
+	}
+
+	entries := entryRE.FindAllSubmatch(m[2], -1)
+	if len(entries) != n-1 {
+		t.Fatalf("xref has %v objects; want %v", len(entries), n-1)
+	}
+
+	d := document{objects: make(map[int]string)}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		obj := objRE.FindSubmatch(b[off:])
+		if obj == nil || !bytes.HasPrefix(b[off:], obj[0]) {
+			t.Fatalf("xref offset %v of object %v does not point at an object", off, i+1)
+		}
+		if got := string(obj[1]); got != strconv.Itoa(i+1) {
+			t.Fatalf("xref offset %v of object %v points at object %v", off, i+1, got)
+		}
+		d.objects[i+1] = string(obj[2])
+	}
+
+	if got := len(objRE.FindAll(b, -1)); got != len(entries) {
+		t.Errorf("document holds %v objects; xref lists %v", got, len(entries))
+	}
+
+	return d
+}
+
+// stream returns the decompressed data of the stream object obj.
+func (d document) stream(t *testing.T, obj int) []byte {
+	s := d.objects[obj]
+	i := strings.Index(s, ">>\nstream\n")
+	if i == -1 {
+		t.Fatalf("object %v is not a stream: %q", obj, s)
+	}
+	data := strings.TrimSuffix(s[i+len(">>\nstream\n"):], "\nendstream")
+
+	var length int
+	if _, err := fmt.Sscanf(s[strings.Index(s, "/Length "):], "/Length %d", &length); err != nil || length != len(data) {
+		t.Errorf("object %v gives /Length %v; its data is %v bytes", obj, length, len(data))
+	}
+
+	zr, err := zlib.NewReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("object %v: %v", obj, err)
+	}
+	b, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("object %v: %v", obj, err)
+	}
+	return b
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d := parse(t, buf.Bytes())
+
+	want := map[int]string{
+		catalogObj: "<< /Type /Catalog /Pages 2 0 R >>",
+		pagesObj:   "<< /Type /Pages /Kids [ ] /Count 0 >>",
+	}
+	for obj, s := range want {
+		if d.objects[obj] != s {
+			t.Errorf("object %v is %q; want %q", obj, d.objects[obj], s)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	opaque := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	copy(opaque.Pix, []byte{1, 2, 3, 0xff, 4, 5, 6, 0xff})
+
+	translucent := image.NewNRGBA(image.Rect(5, 5, 6, 7))
+	copy(translucent.Pix, []byte{10, 20, 30, 0xff, 40, 50, 60, 0x80})
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	im1, err := w.Image(opaque)
+	if err != nil {
+		t.Fatal(err)
+	}
+	im2, err := w.Image(translucent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if im1.Width != 2 || im1.Height != 1 || im2.Width != 1 || im2.Height != 2 {
+		t.Errorf("images are %vx%v and %vx%v; want 2x1 and 1x2", im1.Width, im1.Height, im2.Width, im2.Height)
+	}
+
+	p1 := NewPage(100, 200)
+	p1.DrawImage(im1, Rect{W: 10, H: 5})
+	trim := p1.MediaBox.Inset(10)
+	p1.TrimBox = &trim
+	if err := w.AddPage(p1); err != nil {
+		t.Fatal(err)
+	}
+
+	p2 := NewPage(300, 400)
+	p2.DrawImage(im1, Rect{W: 10, H: 5})
+	p2.DrawImage(im2, Rect{W: 10, H: 20})
+	if err := w.AddPage(p2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d := parse(t, buf.Bytes())
+
+	// objects 1 and 2 are the catalog and page tree, and the rest follow in
+	// the order written: the opaque image, the soft mask and image of the
+	// translucent image, then the content and page of each page
+	if got := d.stream(t, im1.obj); !bytes.Equal(got, []byte{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("opaque image data %v; want its RGB", got)
+	}
+	if strings.Contains(d.objects[im1.obj], "/SMask") {
+		t.Errorf("opaque image has a soft mask: %q", d.objects[im1.obj])
+	}
+
+	if !strings.Contains(d.objects[im2.obj], fmt.Sprintf("/SMask %v 0 R", im2.obj-1)) {
+		t.Errorf("translucent image %q has no soft mask %v", d.objects[im2.obj], im2.obj-1)
+	}
+	if got := d.stream(t, im2.obj-1); !bytes.Equal(got, []byte{0xff, 0x80}) {
+		t.Errorf("soft mask data %v; want the alpha of the image", got)
+	}
+	if got := d.stream(t, im2.obj); !bytes.Equal(got, []byte{10, 20, 30, 40, 50, 60}) {
+		t.Errorf("translucent image data %v; want its RGB", got)
+	}
+
+	page1, page2 := im2.obj+2, im2.obj+4
+	want := map[int]string{
+		catalogObj: "<< /Type /Catalog /Pages 2 0 R >>",
+		pagesObj:   fmt.Sprintf("<< /Type /Pages /Kids [ %v 0 R %v 0 R ] /Count 2 >>", page1, page2),
+		page1: fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 100 200] /TrimBox [10 10 90 190] /Resources << /XObject << /Im%v %v 0 R >> >> /Contents %v 0 R >>",
+			im1.obj, im1.obj, page1-1),
+	}
+	for obj, s := range want {
+		if d.objects[obj] != s {
+			t.Errorf("object %v is %q; want %q", obj, d.objects[obj], s)
+		}
+	}
+
+	// the images of a page are listed in no particular order
+	for _, im := range []Image{im1, im2} {
+		if ref := fmt.Sprintf("/Im%v %v 0 R", im.obj, im.obj); !strings.Contains(d.objects[page2], ref) {
+			t.Errorf("second page %q does not list %v", d.objects[page2], ref)
+		}
+	}
+
+	if got, want := string(d.stream(t, page1-1)), fmt.Sprintf("q 10 0 0 5 0 0 cm /Im%v Do Q\n", im1.obj); got != want {
+		t.Errorf("content of the first page %q; want %q", got, want)
+	}
+}
+
+type errWriter struct {
+	n int
+}
+
+func (e *errWriter) Write(b []byte) (int, error) {
+	if e.n < len(b) {
+		n := e.n
+		e.n = 0
+		return n, fmt.Errorf("write failed")
+	}
+	e.n -= len(b)
+	return len(b), nil
+}
+
+func TestWriterError(t *testing.T) {
+	w := NewWriter(&errWriter{n: 20})
+
+	if _, err := w.Image(image.NewNRGBA(image.Rect(0, 0, 1, 1))); err == nil {
+		t.Errorf("Image gave no error after a failed write")
+	}
+	if err := w.AddPage(NewPage(1, 1)); err == nil {
+		t.Errorf("AddPage gave no error after a failed write")
+	}
+	if err := w.Close(); err == nil {
+		t.Errorf("Close gave no error after a failed write")
+	}
+}
@@ -0,0 +1,254 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+// ShadowColour is the colour of drop shadows.
+var ShadowColour = color.NRGBA{A: 0x60}
+
+// The offset and blur radius of the drop shadow effect, in pixels of the full
+// size canvas.
+const (
+	shadowOffset = 12
+	shadowBlur   = 16
+)
+
+// ApplyEffects returns m with the effects e applied, in the order described
+// by gopher.Effects. m is not modified.
+func ApplyEffects(m *image.NRGBA, e gopher.Effects) *image.NRGBA {
+	if e.Outline > 0 {
+		m = Outline(m, float64(e.Outline), e.OutlineRGBA())
+	}
+	if e.Shadow {
+		m = Shadow(m, shadowOffset, shadowOffset, shadowBlur, ShadowColour)
+	}
+
+	switch e.Filter {
+	case gopher.FilterGrayscale:
+		m = Duotone(m, color.NRGBA{A: 0xff}, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	case gopher.FilterDuotone:
+		dark, light := e.DuotoneRGBA()
+		m = Duotone(m, dark, light)
+	}
+
+	if e.PixelSize > 0 {
+		m = Pixelate(m, e.PixelSize)
+	}
+
+	return m
+}
+
+// Duotone returns m with the luminance of each pixel mapped onto the range of
+// colours from dark to light; black and white make m grayscale.
+func Duotone(m *image.NRGBA, dark, light color.NRGBA) *image.NRGBA {
+	res := image.NewNRGBA(m.Bounds())
+
+	lerp := func(a, b uint8, t float64) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*t + 0.5)
+	}
+
+	for i := 0; i < len(m.Pix); i += 4 {
+		p := m.Pix[i : i+4]
+		if p[3] == 0 {
+			continue
+		}
+		t := (0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])) / 0xff
+		q := res.Pix[i : i+4]
+		q[0], q[1], q[2], q[3] = lerp(dark.R, light.R, t), lerp(dark.G, light.G, t), lerp(dark.B, light.B, t), p[3]
+	}
+
+	return res
+}
+
+// Pixelate returns m redrawn as pixel art: m is sampled, nearest neighbour,
+// at the centre of each size x size block, and the block filled with that
+// sample.
+func Pixelate(m *image.NRGBA, size int) *image.NRGBA {
+	b := m.Bounds()
+	res := image.NewNRGBA(b)
+
+	for by := b.Min.Y; by < b.Max.Y; by += size {
+		for bx := b.Min.X; bx < b.Max.X; bx += size {
+			block := image.Rect(bx, by, bx+size, by+size).Intersect(b)
+			c := m.NRGBAAt((block.Min.X+block.Max.X)/2, (block.Min.Y+block.Max.Y)/2)
+			draw.Draw(res, block, image.NewUniform(c), image.Point{}, draw.Src)
+		}
+	}
+
+	return res
+}
+
+// Outline returns m surrounded by a stroke of the given width in pixels and
+// colour, following the edge of its opaque area. The stroke is anti-aliased
+// and drawn beneath m, within the bounds of m.
+func Outline(m *image.NRGBA, width float64, c color.NRGBA) *image.NRGBA {
+	b := m.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// the squared distance of each pixel from the nearest pixel of m that is
+	// at least half opaque
+	inside := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			inside[y*w+x] = m.Pix[y*m.Stride+x*4+3] >= 0x80
+		}
+	}
+	dist := distance(inside, w, h)
+
+	res := image.NewNRGBA(b)
+	for i, d := range dist {
+		cov := width + 0.5 - math.Sqrt(d)
+		if cov <= 0 {
+			continue
+		}
+		p := res.Pix[(i/w)*res.Stride+(i%w)*4:]
+		p[0], p[1], p[2] = c.R, c.G, c.B
+		p[3] = uint8(math.Min(cov, 1)*float64(c.A) + 0.5)
+	}
+
+	draw.Draw(res, b, m, b.Min, draw.Over)
+
+	return res
+}
+
+// Shadow returns m over a shadow of itself in the colour c, offset by
+// (dx, dy) pixels and blurred with the given radius. The shadow is clipped
+// to the bounds of m.
+func Shadow(m *image.NRGBA, dx, dy, blur int, c color.NRGBA) *image.NRGBA {
+	b := m.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	alpha := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		sy := y - dy
+		if sy < 0 || sy >= h {
+			continue
+		}
+		for x := 0; x < w; x++ {
+			sx := x - dx
+			if sx < 0 || sx >= w {
+				continue
+			}
+			alpha[y*w+x] = float64(m.Pix[sy*m.Stride+sx*4+3])
+		}
+	}
+
+	// three box blurs approximate a Gaussian blur
+	for i := 0; i < 3; i++ {
+		boxBlur(alpha, w, h, blur)
+	}
+
+	res := image.NewNRGBA(b)
+	for i, a := range alpha {
+		p := res.Pix[(i/w)*res.Stride+(i%w)*4:]
+		p[0], p[1], p[2] = c.R, c.G, c.B
+		p[3] = uint8(math.Min(a/0xff*float64(c.A), 0xff) + 0.5)
+	}
+
+	draw.Draw(res, b, m, b.Min, draw.Over)
+
+	return res
+}
+
+// boxBlur replaces each value of the w x h grid v by the mean of the values
+// within r of it, horizontally then vertically.
+func boxBlur(v []float64, w, h, r int) {
+	if r < 1 {
+		return
+	}
+
+	line := func(get func(i int) float64, set func(i int, f float64), n int) {
+		out := make([]float64, n)
+		sum := 0.0
+		for i := -r; i < n+r; i++ {
+			if i+r < n && i+r >= 0 {
+				sum += get(i + r)
+			}
+			if i-r-1 >= 0 && i-r-1 < n {
+				sum -= get(i - r - 1)
+			}
+			if i >= 0 && i < n {
+				out[i] = sum / float64(2*r+1)
+			}
+		}
+		for i, f := range out {
+			set(i, f)
+		}
+	}
+
+	for y := 0; y < h; y++ {
+		row := v[y*w : (y+1)*w]
+		line(func(i int) float64 { return row[i] }, func(i int, f float64) { row[i] = f }, w)
+	}
+	for x := 0; x < w; x++ {
+		line(func(i int) float64 { return v[i*w+x] }, func(i int, f float64) { v[i*w+x] = f }, h)
+	}
+}
+
+// distance returns the squared Euclidean distance of each cell of the w x h
+// grid from the nearest cell that is inside, using the two pass algorithm of
+// Felzenszwalb and Huttenlocher.
+func distance(inside []bool, w, h int) []float64 {
+	const inf = 1e20
+
+	d := make([]float64, w*h)
+	for i, in := range inside {
+		if !in {
+			d[i] = inf
+		}
+	}
+
+	n := maxInt(w, h)
+	f := make([]float64, n)
+	out := make([]float64, n)
+	v := make([]int, n)
+	z := make([]float64, n+1)
+
+	// transform1 computes the one dimensional distance transform of f[:n]
+	// into out
+	transform1 := func(n int) {
+		k := 0
+		v[0] = 0
+		z[0], z[1] = -inf, inf
+		for q := 1; q < n; q++ {
+			s := ((f[q] + float64(q*q)) - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+			for s <= z[k] {
+				k--
+				s = ((f[q] + float64(q*q)) - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+			}
+			k++
+			v[k] = q
+			z[k], z[k+1] = s, inf
+		}
+		k = 0
+		for q := 0; q < n; q++ {
+			for z[k+1] < float64(q) {
+				k++
+			}
+			out[q] = float64((q-v[k])*(q-v[k])) + f[v[k]]
+		}
+	}
+
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			f[y] = d[y*w+x]
+		}
+		transform1(h)
+		for y := 0; y < h; y++ {
+			d[y*w+x] = out[y]
+		}
+	}
+	for y := 0; y < h; y++ {
+		copy(f, d[y*w:(y+1)*w])
+		transform1(w)
+		copy(d[y*w:(y+1)*w], out[:w])
+	}
+
+	return d
+}
@@ -0,0 +1,134 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+func TestOutline(t *testing.T) {
+	m := square(20, image.Rect(8, 8, 12, 12), red)
+	res := Outline(m, 2, blue)
+
+	tests := []struct {
+		p    image.Point
+		want color.NRGBA
+	}{
+		{image.Pt(10, 10), red},
+		{image.Pt(7, 10), blue},
+		{image.Pt(10, 12), blue},
+		// the edge of the stroke is anti-aliased
+		{image.Pt(6, 10), color.NRGBA{B: 0xff, A: 0x80}},
+		{image.Pt(5, 10), color.NRGBA{}},
+		{image.Pt(0, 0), color.NRGBA{}},
+	}
+
+	for _, test := range tests {
+		if got := res.NRGBAAt(test.p.X, test.p.Y); got != test.want {
+			t.Errorf("pixel %v of outline is %v; want %v", test.p, got, test.want)
+		}
+	}
+
+	if m.NRGBAAt(6, 10) != (color.NRGBA{}) {
+		t.Errorf("Outline modified its argument")
+	}
+}
+
+func TestShadow(t *testing.T) {
+	m := square(40, image.Rect(10, 10, 20, 20), red)
+	c := color.NRGBA{A: 0x80}
+
+	sharp := Shadow(m, 5, 5, 0, c)
+	tests := []struct {
+		p    image.Point
+		want color.NRGBA
+	}{
+		{image.Pt(15, 15), red},
+		{image.Pt(22, 22), c},
+		{image.Pt(17, 22), c},
+		{image.Pt(26, 26), color.NRGBA{}},
+		{image.Pt(12, 8), color.NRGBA{}},
+	}
+	for _, test := range tests {
+		if got := sharp.NRGBAAt(test.p.X, test.p.Y); got != test.want {
+			t.Errorf("pixel %v of unblurred shadow is %v; want %v", test.p, got, test.want)
+		}
+	}
+
+	// blurring spreads the shadow beyond its offset copy, fading it
+	blurred := Shadow(m, 5, 5, 2, c)
+	if a := blurred.NRGBAAt(26, 26).A; a == 0 || a >= c.A {
+		t.Errorf("blurred shadow beyond the gopher has alpha %v", a)
+	}
+	if got := blurred.NRGBAAt(15, 15); got != red {
+		t.Errorf("gopher over blurred shadow is %v; want %v", got, red)
+	}
+}
+
+func TestDuotone(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	m.SetNRGBA(0, 0, color.NRGBA{A: 0xff})
+	m.SetNRGBA(1, 0, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x80})
+
+	dark, light := color.NRGBA{R: 0x10, A: 0xff}, color.NRGBA{B: 0xf0, A: 0xff}
+	res := Duotone(m, dark, light)
+
+	want := []color.NRGBA{
+		{R: 0x10, A: 0xff},
+		{B: 0xf0, A: 0x80},
+		{},
+	}
+	for x, w := range want {
+		if got := res.NRGBAAt(x, 0); got != w {
+			t.Errorf("pixel %v of duotone is %v; want %v", x, got, w)
+		}
+	}
+}
+
+func TestPixelate(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 5, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 5; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), A: 0xff})
+		}
+	}
+
+	res := Pixelate(m, 2)
+
+	// each block takes the colour at its centre; the last column is a
+	// block of its own
+	tests := []struct {
+		p    image.Point
+		want color.NRGBA
+	}{
+		{image.Pt(0, 0), color.NRGBA{R: 1, G: 1, A: 0xff}},
+		{image.Pt(1, 1), color.NRGBA{R: 1, G: 1, A: 0xff}},
+		{image.Pt(2, 3), color.NRGBA{R: 3, G: 3, A: 0xff}},
+		{image.Pt(4, 0), color.NRGBA{R: 4, G: 1, A: 0xff}},
+	}
+	for _, test := range tests {
+		if got := res.NRGBAAt(test.p.X, test.p.Y); got != test.want {
+			t.Errorf("pixel %v of pixelated image is %v; want %v", test.p, got, test.want)
+		}
+	}
+}
+
+func TestApplyEffects(t *testing.T) {
+	m := square(40, image.Rect(10, 10, 20, 20), red)
+
+	if res := ApplyEffects(m, gopher.Effects{}); res != m {
+		t.Errorf("no effects gave a new image")
+	}
+
+	res := ApplyEffects(m, gopher.Effects{Filter: gopher.FilterGrayscale})
+	if got := res.NRGBAAt(15, 15); got.R != got.G || got.G != got.B || got.A != 0xff {
+		t.Errorf("grayscale gave %v", got)
+	}
+
+	res = ApplyEffects(m, gopher.Effects{Shadow: true})
+	if got := res.NRGBAAt(25, 25); got.A == 0 {
+		t.Errorf("shadow effect drew no shadow")
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// Package render composes gophers into images, in pure Go. It draws the
+// layers of a gopher exactly as the browser client does, so images created
+// by the client, the command line and any server match.
+package render
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+// Artwork provides the images of the options of the catalogue.
+type Artwork interface {
+	// Layer returns the full size image of the option o, for example
+	// "020-Eyes/eyes".
+	Layer(o string) (image.Image, error)
+}
+
+// PNGArtwork is implemented by an Artwork that can provide the PNG encoded
+// image of an option without re-encoding it.
+type PNGArtwork interface {
+	Artwork
+
+	// LayerPNG returns the PNG encoded full size image of the option o.
+	LayerPNG(o string) ([]byte, error)
+}
+
+// Dir is an Artwork that reads the catalogue from files laid out like the
+// artwork directory of this repository. Decoded images are cached, the least
+// recently used being discarded once they total more than DirCacheSize bytes.
+type Dir struct {
+	open func(name string) (io.ReadCloser, error)
+	max  int
+
+	mu     sync.Mutex
+	size   int
+	order  *list.List // of *dirEntry, most recently used first
+	layers map[string]*list.Element
+}
+
+// DirCacheSize is the number of bytes of decoded images cached by a Dir.
+const DirCacheSize = 256 << 20
+
+// dirEntry is a layer cached by a Dir. An entry is added before its image is
+// loaded, outside the lock of the Dir, so that a layer is loaded once however
+// many ask for it, and different layers load in parallel.
+type dirEntry struct {
+	o    string
+	once sync.Once
+	l    image.Image
+	err  error
+	size int
+}
+
+// NewDir returns an Artwork for the directory path.
+func NewDir(path string) *Dir {
+	return NewDirFunc(func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(path, filepath.FromSlash(name)))
+	})
+}
+
+// NewDirFunc returns an Artwork that reads the catalogue using open, which
+// opens the file name, a slash separated path such as "020-Eyes/eyes.png",
+// of an artwork directory.
+func NewDirFunc(open func(name string) (io.ReadCloser, error)) *Dir {
+	return &Dir{
+		open:   open,
+		max:    DirCacheSize,
+		order:  list.New(),
+		layers: make(map[string]*list.Element),
+	}
+}
+
+func (d *Dir) Layer(o string) (image.Image, error) {
+	d.mu.Lock()
+	e, ok := d.layers[o]
+	if ok {
+		d.order.MoveToFront(e)
+	} else {
+		e = d.order.PushFront(&dirEntry{o: o})
+		d.layers[o] = e
+	}
+	de := e.Value.(*dirEntry)
+	d.mu.Unlock()
+
+	de.once.Do(func() {
+		de.l, de.err = d.decode(o)
+	})
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// the entry may have been evicted, or already counted, by the time it
+	// has loaded
+	if d.layers[o] != e || de.size != 0 {
+		return de.l, de.err
+	}
+
+	if de.err != nil {
+		d.remove(e)
+		return nil, de.err
+	}
+
+	b := de.l.Bounds()
+	de.size = 4 * b.Dx() * b.Dy()
+	d.size += de.size
+	for d.size > d.max && d.order.Back() != e {
+		d.remove(d.order.Back())
+	}
+
+	return de.l, nil
+}
+
+func (d *Dir) remove(e *list.Element) {
+	de := d.order.Remove(e).(*dirEntry)
+	delete(d.layers, de.o)
+	d.size -= de.size
+}
+
+func (d *Dir) decode(o string) (image.Image, error) {
+	f, err := d.open(o + ".png")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %v.png: %v", o, err)
+	}
+
+	return l, nil
+}
+
+func (d *Dir) LayerPNG(o string) ([]byte, error) {
+	f, err := d.open(o + ".png")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
+// Compose draws the visible layers of g, in order, onto a canvas of
+// gopher.Width x gopher.Height pixels, applies the effects of g and then
+// fills the background of g beneath them.
+func Compose(g *gopher.Gopher, a Artwork) (*image.NRGBA, error) {
+	dst := image.NewNRGBA(image.Rect(0, 0, gopher.Width, gopher.Height))
+
+	for _, p := range g.Layers() {
+		l, err := Layer(g, p, a)
+		if err != nil {
+			return nil, err
+		}
+
+		DrawLayer(dst, l, g.Transform(p))
+	}
+
+	if !g.Effects.IsZero() {
+		dst = ApplyEffects(dst, *g.Effects)
+	}
+
+	if g.Background != "" {
+		bg, err := gopher.ParseColour(g.Background)
+		if err != nil {
+			return nil, err
+		}
+
+		res := image.NewNRGBA(dst.Bounds())
+		draw.Draw(res, res.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+		draw.Draw(res, res.Bounds(), dst, image.Point{}, draw.Over)
+		dst = res
+	}
+
+	return dst, nil
+}
+
+// Layer returns the untransformed image of the part p of g, which may be an
+// option from the catalogue, an upload or a text.
+func Layer(g *gopher.Gopher, p string, a Artwork) (image.Image, error) {
+	if u, ok := g.Upload(p); ok {
+		if u.Data == nil {
+			return nil, fmt.Errorf("no data for upload %v", u.ID)
+		}
+
+		return UploadLayer(u)
+	}
+
+	if t, ok := g.Text(p); ok {
+		return Text(t), nil
+	}
+
+	return a.Layer(p)
+}
+
+// LayerPNG returns the PNG encoded, untransformed image of the part p of g.
+func LayerPNG(g *gopher.Gopher, p string, a Artwork) ([]byte, error) {
+	if u, ok := g.Upload(p); ok && u.Data != nil {
+		return u.Data, nil
+	}
+
+	if pa, ok := a.(PNGArtwork); ok && !gopher.IsUpload(p) && !gopher.IsText(p) {
+		return pa.LayerPNG(p)
+	}
+
+	l, err := Layer(g, p, a)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, l); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DrawLayer draws the full size layer l over dst, placed as described by t.
+func DrawLayer(dst draw.Image, l image.Image, t gopher.Transform) {
+	if t.IsIdentity() {
+		draw.Draw(dst, dst.Bounds(), l, image.Point{}, draw.Over)
+		return
+	}
+
+	x, y, w, h := t.Rect()
+	DrawScaled(dst, l, x, y, w, h, t.FlipH)
+}
+
+// DrawScaled draws m over dst, scaled to fill the rectangle of size (w, h)
+// whose top left corner is at (x, y), and mirrored horizontally within it if
+// flip is set. Pixels are sampled bilinearly, so m should be no more than
+// about twice the size at which it is drawn.
+func DrawScaled(dst draw.Image, m image.Image, x, y, w, h float64, flip bool) {
+	r := image.Rect(int(math.Floor(x)), int(math.Floor(y)), int(math.Ceil(x+w)), int(math.Ceil(y+h))).Intersect(dst.Bounds())
+	if r.Empty() {
+		return
+	}
+
+	// the scale at which m is drawn
+	b := m.Bounds()
+	sx, sy := w/float64(b.Dx()), h/float64(b.Dy())
+
+	tl := image.NewNRGBA(r)
+
+	for cy := r.Min.Y; cy < r.Max.Y; cy++ {
+		for cx := r.Min.X; cx < r.Max.X; cx++ {
+			lx := (float64(cx) + 0.5 - x) / sx
+			ly := (float64(cy) + 0.5 - y) / sy
+			if flip {
+				lx = float64(b.Dx()) - lx
+			}
+			tl.SetNRGBA(cx, cy, bilinear(m, float64(b.Min.X)+lx-0.5, float64(b.Min.Y)+ly-0.5))
+		}
+	}
+
+	draw.Draw(dst, r, tl, r.Min, draw.Over)
+}
+
+// UploadLayer decodes the layer of the upload u.
+func UploadLayer(u gopher.Upload) (image.Image, error) {
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+
+	return decodePNG(u.Data)
+}
@@ -0,0 +1,254 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+var (
+	red   = color.NRGBA{R: 0xff, A: 0xff}
+	green = color.NRGBA{G: 0xff, A: 0xff}
+	blue  = color.NRGBA{B: 0xff, A: 0xff}
+)
+
+// testArtwork is an Artwork of images held in memory.
+type testArtwork map[string]image.Image
+
+func (a testArtwork) Layer(o string) (image.Image, error) {
+	l, ok := a[o]
+	if !ok {
+		return nil, fmt.Errorf("no artwork for %v", o)
+	}
+	return l, nil
+}
+
+// square returns a transparent image of size x size pixels with the
+// rectangle r filled with c.
+func square(size int, r image.Rectangle, c color.Color) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(m, r, image.NewUniform(c), image.Point{}, draw.Src)
+	return m
+}
+
+func TestCompose(t *testing.T) {
+	a := testArtwork{
+		"body/a": square(20, image.Rect(0, 0, 10, 10), red),
+		"eyes/a": square(20, image.Rect(5, 5, 15, 15), blue),
+	}
+
+	tests := []struct {
+		name string
+		g    *gopher.Gopher
+		want map[image.Point]color.NRGBA
+	}{
+		{
+			"stacked",
+			&gopher.Gopher{Parts: [][]string{{"body/a"}, {"eyes/a"}}},
+			map[image.Point]color.NRGBA{{2, 2}: red, {7, 7}: blue, {12, 12}: blue, {30, 30}: {}},
+		},
+		{
+			"reordered",
+			&gopher.Gopher{Parts: [][]string{{"body/a"}, {"eyes/a"}}, Order: []string{"eyes/a", "body/a"}},
+			map[image.Point]color.NRGBA{{2, 2}: red, {7, 7}: red, {12, 12}: blue},
+		},
+		{
+			"hidden",
+			&gopher.Gopher{Parts: [][]string{{"body/a"}, {"eyes/a"}}, Hidden: map[string]bool{"body/a": true}},
+			map[image.Point]color.NRGBA{{2, 2}: {}, {7, 7}: blue},
+		},
+		{
+			"background",
+			&gopher.Gopher{Parts: [][]string{{"body/a"}, nil}, Background: "#00ff00"},
+			map[image.Point]color.NRGBA{{2, 2}: red, {12, 12}: green, {gopher.Width - 1, gopher.Height - 1}: green},
+		},
+		{
+			// the layer is scaled about the centre of the canvas, taking the
+			// red square out of the top left corner
+			"transformed",
+			&gopher.Gopher{Parts: [][]string{{"body/a"}, nil}, Transforms: map[string]gopher.Transform{"body/a": {Scale: 0.5}}},
+			map[image.Point]color.NRGBA{{2, 2}: {}, {gopher.Width/4 + gopher.Width/8, gopher.Height/4 + gopher.Height/8}: red},
+		},
+	}
+
+	for _, test := range tests {
+		m, err := Compose(test.g, a)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if b := m.Bounds(); b != image.Rect(0, 0, gopher.Width, gopher.Height) {
+			t.Errorf("%v: bounds %v", test.name, b)
+		}
+		for p, want := range test.want {
+			if got := m.NRGBAAt(p.X, p.Y); got != want {
+				t.Errorf("%v: pixel %v is %v; want %v", test.name, p, got, want)
+			}
+		}
+	}
+
+	if _, err := Compose(&gopher.Gopher{Parts: [][]string{{"body/b"}}}, a); err == nil {
+		t.Errorf("Compose with missing artwork gave no error")
+	}
+}
+
+func TestDrawScaled(t *testing.T) {
+	// left half red, right half blue
+	m := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(m, image.Rect(0, 0, 2, 4), image.NewUniform(red), image.Point{}, draw.Src)
+	draw.Draw(m, image.Rect(2, 0, 4, 4), image.NewUniform(blue), image.Point{}, draw.Src)
+
+	tests := []struct {
+		name       string
+		x, y, w, h float64
+		flip       bool
+		want       map[image.Point]color.NRGBA
+	}{
+		{
+			"same size", 0, 0, 4, 4, false,
+			map[image.Point]color.NRGBA{{0, 0}: red, {1, 3}: red, {3, 0}: blue, {4, 4}: {}},
+		},
+		{
+			"doubled", 2, 2, 8, 8, false,
+			map[image.Point]color.NRGBA{{1, 1}: {}, {3, 3}: red, {4, 8}: red, {8, 4}: blue, {10, 10}: {}},
+		},
+		{
+			"flipped", 2, 2, 8, 8, true,
+			map[image.Point]color.NRGBA{{3, 3}: blue, {8, 4}: red},
+		},
+		{
+			"clipped", -4, -4, 16, 16, false,
+			map[image.Point]color.NRGBA{{0, 0}: red, {6, 6}: blue},
+		},
+	}
+
+	for _, test := range tests {
+		dst := image.NewNRGBA(image.Rect(0, 0, 12, 12))
+		DrawScaled(dst, m, test.x, test.y, test.w, test.h, test.flip)
+
+		for p, want := range test.want {
+			if got := dst.NRGBAAt(p.X, p.Y); got != want {
+				t.Errorf("%v: pixel %v is %v; want %v", test.name, p, got, want)
+			}
+		}
+	}
+}
+
+// testDir returns a Dir of the PNG encoded layers, and the number of times
+// each has been opened.
+func testDir(t *testing.T, layers map[string]image.Image) (*Dir, map[string]int) {
+	files := make(map[string][]byte)
+	for o, m := range layers {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, m); err != nil {
+			t.Fatal(err)
+		}
+		files[o+".png"] = buf.Bytes()
+	}
+
+	var mu sync.Mutex
+	opens := make(map[string]int)
+
+	d := NewDirFunc(func(name string) (io.ReadCloser, error) {
+		mu.Lock()
+		opens[strings.TrimSuffix(name, ".png")]++
+		mu.Unlock()
+
+		b, ok := files[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	})
+
+	return d, opens
+}
+
+func TestDir(t *testing.T) {
+	d, opens := testDir(t, map[string]image.Image{
+		"body/a": square(10, image.Rect(0, 0, 5, 5), red),
+		"body/b": square(10, image.Rect(0, 0, 5, 5), green),
+		"body/c": square(10, image.Rect(0, 0, 5, 5), blue),
+	})
+	// room for two layers of 10 x 10 pixels
+	d.max = 2 * 4 * 10 * 10
+
+	load := func(o string, want color.NRGBA) {
+		l, err := d.Layer(o)
+		if err != nil {
+			t.Fatalf("Layer(%q): %v", o, err)
+		}
+		if got := color.NRGBAModel.Convert(l.At(2, 2)); got != want {
+			t.Errorf("Layer(%q) has colour %v; want %v", o, got, want)
+		}
+	}
+
+	load("body/a", red)
+	load("body/b", green)
+	load("body/a", red)
+	if opens["body/a"] != 1 || opens["body/b"] != 1 {
+		t.Errorf("cached layers opened %v times", opens)
+	}
+
+	// body/b, the least recently used, makes way for body/c
+	load("body/c", blue)
+	load("body/a", red)
+	load("body/b", green)
+	if want := map[string]int{"body/a": 1, "body/b": 2, "body/c": 1}; fmt.Sprint(opens) != fmt.Sprint(want) {
+		t.Errorf("layers opened %v times; want %v", opens, want)
+	}
+	if d.size > d.max || len(d.layers) != 2 {
+		t.Errorf("cache holds %v layers of %v bytes; at most %v bytes allowed", len(d.layers), d.size, d.max)
+	}
+
+	// errors are not cached
+	for i := 0; i < 2; i++ {
+		if _, err := d.Layer("body/d"); !os.IsNotExist(err) {
+			t.Errorf("Layer of missing artwork gave error %v", err)
+		}
+	}
+	if opens["body/d"] != 2 {
+		t.Errorf("missing artwork opened %v times; want 2", opens["body/d"])
+	}
+
+	b, err := d.LayerPNG("body/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(b)); err != nil {
+		t.Errorf("LayerPNG gave invalid PNG: %v", err)
+	}
+}
+
+func TestDirConcurrent(t *testing.T) {
+	d, opens := testDir(t, map[string]image.Image{
+		"body/a": square(10, image.Rect(0, 0, 5, 5), red),
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := d.Layer("body/a"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if opens["body/a"] != 1 {
+		t.Errorf("layer loaded concurrently was opened %v times; want 1", opens["body/a"])
+	}
+}
@@ -0,0 +1,112 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// Resize returns m scaled to w x h pixels. Each pixel of the result is the
+// average of the area of m that it covers, computed in premultiplied space,
+// which suits the large reductions made for thumbnails, emoji and
+// animations.
+func Resize(m image.Image, w, h int) *image.NRGBA {
+	b := m.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+
+	src := image.NewRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(src, src.Bounds(), m, b.Min, draw.Src)
+
+	// resize horizontally into tmp, sh rows of w premultiplied pixels, then
+	// vertically into dst
+	tmp := make([]float64, w*sh*4)
+
+	for x := 0; x < w; x++ {
+		for _, s := range spans(x, w, sw) {
+			for y := 0; y < sh; y++ {
+				p := src.Pix[y*src.Stride+s.i*4:]
+				t := tmp[(y*w+x)*4:]
+				for c := 0; c < 4; c++ {
+					t[c] += float64(p[c]) * s.w
+				}
+			}
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		ss := spans(y, h, sh)
+		for x := 0; x < w; x++ {
+			var v [4]float64
+			for _, s := range ss {
+				t := tmp[(s.i*w+x)*4:]
+				for c := 0; c < 4; c++ {
+					v[c] += t[c] * s.w
+				}
+			}
+
+			if v[3] < 0.5 {
+				continue
+			}
+
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(math.Min(v[0]/v[3]*0xff+0.5, 0xff)),
+				G: uint8(math.Min(v[1]/v[3]*0xff+0.5, 0xff)),
+				B: uint8(math.Min(v[2]/v[3]*0xff+0.5, 0xff)),
+				A: uint8(v[3] + 0.5),
+			})
+		}
+	}
+
+	return dst
+}
+
+// span is the weight of the source pixel i in a destination pixel.
+type span struct {
+	i int
+	w float64
+}
+
+// spans returns the source pixels covered by the destination pixel i, when n
+// destination pixels cover sn source pixels, with weights that sum to 1.
+func spans(i, n, sn int) []span {
+	start := float64(i) * float64(sn) / float64(n)
+	end := float64(i+1) * float64(sn) / float64(n)
+
+	var res []span
+	for j := int(start); float64(j) < end && j < sn; j++ {
+		w := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+		if w > 0 {
+			res = append(res, span{i: j, w: w / (end - start)})
+		}
+	}
+	return res
+}
+
+// OpaqueBounds returns the smallest rectangle that contains every pixel of m
+// that is not fully transparent.
+func OpaqueBounds(m *image.NRGBA) image.Rectangle {
+	b := m.Bounds()
+	// start with an empty rectangle; image.Rect would swap the corners
+	res := image.Rectangle{Min: b.Max, Max: b.Min}
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if m.Pix[m.PixOffset(x, y)+3] == 0 {
+				continue
+			}
+			res.Min.X = minInt(res.Min.X, x)
+			res.Min.Y = minInt(res.Min.Y, y)
+			res.Max.X = maxInt(res.Max.X, x+1)
+			res.Max.Y = maxInt(res.Max.Y, y+1)
+		}
+	}
+
+	if res.Empty() {
+		return image.Rectangle{}
+	}
+
+	return res
+}
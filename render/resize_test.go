@@ -0,0 +1,61 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestResize(t *testing.T) {
+	// left half opaque red, right half transparent
+	m := square(4, image.Rect(0, 0, 2, 4), red)
+
+	tests := []struct {
+		w, h int
+		want []color.NRGBA
+	}{
+		{4, 4, []color.NRGBA{red, red, {}, {}}},
+		{2, 2, []color.NRGBA{red, {}}},
+		// averaged in premultiplied space, so red does not darken
+		{1, 1, []color.NRGBA{{R: 0xff, A: 0x80}}},
+		{8, 1, []color.NRGBA{red, red, red, red, {}, {}, {}, {}}},
+		{3, 1, []color.NRGBA{red, {R: 0xff, A: 0x80}, {}}},
+	}
+
+	for _, test := range tests {
+		res := Resize(m, test.w, test.h)
+		if b := res.Bounds(); b != image.Rect(0, 0, test.w, test.h) {
+			t.Errorf("Resize to %vx%v gave bounds %v", test.w, test.h, b)
+			continue
+		}
+		for x, want := range test.want {
+			if got := res.NRGBAAt(x, 0); got != want {
+				t.Errorf("Resize to %vx%v: pixel %v is %v; want %v", test.w, test.h, x, got, want)
+			}
+		}
+	}
+
+	// the bounds of m need not start at the origin
+	sub := m.SubImage(image.Rect(1, 1, 3, 3))
+	res := Resize(sub, 2, 2)
+	if got := []color.NRGBA{res.NRGBAAt(0, 0), res.NRGBAAt(1, 0)}; got[0] != red || got[1] != (color.NRGBA{}) {
+		t.Errorf("Resize of a sub image gave %v", got)
+	}
+}
+
+func TestOpaqueBounds(t *testing.T) {
+	tests := []struct {
+		m    *image.NRGBA
+		want image.Rectangle
+	}{
+		{square(10, image.Rect(2, 3, 5, 7), red), image.Rect(2, 3, 5, 7)},
+		{square(10, image.Rect(0, 0, 10, 10), color.NRGBA{A: 1}), image.Rect(0, 0, 10, 10)},
+		{square(10, image.Rectangle{}, red), image.Rectangle{}},
+	}
+
+	for _, test := range tests {
+		if got := OpaqueBounds(test.m); got != test.want {
+			t.Errorf("OpaqueBounds = %v; want %v", got, test.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+// bilinear samples m at the point (x, y), where pixel centres lie at integer
+// coordinates, interpolating in premultiplied space. Points outside m are
+// transparent.
+func bilinear(m image.Image, x, y float64) color.NRGBA {
+	x0 := int(math.Floor(x))
+	y0 := int(math.Floor(y))
+	fx := x - float64(x0)
+	fy := y - float64(y0)
+
+	var r, g, b, a float64
+
+	add := func(px, py int, w float64) {
+		if w == 0 || !(image.Point{px, py}.In(m.Bounds())) {
+			return
+		}
+		pr, pg, pb, pa := m.At(px, py).RGBA()
+		r += float64(pr) * w
+		g += float64(pg) * w
+		b += float64(pb) * w
+		a += float64(pa) * w
+	}
+
+	add(x0, y0, (1-fx)*(1-fy))
+	add(x0+1, y0, fx*(1-fy))
+	add(x0, y0+1, (1-fx)*fy)
+	add(x0+1, y0+1, fx*fy)
+
+	if a == 0 {
+		return color.NRGBA{}
+	}
+
+	return color.NRGBA{
+		R: uint8(r/a*0xff + 0.5),
+		G: uint8(g/a*0xff + 0.5),
+		B: uint8(b/a*0xff + 0.5),
+		A: uint8(a/0xffff*0xff + 0.5),
+	}
+}
+
+func decodePNG(b []byte) (image.Image, error) {
+	return png.Decode(bytes.NewReader(b))
+}
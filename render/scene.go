@@ -0,0 +1,55 @@
+package render
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+// ComposeScene draws the gophers of s, each composed as by Compose but
+// without its background, in order onto a canvas of s.Width x s.Height
+// pixels filled with the background of s.
+func ComposeScene(s *gopher.Scene, a Artwork) (*image.NRGBA, error) {
+	dst := image.NewNRGBA(image.Rect(0, 0, s.Width, s.Height))
+
+	if s.Background != "" {
+		bg, err := gopher.ParseColour(s.Background)
+		if err != nil {
+			return nil, err
+		}
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	}
+
+	for _, p := range s.Gophers {
+		m, err := placedGopher(p, a)
+		if err != nil {
+			return nil, err
+		}
+
+		x, y, w, h := p.Rect()
+		DrawScaled(dst, m, x, y, w, h, p.FlipH)
+	}
+
+	return dst, nil
+}
+
+// placedGopher returns the image of the gopher of p, without its
+// background, reduced to about the size at which it is drawn, so that
+// DrawScaled samples it well.
+func placedGopher(p gopher.Placement, a Artwork) (*image.NRGBA, error) {
+	g := p.Gopher.Copy()
+	g.Background = ""
+
+	m, err := Compose(g, a)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, w, h := p.Rect(); w < gopher.Width {
+		m = Resize(m, int(math.Ceil(w)), int(math.Ceil(h)))
+	}
+
+	return m, nil
+}
@@ -0,0 +1,272 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+const (
+	// glyphHeight is the height of the glyphs of the built in fonts, in
+	// font pixels; the sizes of texts are multiples of it.
+	glyphHeight = gopher.TextSizeStep
+
+	// glyphSpacing and lineSpacing are the gaps, in font pixels, between
+	// glyphs and between lines.
+	glyphSpacing = 1
+	lineSpacing  = 3
+
+	// outlineWidth is the width of the outline of banners and bubbles.
+	outlineWidth = 8
+)
+
+var (
+	decorationFill    = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	decorationOutline = color.NRGBA{R: 0x22, G: 0x22, B: 0x22, A: 0xff}
+)
+
+// position describes where on the canvas text is drawn for one of
+// gopher.Positions.
+type position struct {
+	// box is the area within which the text is centred; text that is sized
+	// to fit is made as large as possible within box.
+	box image.Rectangle
+
+	// decorate, if set, draws the background of the text, e.g. a banner.
+	decorate func(dst *image.NRGBA)
+}
+
+var positions = map[string]position{
+	gopher.PositionShirt: {
+		box: image.Rect(330, 1110, 950, 1290),
+	},
+	gopher.PositionBanner: {
+		box: image.Rect(150, 1230, 1150, 1350),
+		decorate: func(dst *image.NRGBA) {
+			r := image.Rect(90, 1200, 1210, 1380)
+			fillRoundedRect(dst, r, 40, decorationOutline)
+			fillRoundedRect(dst, r.Inset(outlineWidth), 40-outlineWidth, decorationFill)
+		},
+	},
+	gopher.PositionBubble: {
+		box: image.Rect(130, 60, 1170, 240),
+		decorate: func(dst *image.NRGBA) {
+			r := image.Rect(70, 20, 1230, 290)
+			tail := [3]image.Point{{560, 260}, {720, 260}, {780, 370}}
+			inner := [3]image.Point{{560 + 2*outlineWidth, 260}, {720 - outlineWidth, 260}, {772, 352}}
+
+			fillRoundedRect(dst, r, 80, decorationOutline)
+			fillTriangle(dst, tail, decorationOutline)
+			fillRoundedRect(dst, r.Inset(outlineWidth), 80-outlineWidth, decorationFill)
+			fillTriangle(dst, inner, decorationFill)
+		},
+	},
+}
+
+// Text draws the text layer t onto a transparent canvas of gopher.Width x
+// gopher.Height pixels. The client uses the same function to draw texts in
+// the browser, so that they appear identically everywhere.
+func Text(t gopher.Text) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, gopher.Width, gopher.Height))
+
+	pos, ok := positions[t.PositionName()]
+	if !ok {
+		return dst
+	}
+
+	if pos.decorate != nil {
+		pos.decorate(dst)
+	}
+
+	lines := t.Lines()
+
+	// the size of the text, in font pixels
+	var w int
+	for _, l := range lines {
+		if lw := lineWidth(t.FontName(), l); lw > w {
+			w = lw
+		}
+	}
+	h := len(lines)*(glyphHeight+lineSpacing) - lineSpacing
+
+	if w == 0 {
+		return dst
+	}
+
+	scale := t.Size / glyphHeight
+	if t.Size == 0 {
+		scale = pos.box.Dx() / w
+		if s := pos.box.Dy() / h; s < scale {
+			scale = s
+		}
+		if s := gopher.MaxTextSize / glyphHeight; s < scale {
+			scale = s
+		}
+	}
+	if scale < 1 {
+		scale = 1
+	}
+
+	c := image.NewUniform(t.RGBA())
+	mid := pos.box.Min.Add(pos.box.Max).Div(2)
+	y := mid.Y - h*scale/2
+
+	for _, l := range lines {
+		x := mid.X - lineWidth(t.FontName(), l)*scale/2
+		drawLine(dst, t.FontName(), l, image.Pt(x, y), scale, c)
+
+		y += (glyphHeight + lineSpacing) * scale
+	}
+
+	return dst
+}
+
+// Caption draws the single line s in the named font and colour c, centred on
+// a transparent canvas of w x h pixels and as large as fits. A line too long
+// to fit even at the smallest size is cut short, ending with "...".
+func Caption(s, font string, w, h int, c color.Color) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	if h < glyphHeight {
+		return dst
+	}
+
+	rs := []rune(s)
+	l := s
+	for len(rs) > 0 && lineWidth(font, l) > w {
+		rs = rs[:len(rs)-1]
+		l = string(rs) + "..."
+	}
+	if len(rs) == 0 {
+		return dst
+	}
+
+	lw := lineWidth(font, l)
+	scale := minInt(w/lw, h/glyphHeight)
+
+	p := image.Pt((w-lw*scale)/2, (h-glyphHeight*scale)/2)
+	drawLine(dst, font, l, p, scale, image.NewUniform(c))
+
+	return dst
+}
+
+// drawLine draws the line l in the named font, with its top left corner at
+// p and each font pixel scale x scale pixels.
+func drawLine(dst draw.Image, font string, l string, p image.Point, scale int, c image.Image) {
+	x, y := p.X, p.Y
+
+	for _, r := range l {
+		cols := glyph(font, r)
+
+		for i, col := range cols {
+			for j := 0; j < glyphHeight; j++ {
+				if col&(1<<uint(j)) == 0 {
+					continue
+				}
+
+				p := image.Rect(x+i*scale, y+j*scale, x+(i+1)*scale, y+(j+1)*scale)
+				draw.Draw(dst, p, c, image.Point{}, draw.Src)
+			}
+		}
+
+		x += (len(cols) + glyphSpacing) * scale
+	}
+}
+
+// glyph returns the columns of the glyph for r in the named font. Characters
+// for which there is no glyph are drawn as '?'.
+func glyph(font string, r rune) []byte {
+	if r < ' ' || int(r-' ') >= len(glyphs5x7) {
+		r = '?'
+	}
+
+	g := glyphs5x7[r-' ']
+
+	if font != gopher.FontBold {
+		return g[:]
+	}
+
+	// the bold font is the 5x7 font with each column smeared one pixel to
+	// the right
+	res := make([]byte, len(g)+1)
+	for i := range res {
+		if i < len(g) {
+			res[i] |= g[i]
+		}
+		if i > 0 {
+			res[i] |= g[i-1]
+		}
+	}
+
+	return res
+}
+
+// lineWidth returns the width in font pixels of the line l.
+func lineWidth(font string, l string) int {
+	var w int
+	for _, r := range l {
+		w += len(glyph(font, r)) + glyphSpacing
+	}
+	if w > 0 {
+		w -= glyphSpacing
+	}
+	return w
+}
+
+func fillRoundedRect(dst *image.NRGBA, r image.Rectangle, radius int, c color.NRGBA) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// the distance of (x, y) into the corner squares
+			dx := maxInt(r.Min.X+radius-x, x-(r.Max.X-1-radius), 0)
+			dy := maxInt(r.Min.Y+radius-y, y-(r.Max.Y-1-radius), 0)
+
+			if dx*dx+dy*dy <= radius*radius {
+				dst.SetNRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+func fillTriangle(dst *image.NRGBA, t [3]image.Point, c color.NRGBA) {
+	var b image.Rectangle
+	for _, p := range t {
+		b = b.Union(image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))})
+	}
+
+	edge := func(a, b, p image.Point) int {
+		return (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+	}
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			p := image.Pt(x, y)
+			e0 := edge(t[0], t[1], p)
+			e1 := edge(t[1], t[2], p)
+			e2 := edge(t[2], t[0], p)
+
+			if (e0 >= 0 && e1 >= 0 && e2 >= 0) || (e0 <= 0 && e1 <= 0 && e2 <= 0) {
+				dst.SetNRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+func maxInt(v int, vs ...int) int {
+	for _, w := range vs {
+		if w > v {
+			v = w
+		}
+	}
+	return v
+}
+
+func minInt(v int, vs ...int) int {
+	for _, w := range vs {
+		if w < v {
+			v = w
+		}
+	}
+	return v
+}
@@ -0,0 +1,96 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"myitcv.io/gopherize.me/gopher"
+)
+
+// inkBounds returns the bounds of the pixels of m of colour c.
+func inkBounds(m *image.NRGBA, c color.NRGBA) image.Rectangle {
+	b := m.Bounds()
+	res := image.Rectangle{Min: b.Max, Max: b.Min}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if m.NRGBAAt(x, y) == c {
+				res = res.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if res.Empty() {
+		return image.Rectangle{}
+	}
+	return res
+}
+
+func TestText(t *testing.T) {
+	black := color.NRGBA{A: 0xff}
+
+	tests := []struct {
+		name string
+		t    gopher.Text
+		h    int
+	}{
+		{"sized", gopher.Text{Text: "HI", Size: 28}, 28},
+		{"smallest", gopher.Text{Text: "HI", Size: gopher.MinTextSize}, gopher.MinTextSize},
+		{"two lines", gopher.Text{Text: "HI\nHI", Size: 14}, 2*14 + 2*lineSpacing},
+		// as large as fits the height of the shirt
+		{"fit", gopher.Text{Text: "HI"}, positions[gopher.PositionShirt].box.Dy() / glyphHeight * glyphHeight},
+		{"bold", gopher.Text{Text: "HI", Font: gopher.FontBold, Size: 70}, 70},
+	}
+
+	for _, test := range tests {
+		m := Text(test.t)
+		ink := inkBounds(m, black)
+		if ink.Empty() {
+			t.Errorf("%v: nothing drawn", test.name)
+			continue
+		}
+		if got := ink.Dy(); got != test.h {
+			t.Errorf("%v: text is %v pixels high; want %v", test.name, got, test.h)
+		}
+
+		// centred in the shirt, to within a font pixel
+		box := positions[gopher.PositionShirt].box
+		mid := box.Min.Add(box.Max).Div(2)
+		scale := ink.Dy() / glyphHeight
+		if c := ink.Min.Add(ink.Max).Div(2); abs(c.X-mid.X) > scale || abs(c.Y-mid.Y) > scale {
+			t.Errorf("%v: text centred at %v; want %v", test.name, c, mid)
+		}
+	}
+
+	if !inkBounds(Text(gopher.Text{Text: "HI", Position: "nowhere"}), black).Empty() {
+		t.Errorf("text in an unknown position was drawn")
+	}
+	if m := Text(gopher.Text{Text: "HI", Position: gopher.PositionBanner}); inkBounds(m, decorationOutline).Empty() {
+		t.Errorf("banner not drawn")
+	}
+}
+
+func TestCaption(t *testing.T) {
+	c := color.NRGBA{R: 0xff, A: 0xff}
+
+	m := Caption("Gordon", gopher.FontPixel, 200, 20, c)
+	if got := inkBounds(m, c).Dy(); got != 14 {
+		t.Errorf("caption is %v pixels high; want 14", got)
+	}
+
+	// too long to fit at the smallest size, so cut short
+	long := Caption("Gordon the gopher", gopher.FontPixel, 40, glyphHeight, c)
+	if ink := inkBounds(long, c); ink.Empty() || ink.Max.X > 40 {
+		t.Errorf("long caption drawn within %v", ink)
+	}
+
+	if !inkBounds(Caption("Gordon", gopher.FontPixel, 200, glyphHeight-1, c), c).Empty() {
+		t.Errorf("caption drawn in too little height")
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"sync"
+	"time"
+
+	"myitcv.io/gopherize.me/export"
+)
+
+// imageCache holds images drawn by the server, PNG encoded, discarding the
+// least recently used once they total more than max bytes.
+type imageCache struct {
+	max int
+
+	mu    sync.Mutex
+	size  int
+	order *list.List // of *cacheEntry, most recently used first
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key string
+	b   []byte
+}
+
+func newImageCache(max int) *imageCache {
+	return &imageCache{
+		max:   max,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// get returns the image cached under key.
+func (c *imageCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+
+	return e.Value.(*cacheEntry).b, true
+}
+
+// add caches the image b under key, unless it is larger than the cache.
+func (c *imageCache) add(key string, b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(b) > c.max {
+		return
+	}
+
+	if e, ok := c.items[key]; ok {
+		c.remove(e)
+	}
+
+	for c.size+len(b) > c.max {
+		c.remove(c.order.Back())
+	}
+
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, b: b})
+	c.size += len(b)
+}
+
+func (c *imageCache) remove(e *list.Element) {
+	ce := c.order.Remove(e).(*cacheEntry)
+	delete(c.items, ce.key)
+	c.size -= len(ce.b)
+}
+
+// maxRenders is the number of images not already cached, such as team photos
+// and contact sheets, that are drawn at once, and maxRenderWait how long a
+// request waits to draw one before the server gives up as too busy.
+const (
+	maxRenders    = 2
+	maxRenderWait = 10 * time.Second
+)
+
+// draw returns the PNG encoded image cached in c under key, or else draws it
+// with f, as one of at most maxRenders being drawn at once, and caches it.
+func (s *Server) draw(r *http.Request, c *imageCache, key string, f func() (image.Image, error)) ([]byte, error) {
+	if b, ok := c.get(key); ok {
+		return b, nil
+	}
+
+	t := time.NewTimer(maxRenderWait)
+	defer t.Stop()
+
+	select {
+	case s.renders <- struct{}{}:
+	case <-t.C:
+		return nil, errorf(http.StatusServiceUnavailable, "too busy to draw; try again later")
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+	defer func() { <-s.renders }()
+
+	// drawn while waiting, perhaps
+	if b, ok := c.get(key); ok {
+		return b, nil
+	}
+
+	m, err := f()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		return nil, err
+	}
+	b := buf.Bytes()
+
+	c.add(key, b)
+
+	return b, nil
+}
+
+// mosaicKey returns a key that identifies the options o of a mosaic.
+func mosaicKey(o export.MosaicOptions) string {
+	return fmt.Sprintf("columns=%v width=%v spacing=%v background=%v captions=%v caption_colour=%v",
+		o.Columns, o.Width, o.Spacing, colourKey(o.Background), o.Captions, colourKey(o.CaptionColour))
+}
+
+func colourKey(c color.Color) string {
+	if c == nil {
+		return "none"
+	}
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x%02x", n.R, n.G, n.B, n.A)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"image/color"
+	"testing"
+
+	"myitcv.io/gopherize.me/export"
+)
+
+func TestImageCache(t *testing.T) {
+	c := newImageCache(10)
+
+	c.add("a", make([]byte, 4))
+	c.add("b", make([]byte, 4))
+
+	// a is now the most recently used, so b is discarded to make room
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("a not cached")
+	}
+	c.add("c", make([]byte, 4))
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.get(key); ok != want {
+			t.Errorf("get(%q) found %v; want %v", key, ok, want)
+		}
+	}
+
+	// replacing an entry does not count it twice
+	c.add("c", make([]byte, 6))
+	if _, ok := c.get("a"); !ok || c.size != 10 {
+		t.Errorf("after replacing c, a cached %v, size %v; want true, 10", ok, c.size)
+	}
+
+	// images larger than the cache are not cached, nor evict anything
+	c.add("d", make([]byte, 11))
+	if _, ok := c.get("d"); ok {
+		t.Errorf("oversized image cached")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Errorf("oversized image evicted a")
+	}
+}
+
+func TestMosaicKey(t *testing.T) {
+	o := export.DefaultMosaicOptions
+
+	same := o
+	same.Background = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	if mosaicKey(o) != mosaicKey(same) {
+		t.Errorf("keys of the same colour differ: %q, %q", mosaicKey(o), mosaicKey(same))
+	}
+
+	var others []export.MosaicOptions
+	for i := 0; i < 5; i++ {
+		d := o
+		switch i {
+		case 0:
+			d.Columns = 3
+		case 1:
+			d.Width = 100
+		case 2:
+			d.Spacing = 0
+		case 3:
+			d.Background = nil
+		case 4:
+			d.Captions = false
+		}
+		others = append(others, d)
+	}
+	for _, d := range others {
+		if mosaicKey(d) == mosaicKey(o) {
+			t.Errorf("options %+v and %+v have the same key %q", d, o, mosaicKey(o))
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"bytes"
+	"html/template"
+	"image"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"myitcv.io/gopherize.me/export"
+	"myitcv.io/gopherize.me/gopher"
+)
+
+const (
+	cataloguePath = "/catalogue"
+	sheetPath     = "/api/catalogue/sheet.png"
+
+	// maxSheetCache is the most, in bytes, of drawn contact sheets that are
+	// kept; sheets are slow to draw but only change with the artwork.
+	maxSheetCache = 64 << 20
+)
+
+// sheetOptions returns the options of the contact sheet of the category
+// given by the query parameter category. The options are otherwise fixed, so
+// that there are few sheets to draw, and each is drawn only once.
+func (s *Server) sheetOptions(r *http.Request) (export.ContactSheetOptions, error) {
+	o := export.DefaultContactSheetOptions
+
+	v := r.URL.Query().Get("category")
+	if v == "" {
+		return o, errorf(http.StatusBadRequest, "no category given")
+	}
+	if s.category(v) == nil {
+		return o, errorf(http.StatusNotFound, "unknown category %q", v)
+	}
+	o.Category = v
+
+	return o, nil
+}
+
+// category returns the category called name, as named by a contact sheet.
+func (s *Server) category(name string) *gopher.Category {
+	for _, c := range s.config.Categories {
+		if gopher.CategoryKey(c.Name) == gopher.CategoryKey(name) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *Server) sheet(w http.ResponseWriter, r *http.Request) error {
+	o, err := s.sheetOptions(r)
+	if err != nil {
+		return err
+	}
+
+	b, err := s.draw(r, s.sheets, gopher.CategoryKey(o.Category), func() (image.Image, error) {
+		m, missing, err := export.ContactSheet(s.config, s.art, o)
+		for _, p := range missing {
+			log.Printf("contact sheet: no artwork for %v", p)
+		}
+		return m, err
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.Write(b)
+
+	return nil
+}
+
+var cataloguePageTmpl = template.Must(template.New("catalogue").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Catalogue</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.sheet { max-width: 100%; }
+</style>
+</head>
+<body>
+<h1>Catalogue</h1>
+<ul>
+{{range .}}<li><a href="#{{.Key}}">{{.Name}}</a> ({{.Options}})</li>
+{{end}}</ul>
+{{range .}}<h2 id="{{.Key}}">{{.Name}}</h2>
+<a href="{{.Sheet}}"><img class="sheet" src="{{.Sheet}}" alt="The options of {{.Name}}"></a>
+{{end}}</body>
+</html>
+`))
+
+// cataloguePage shows the contact sheet of each category.
+func (s *Server) cataloguePage(w http.ResponseWriter, r *http.Request) error {
+	type category struct {
+		Key     string
+		Name    string
+		Options int
+		Sheet   string
+	}
+
+	var cats []category
+	for _, c := range s.config.Categories {
+		q := url.Values{"category": {gopher.CategoryKey(c.Name)}}
+
+		n := 0
+		for _, o := range c.Options {
+			if o != "" {
+				n++
+			}
+		}
+
+		cats = append(cats, category{
+			Key:     gopher.CategoryKey(c.Name),
+			Name:    c.Name,
+			Options: n,
+			Sheet:   sheetPath + "?" + q.Encode(),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := cataloguePageTmpl.Execute(&buf, cats); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+
+	return nil
+}
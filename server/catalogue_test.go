@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"myitcv.io/gopherize.me/store"
+)
+
+func TestSheetCache(t *testing.T) {
+	s := newTestServer(store.NewMemory())
+
+	sheet := func(query string) int {
+		w := do(s, "GET", sheetPath+query, nil, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("sheet%v gave %v: %v", query, w.Code, w.Body)
+		}
+		return s.sheets.order.Len()
+	}
+
+	sheet("?category=eyes")
+
+	// queries that name the same category give the same sheet; other
+	// parameters are ignored
+	for _, q := range []string{
+		"?category=Eyes",
+		"?category=eyes&junk=1",
+		"?category=eyes&width=1024&base=nonsense",
+	} {
+		if n := sheet(q); n != 1 {
+			t.Errorf("sheet%v was drawn again", q)
+		}
+	}
+
+	// invalid queries draw nothing
+	for _, q := range []string{"", "?category=shoes", "?width=64"} {
+		if w := do(s, "GET", sheetPath+q, nil, ""); w.Code == http.StatusOK || !strings.Contains(w.Body.String(), "Error") {
+			t.Errorf("sheet%v gave %v", q, w.Code)
+		}
+	}
+	if n := s.sheets.order.Len(); n != 1 {
+		t.Errorf("%v sheets cached; want 1", n)
+	}
+}
+
+func TestDrawBusy(t *testing.T) {
+	s := newTestServer(store.NewMemory())
+
+	// a request that is cancelled while others draw draws nothing
+	for i := 0; i < maxRenders; i++ {
+		s.renders <- struct{}{}
+	}
+	r, _ := http.NewRequest("GET", sheetPath+"?category=eyes", nil)
+	ctx, cancel := context.WithCancel(r.Context())
+	cancel()
+
+	if _, err := s.draw(r.WithContext(ctx), s.sheets, "key", nil); err == nil {
+		t.Errorf("draw while busy gave no error")
+	}
+	if n := s.sheets.order.Len(); n != 0 {
+		t.Errorf("%v sheets cached; want 0", n)
+	}
+}
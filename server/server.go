@@ -0,0 +1,651 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// Package server implements the gallery of gophers saved from gopherize.me,
+// a REST API through which gophers are saved and fetched:
+//
+//	POST   /api/gophers                    save a recipe, returning the gopher
+//	GET    /api/gophers?after=id&limit=n   list saved gophers, in the order saved
+//	GET    /api/gophers/{id}               fetch a gopher
+//	PUT    /api/gophers/{id}               replace the recipe of a gopher
+//	DELETE /api/gophers/{id}               delete a gopher
+//	GET    /api/gophers/{id}/thumbnail.png fetch the thumbnail of a gopher
+//
+//	POST   /api/teams                      create a team of saved gophers
+//	GET    /api/teams?after=id&limit=n     list teams, in the order created
+//	GET    /api/teams/{id}                 fetch a team
+//	PUT    /api/teams/{id}                 replace the name and members of a team
+//	DELETE /api/teams/{id}                 delete a team
+//	GET    /api/teams/{id}/photo.png       draw the mosaic of a team
+//	GET    /teams/{id}                     a page showing a team
+//
+//	GET    /api/catalogue/sheet.png        draw a contact sheet of the artwork
+//	GET    /catalogue                      a page showing the sheet of each category
+//
+// A recipe is saved as a JSON recipe, a text recipe or a PNG image exported
+// with its recipe. Gophers are returned as JSON objects holding their ID,
+// times of creation and last update, JSON recipe and the URL of their
+// thumbnail.
+//
+// There are no user accounts. Instead, the response to saving a gopher
+// includes an EditToken, a secret that is returned only once and of which
+// only a hash is stored. Requests to replace or delete the gopher must
+// present it in the header "Authorization: Bearer <token>". Teams are
+// created, and edited, in the same way.
+//
+// A team is a named list of members, each a name and the ID of a saved
+// gopher, created from a JSON object such as
+//
+//	{"Name": "Gophers", "Members": [{"Name": "Gordon", "Gopher": "06gl290h0gpfu2gh"}]}
+//
+// Its photo is drawn as described by the query parameters columns (1 to 6,
+// 8 or 10), width (of each gopher: 64, 128, 256 or 512), spacing (0, 8, 16
+// or 32), background (a colour #rrggbb, or "none") and captions (true or
+// false).
+//
+// A contact sheet shows each option of the category given by the parameter
+// category worn by the first Body and Eyes, laid out as a team photo with
+// the default options.
+//
+// Photos and sheets are cached once drawn; only a few are drawn at once, and
+// a request that waits too long for its turn fails with 503 Service
+// Unavailable.
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"myitcv.io/gopherize.me/export"
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+	"myitcv.io/gopherize.me/store"
+)
+
+const (
+	// ThumbnailWidth is the width in pixels of the thumbnails of saved
+	// gophers.
+	ThumbnailWidth = 256
+
+	// MaxRecipeSize is the largest recipe, in bytes, that can be saved;
+	// recipes with uploads carry their images.
+	MaxRecipeSize = export.MaxRecipeSize
+
+	// DefaultPageSize and MaxPageSize are the default and largest number of
+	// gophers returned by a list.
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+const apiPrefix = "/api/gophers"
+
+// The prefixes of the keys under which gophers and their thumbnails are
+// stored, followed by their IDs.
+const (
+	gopherPrefix    = "gophers/"
+	thumbnailPrefix = "thumbnails/"
+)
+
+// Saved is a gopher saved to the gallery, as stored.
+type Saved struct {
+	// ID identifies the gopher; IDs sort in the order in which gophers were
+	// saved.
+	ID string
+
+	Created time.Time
+	Updated time.Time
+
+	// Recipe is the JSON recipe of the gopher, as written by
+	// gopher.MarshalRecipe.
+	Recipe json.RawMessage
+
+	// TokenHash is the hash of the edit token of the gopher; see
+	// hashToken.
+	TokenHash string
+}
+
+// Server serves the gallery API.
+type Server struct {
+	store  store.Store
+	art    render.Artwork
+	config *gopher.Config
+
+	// photos holds drawn team photos, keyed by the versions of the team
+	// and its gophers, and the options of the photo
+	photos *imageCache
+
+	// sheets holds drawn contact sheets, keyed by their category
+	sheets *imageCache
+
+	// renders holds a value for each photo or sheet being drawn; see draw
+	renders chan struct{}
+}
+
+// New returns a Server that keeps gophers in s, drawing their thumbnails
+// with the artwork a.
+func New(s store.Store, a render.Artwork) *Server {
+	return &Server{
+		store:  s,
+		art:    a,
+		config: gopher.DefaultConfig,
+		photos: newImageCache(maxPhotoCache),
+		sheets: newImageCache(maxSheetCache),
+
+		renders: make(chan struct{}, maxRenders),
+	}
+}
+
+// gopherJSON is the form in which a gopher is returned by the API.
+type gopherJSON struct {
+	ID        string
+	Created   time.Time
+	Updated   time.Time
+	Recipe    json.RawMessage
+	Thumbnail string
+
+	// EditToken is only returned when a gopher is first saved.
+	EditToken string `json:",omitempty"`
+}
+
+// listJSON is the form in which a page of gophers is returned by the API.
+type listJSON struct {
+	Gophers []gopherJSON
+
+	// Next is the value of the after parameter that fetches the next page,
+	// if there may be one.
+	Next string `json:",omitempty"`
+}
+
+// errorJSON is the form in which errors are returned by the API.
+type errorJSON struct {
+	Error string
+}
+
+// httpError is an error with the HTTP status with which it is returned.
+type httpError struct {
+	code int
+	msg  string
+}
+
+func (h httpError) Error() string {
+	return h.msg
+}
+
+func errorf(code int, format string, args ...interface{}) error {
+	return httpError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := s.serve(w, r); err != nil {
+		code := http.StatusInternalServerError
+		msg := "internal error"
+		if he, ok := err.(httpError); ok {
+			code, msg = he.code, he.msg
+		} else {
+			log.Printf("%v %v: %v", r.Method, r.URL.Path, err)
+		}
+		writeJSON(w, code, errorJSON{Error: msg})
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) error {
+	p := r.URL.Path
+
+	switch {
+	case p == teamsPrefix || strings.HasPrefix(p, teamsPrefix+"/"):
+		return s.serveTeams(w, r)
+	case strings.HasPrefix(p, teamPagePrefix):
+		if r.Method != "GET" && r.Method != "HEAD" {
+			return methodNotAllowed(w, "GET, HEAD")
+		}
+		return s.teamPage(w, strings.TrimPrefix(p, teamPagePrefix))
+	case p == cataloguePath || p == sheetPath:
+		if r.Method != "GET" && r.Method != "HEAD" {
+			return methodNotAllowed(w, "GET, HEAD")
+		}
+		if p == sheetPath {
+			return s.sheet(w, r)
+		}
+		return s.cataloguePage(w, r)
+	}
+
+	if p != apiPrefix && !strings.HasPrefix(p, apiPrefix+"/") {
+		return errorf(http.StatusNotFound, "not found")
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(p, apiPrefix), "/"), "/")
+
+	switch {
+	case parts[0] == "":
+		switch r.Method {
+		case "GET", "HEAD":
+			return s.list(w, r)
+		case "POST":
+			return s.create(w, r)
+		}
+		return methodNotAllowed(w, "GET, HEAD, POST")
+
+	case len(parts) == 1:
+		switch r.Method {
+		case "GET", "HEAD":
+			return s.get(w, parts[0])
+		case "PUT":
+			return s.update(w, r, parts[0])
+		case "DELETE":
+			return s.delete(w, r, parts[0])
+		}
+		return methodNotAllowed(w, "GET, HEAD, PUT, DELETE")
+
+	case len(parts) == 2 && parts[1] == "thumbnail.png":
+		if r.Method != "GET" && r.Method != "HEAD" {
+			return methodNotAllowed(w, "GET, HEAD")
+		}
+		return s.thumbnail(w, parts[0])
+	}
+
+	return errorf(http.StatusNotFound, "not found")
+}
+
+func methodNotAllowed(w http.ResponseWriter, allow string) error {
+	w.Header().Set("Allow", allow)
+	return errorf(http.StatusMethodNotAllowed, "method not allowed")
+}
+
+// readRecipe returns the JSON recipe and thumbnail of the gopher whose
+// recipe is the body of r.
+func (s *Server) readRecipe(w http.ResponseWriter, r *http.Request) (recipe, thumb []byte, err error) {
+	b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxRecipeSize))
+	if err != nil {
+		return nil, nil, errorf(http.StatusRequestEntityTooLarge, "recipe larger than %v bytes", MaxRecipeSize)
+	}
+
+	g, err := s.parseRecipe(b)
+	if errs, ok := err.(gopher.RecipeTextErrors); ok {
+		var msgs []string
+		for _, e := range errs {
+			msgs = append(msgs, e.Error())
+		}
+		return nil, nil, errorf(http.StatusBadRequest, "invalid recipe: %v", strings.Join(msgs, "; "))
+	}
+	if err != nil {
+		return nil, nil, errorf(http.StatusBadRequest, "invalid recipe: %v", err)
+	}
+
+	recipe, err = gopher.MarshalRecipe(g)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	thumb, err = s.drawThumbnail(g)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return recipe, thumb, nil
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) error {
+	recipe, thumb, err := s.readRecipe(w, r)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	id, err := newID(now)
+	if err != nil {
+		return err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	saved := Saved{
+		ID:        id,
+		Created:   now,
+		Updated:   now,
+		Recipe:    recipe,
+		TokenHash: hashToken(token),
+	}
+
+	b, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+
+	// the thumbnail is stored first, so that every stored gopher has one,
+	// and removed again if the gopher cannot be stored
+	if _, err := s.store.Put(thumbnailPrefix+id, thumb, store.NoVersion); err != nil {
+		return err
+	}
+	if _, err := s.store.Put(gopherPrefix+id, b, store.NoVersion); err != nil {
+		s.store.Delete(thumbnailPrefix+id, store.AnyVersion)
+		return err
+	}
+
+	res := toJSON(saved)
+	res.EditToken = token
+
+	w.Header().Set("Location", apiPrefix+"/"+id)
+	writeJSON(w, http.StatusCreated, res)
+
+	return nil
+}
+
+// authorise returns the stored record and gopher with the given id, provided
+// r presents its edit token.
+func (s *Server) authorise(r *http.Request, id string) (store.Record, Saved, error) {
+	rec, err := s.load(gopherPrefix, id)
+	if err != nil {
+		return store.Record{}, Saved{}, err
+	}
+
+	saved, err := decode(rec)
+	if err != nil {
+		return store.Record{}, Saved{}, err
+	}
+
+	if err := checkToken(r, saved.TokenHash, "gopher "+id); err != nil {
+		return store.Record{}, Saved{}, err
+	}
+
+	return rec, saved, nil
+}
+
+// checkToken returns an error unless r presents the edit token of what,
+// whose hash is hash.
+func checkToken(r *http.Request, hash, what string) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return errorf(http.StatusUnauthorized, "an edit token is required")
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+
+	if hash == "" || subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(hash)) != 1 {
+		return errorf(http.StatusForbidden, "invalid edit token for %v", what)
+	}
+
+	return nil
+}
+
+func (s *Server) update(w http.ResponseWriter, r *http.Request, id string) error {
+	rec, saved, err := s.authorise(r, id)
+	if err != nil {
+		return err
+	}
+
+	recipe, thumb, err := s.readRecipe(w, r)
+	if err != nil {
+		return err
+	}
+
+	saved.Recipe = recipe
+	saved.Updated = time.Now().UTC()
+
+	b, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+
+	// the gopher is written first, so that of two concurrent updates only
+	// the one that wins writes a thumbnail
+	_, err = s.store.Put(gopherPrefix+id, b, rec.Version)
+	if store.IsConflict(err) {
+		return errorf(http.StatusConflict, "gopher %v was changed concurrently; try again", id)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := s.store.Put(thumbnailPrefix+id, thumb, store.AnyVersion); err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, toJSON(saved))
+
+	return nil
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request, id string) error {
+	rec, _, err := s.authorise(r, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.Delete(gopherPrefix+id, rec.Version)
+	if store.IsConflict(err) {
+		return errorf(http.StatusConflict, "gopher %v was changed concurrently; try again", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.Delete(thumbnailPrefix+id, store.AnyVersion); err != nil && !store.IsNotFound(err) {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// parseRecipe returns the gopher described by b, which is a PNG image
+// exported with its recipe, a JSON recipe or a text recipe.
+func (s *Server) parseRecipe(b []byte) (*gopher.Gopher, error) {
+	if bytes.HasPrefix(b, []byte("\x89PNG")) {
+		return export.ReadPNG(bytes.NewReader(b), s.config)
+	}
+
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] == '{' {
+		return gopher.UnmarshalRecipe(b, s.config)
+	}
+
+	return gopher.ParseRecipeText(b, s.config)
+}
+
+// drawThumbnail returns the PNG encoded thumbnail of g.
+func (s *Server) drawThumbnail(g *gopher.Gopher) ([]byte, error) {
+	m, err := render.Compose(g, s.art)
+	if err != nil {
+		return nil, err
+	}
+
+	b := m.Bounds()
+	m = render.Resize(m, ThumbnailWidth, ThumbnailWidth*b.Dy()/b.Dx())
+
+	var buf bytes.Buffer
+	if err := export.PNG(&buf, m, g); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// load returns the stored record of the gopher id.
+func (s *Server) load(prefix, id string) (store.Record, error) {
+	if !validID(id) {
+		return store.Record{}, errorf(http.StatusNotFound, "no gopher %v", id)
+	}
+
+	r, err := s.store.Get(prefix + id)
+	if store.IsNotFound(err) {
+		return store.Record{}, errorf(http.StatusNotFound, "no gopher %v", id)
+	}
+
+	return r, err
+}
+
+// decode returns the gopher stored in r.
+func decode(r store.Record) (Saved, error) {
+	var saved Saved
+	if err := json.Unmarshal(r.Value, &saved); err != nil {
+		return Saved{}, fmt.Errorf("corrupt gopher %v: %v", r.Key, err)
+	}
+	return saved, nil
+}
+
+func (s *Server) get(w http.ResponseWriter, id string) error {
+	r, err := s.load(gopherPrefix, id)
+	if err != nil {
+		return err
+	}
+
+	saved, err := decode(r)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, toJSON(saved))
+
+	return nil
+}
+
+func (s *Server) thumbnail(w http.ResponseWriter, id string) error {
+	r, err := s.load(thumbnailPrefix, id)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(r.Value)))
+	w.Write(r.Value)
+
+	return nil
+}
+
+// page returns the records with the given prefix of the page requested by
+// the after and limit parameters of r.
+func (s *Server) page(r *http.Request, prefix string) (rs []store.Record, limit int, err error) {
+	q := r.URL.Query()
+
+	limit = DefaultPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxPageSize {
+			return nil, 0, errorf(http.StatusBadRequest, "invalid limit %q; expected 1 to %v", v, MaxPageSize)
+		}
+		limit = n
+	}
+
+	after := q.Get("after")
+	if after != "" {
+		after = prefix + after
+	}
+
+	rs, err = s.store.List(prefix, after, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return rs, limit, nil
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) error {
+	rs, limit, err := s.page(r, gopherPrefix)
+	if err != nil {
+		return err
+	}
+
+	res := listJSON{Gophers: []gopherJSON{}}
+	for _, r := range rs {
+		saved, err := decode(r)
+		if err != nil {
+			return err
+		}
+		res.Gophers = append(res.Gophers, toJSON(saved))
+	}
+	if len(res.Gophers) == limit {
+		res.Next = res.Gophers[limit-1].ID
+	}
+
+	writeJSON(w, http.StatusOK, res)
+
+	return nil
+}
+
+func toJSON(s Saved) gopherJSON {
+	return gopherJSON{
+		ID:        s.ID,
+		Created:   s.Created,
+		Updated:   s.Updated,
+		Recipe:    s.Recipe,
+		Thumbnail: apiPrefix + "/" + s.ID + "/thumbnail.png",
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Printf("failed to encode response: %v", err)
+		code = http.StatusInternalServerError
+		b = []byte(`{"Error": "internal error"}`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(append(b, '\n'))
+}
+
+// idEncoding is the encoding of IDs; the extended hex alphabet preserves the
+// order of the encoded bytes.
+var idEncoding = base32.NewEncoding("0123456789abcdefghijklmnopqrstuv").WithPadding(base32.NoPadding)
+
+// idLen is the length of an encoded ID of 10 bytes.
+const idLen = 16
+
+// newID returns a new ID for a gopher saved at t: the milliseconds since the
+// epoch, so that IDs sort in the order in which gophers were saved, followed
+// by random bytes.
+func newID(t time.Time) (string, error) {
+	var b [10]byte
+
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixNano()/int64(time.Millisecond)))
+	copy(b[:6], ms[2:])
+
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %v", err)
+	}
+
+	return idEncoding.EncodeToString(b[:]), nil
+}
+
+// validID reports whether id is of the form returned by newID.
+func validID(id string) bool {
+	if len(id) != idLen {
+		return false
+	}
+	_, err := idEncoding.DecodeString(id)
+	return err == nil
+}
+
+// newToken returns a new edit token.
+func newToken() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate edit token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// hashToken returns the hash of the edit token t that is stored in its
+// place. Tokens are random and long, so a fast hash suffices.
+func hashToken(t string) string {
+	h := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"myitcv.io/gopherize.me/render"
+	"myitcv.io/gopherize.me/store"
+)
+
+const testRecipe = "body=blue_gopher eyes=eyes"
+
+func newTestServer(st store.Store) *Server {
+	return New(st, render.NewDir(filepath.Join("..", "artwork")))
+}
+
+// do makes a request of s, with the edit token token if not empty.
+func do(s *Server, method, path string, body io.Reader, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, body)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+// failingStore fails to Put records whose keys have the prefix fail.
+type failingStore struct {
+	store.Store
+	fail string
+}
+
+func (f failingStore) Put(key string, value []byte, version int64) (int64, error) {
+	if strings.HasPrefix(key, f.fail) {
+		return 0, errors.New("disk full")
+	}
+	return f.Store.Put(key, value, version)
+}
+
+func TestCreateFailureLeavesNoThumbnail(t *testing.T) {
+	mem := store.NewMemory()
+	s := newTestServer(failingStore{Store: mem, fail: gopherPrefix})
+
+	w := do(s, "POST", apiPrefix, strings.NewReader(testRecipe), "")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("create gave %v; want %v", w.Code, http.StatusInternalServerError)
+	}
+
+	rs, err := mem.List(thumbnailPrefix, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 0 {
+		t.Errorf("failed create left thumbnail %v", rs[0].Key)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	s := newTestServer(store.NewMemory())
+
+	w := do(s, "POST", apiPrefix, strings.NewReader(testRecipe), "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create gave %v: %v", w.Code, w.Body)
+	}
+
+	var g gopherJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &g); err != nil {
+		t.Fatal(err)
+	}
+	if g.EditToken == "" {
+		t.Errorf("create returned no edit token")
+	}
+	if loc := w.Header().Get("Location"); loc != apiPrefix+"/"+g.ID {
+		t.Errorf("create gave Location %q; want %q", loc, apiPrefix+"/"+g.ID)
+	}
+
+	w = do(s, "GET", g.Thumbnail, nil, "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("thumbnail gave %v, %v", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	w = do(s, "GET", apiPrefix+"/"+g.ID, nil, "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "EditToken") {
+		t.Errorf("get gave %v: %v", w.Code, w.Body)
+	}
+}
+
+func TestCreateInvalid(t *testing.T) {
+	s := newTestServer(store.NewMemory())
+
+	w := do(s, "POST", apiPrefix, strings.NewReader("body=blue_gopher"), "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("create of invalid recipe gave %v; want %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+// create saves testRecipe to s, returning the gopher saved.
+func create(t *testing.T, s *Server) gopherJSON {
+	w := do(s, "POST", apiPrefix, strings.NewReader(testRecipe), "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create gave %v: %v", w.Code, w.Body)
+	}
+
+	var g gopherJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &g); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestEditToken(t *testing.T) {
+	s := newTestServer(store.NewMemory())
+	g := create(t, s)
+	other := create(t, s)
+
+	p := apiPrefix + "/" + g.ID
+	edit := "body=pink_gopher eyes=eyes"
+
+	tests := []struct {
+		method, token string
+		want          int
+	}{
+		{"PUT", "", http.StatusUnauthorized},
+		{"PUT", "not-the-token", http.StatusForbidden},
+		{"PUT", other.EditToken, http.StatusForbidden},
+		{"DELETE", "", http.StatusUnauthorized},
+		{"DELETE", "not-the-token", http.StatusForbidden},
+		{"PUT", g.EditToken, http.StatusOK},
+		{"DELETE", g.EditToken, http.StatusNoContent},
+		{"GET", "", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		w := do(s, test.method, p, strings.NewReader(edit), test.token)
+		if w.Code != test.want {
+			t.Errorf("%v with token %q gave %v; want %v: %v", test.method, test.token, w.Code, test.want, w.Body)
+		}
+	}
+
+	// the gopher of the other token is untouched
+	if w := do(s, "GET", apiPrefix+"/"+other.ID, nil, ""); w.Code != http.StatusOK {
+		t.Errorf("get of other gopher gave %v", w.Code)
+	}
+}
+
+func TestEditTokenHeader(t *testing.T) {
+	s := newTestServer(store.NewMemory())
+	g := create(t, s)
+
+	r := httptest.NewRequest("DELETE", apiPrefix+"/"+g.ID, nil)
+	r.Header.Set("Authorization", "Basic "+g.EditToken)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("delete with a Basic token gave %v; want %v", w.Code, http.StatusUnauthorized)
+	}
+}
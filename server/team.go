@@ -0,0 +1,551 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"image"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"myitcv.io/gopherize.me/export"
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/store"
+)
+
+const (
+	// MaxTeamSize is the largest number of members of a team.
+	MaxTeamSize = 200
+
+	// MaxNameLength is the longest name, in characters, of a team or
+	// member.
+	MaxNameLength = 100
+
+	// MaxPhotoSize is the largest width and height in pixels of the photo
+	// of a team.
+	MaxPhotoSize = 8192
+
+	// maxTeamRequest is the largest request, in bytes, that creates or
+	// updates a team.
+	maxTeamRequest = 64 << 10
+
+	// maxPhotoCache is the most, in bytes, of drawn team photos that are
+	// kept.
+	maxPhotoCache = 64 << 20
+)
+
+const (
+	teamsPrefix    = "/api/teams"
+	teamPagePrefix = "/teams/"
+
+	// teamPrefix is the prefix of the keys under which teams are stored,
+	// followed by their IDs.
+	teamPrefix = "teams/"
+)
+
+// Team is a named collection of saved gophers, as stored.
+type Team struct {
+	// ID identifies the team; IDs sort in the order in which teams were
+	// created.
+	ID string
+
+	Created time.Time
+	Updated time.Time
+
+	Name    string
+	Members []Member
+
+	// TokenHash is the hash of the edit token of the team; see hashToken.
+	TokenHash string
+}
+
+// Member is a member of a team, a name and the ID of a saved gopher.
+type Member struct {
+	Name   string
+	Gopher string
+}
+
+// teamJSON is the form in which a team is returned by the API.
+type teamJSON struct {
+	ID      string
+	Created time.Time
+	Updated time.Time
+	Name    string
+	Members []memberJSON
+	Photo   string
+	Page    string
+
+	// EditToken is only returned when a team is first created.
+	EditToken string `json:",omitempty"`
+}
+
+type memberJSON struct {
+	Name      string
+	Gopher    string
+	Thumbnail string
+}
+
+// teamListJSON is the form in which a page of teams is returned by the API.
+type teamListJSON struct {
+	Teams []teamJSON
+	Next  string `json:",omitempty"`
+}
+
+func (s *Server) serveTeams(w http.ResponseWriter, r *http.Request) error {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, teamsPrefix), "/"), "/")
+
+	switch {
+	case parts[0] == "":
+		switch r.Method {
+		case "GET", "HEAD":
+			return s.listTeams(w, r)
+		case "POST":
+			return s.createTeam(w, r)
+		}
+		return methodNotAllowed(w, "GET, HEAD, POST")
+
+	case len(parts) == 1:
+		switch r.Method {
+		case "GET", "HEAD":
+			return s.getTeam(w, parts[0])
+		case "PUT":
+			return s.updateTeam(w, r, parts[0])
+		case "DELETE":
+			return s.deleteTeam(w, r, parts[0])
+		}
+		return methodNotAllowed(w, "GET, HEAD, PUT, DELETE")
+
+	case len(parts) == 2 && parts[1] == "photo.png":
+		if r.Method != "GET" && r.Method != "HEAD" {
+			return methodNotAllowed(w, "GET, HEAD")
+		}
+		return s.teamPhoto(w, r, parts[0])
+	}
+
+	return errorf(http.StatusNotFound, "not found")
+}
+
+// readTeam returns the name and members of the team described by the body
+// of r, checking that each member is a saved gopher.
+func (s *Server) readTeam(w http.ResponseWriter, r *http.Request) (string, []Member, error) {
+	b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxTeamRequest))
+	if err != nil {
+		return "", nil, errorf(http.StatusRequestEntityTooLarge, "team larger than %v bytes", maxTeamRequest)
+	}
+
+	var t struct {
+		Name    string
+		Members []Member
+	}
+	if err := json.Unmarshal(b, &t); err != nil {
+		return "", nil, errorf(http.StatusBadRequest, "invalid team: %v", err)
+	}
+
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return "", nil, errorf(http.StatusBadRequest, "invalid team: no name")
+	}
+	if len([]rune(t.Name)) > MaxNameLength {
+		return "", nil, errorf(http.StatusBadRequest, "invalid team: name longer than %v characters", MaxNameLength)
+	}
+	if len(t.Members) == 0 || len(t.Members) > MaxTeamSize {
+		return "", nil, errorf(http.StatusBadRequest, "invalid team: %v members; expected 1 to %v", len(t.Members), MaxTeamSize)
+	}
+
+	for i := range t.Members {
+		m := &t.Members[i]
+		m.Name = strings.TrimSpace(m.Name)
+
+		if len([]rune(m.Name)) > MaxNameLength {
+			return "", nil, errorf(http.StatusBadRequest, "invalid team: name of member %v longer than %v characters", i+1, MaxNameLength)
+		}
+		if _, err := s.load(gopherPrefix, m.Gopher); err != nil {
+			if he, ok := err.(httpError); ok {
+				return "", nil, errorf(http.StatusBadRequest, "invalid team: member %v: %v", i+1, he.msg)
+			}
+			return "", nil, err
+		}
+	}
+
+	return t.Name, t.Members, nil
+}
+
+func (s *Server) createTeam(w http.ResponseWriter, r *http.Request) error {
+	name, members, err := s.readTeam(w, r)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	id, err := newID(now)
+	if err != nil {
+		return err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	t := Team{
+		ID:        id,
+		Created:   now,
+		Updated:   now,
+		Name:      name,
+		Members:   members,
+		TokenHash: hashToken(token),
+	}
+
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.store.Put(teamPrefix+id, b, store.NoVersion); err != nil {
+		return err
+	}
+
+	res := teamToJSON(t)
+	res.EditToken = token
+
+	w.Header().Set("Location", teamsPrefix+"/"+id)
+	writeJSON(w, http.StatusCreated, res)
+
+	return nil
+}
+
+// loadTeam returns the stored record and team with the given id.
+func (s *Server) loadTeam(id string) (store.Record, Team, error) {
+	if !validID(id) {
+		return store.Record{}, Team{}, errorf(http.StatusNotFound, "no team %v", id)
+	}
+
+	r, err := s.store.Get(teamPrefix + id)
+	if store.IsNotFound(err) {
+		return store.Record{}, Team{}, errorf(http.StatusNotFound, "no team %v", id)
+	}
+	if err != nil {
+		return store.Record{}, Team{}, err
+	}
+
+	t, err := decodeTeam(r)
+	if err != nil {
+		return store.Record{}, Team{}, err
+	}
+
+	return r, t, nil
+}
+
+func decodeTeam(r store.Record) (Team, error) {
+	var t Team
+	if err := json.Unmarshal(r.Value, &t); err != nil {
+		return Team{}, fmt.Errorf("corrupt team %v: %v", r.Key, err)
+	}
+	return t, nil
+}
+
+func (s *Server) getTeam(w http.ResponseWriter, id string) error {
+	_, t, err := s.loadTeam(id)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, teamToJSON(t))
+
+	return nil
+}
+
+func (s *Server) updateTeam(w http.ResponseWriter, r *http.Request, id string) error {
+	rec, t, err := s.loadTeam(id)
+	if err != nil {
+		return err
+	}
+	if err := checkToken(r, t.TokenHash, "team "+id); err != nil {
+		return err
+	}
+
+	t.Name, t.Members, err = s.readTeam(w, r)
+	if err != nil {
+		return err
+	}
+	t.Updated = time.Now().UTC()
+
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.store.Put(teamPrefix+id, b, rec.Version)
+	if store.IsConflict(err) {
+		return errorf(http.StatusConflict, "team %v was changed concurrently; try again", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, teamToJSON(t))
+
+	return nil
+}
+
+func (s *Server) deleteTeam(w http.ResponseWriter, r *http.Request, id string) error {
+	rec, t, err := s.loadTeam(id)
+	if err != nil {
+		return err
+	}
+	if err := checkToken(r, t.TokenHash, "team "+id); err != nil {
+		return err
+	}
+
+	err = s.store.Delete(teamPrefix+id, rec.Version)
+	if store.IsConflict(err) {
+		return errorf(http.StatusConflict, "team %v was changed concurrently; try again", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (s *Server) listTeams(w http.ResponseWriter, r *http.Request) error {
+	rs, limit, err := s.page(r, teamPrefix)
+	if err != nil {
+		return err
+	}
+
+	res := teamListJSON{Teams: []teamJSON{}}
+	for _, r := range rs {
+		t, err := decodeTeam(r)
+		if err != nil {
+			return err
+		}
+		res.Teams = append(res.Teams, teamToJSON(t))
+	}
+	if len(res.Teams) == limit {
+		res.Next = res.Teams[limit-1].ID
+	}
+
+	writeJSON(w, http.StatusOK, res)
+
+	return nil
+}
+
+// members returns the members of t whose gophers are still saved, with
+// their gophers, and the IDs and versions of those gophers, which identify
+// what is drawn. Gophers deleted since they joined the team are skipped.
+func (s *Server) members(t Team) ([]export.MosaicMember, string, error) {
+	var res []export.MosaicMember
+	var versions []string
+
+	for _, m := range t.Members {
+		r, err := s.store.Get(gopherPrefix + m.Gopher)
+		if store.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		saved, err := decode(r)
+		if err != nil {
+			return nil, "", err
+		}
+
+		g, err := gopher.UnmarshalRecipe(saved.Recipe, s.config)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid recipe of gopher %v: %v", m.Gopher, err)
+		}
+
+		res = append(res, export.MosaicMember{Name: m.Name, Gopher: g})
+		versions = append(versions, fmt.Sprintf("%v@%v", m.Gopher, r.Version))
+	}
+
+	return res, strings.Join(versions, ","), nil
+}
+
+// The values allowed for the layout of a team photo, few enough that the
+// photos of a team that can be drawn are few too, and none of them slow.
+var (
+	photoColumns  = []int{1, 2, 3, 4, 5, 6, 8, 10}
+	photoWidths   = []int{64, 128, 256, 512}
+	photoSpacings = []int{0, 8, 16, 32}
+)
+
+// photoOptions returns the options of the photo of a team given by the query
+// parameters of r.
+func photoOptions(r *http.Request) (export.MosaicOptions, error) {
+	o := export.DefaultMosaicOptions
+	q := r.URL.Query()
+
+	ints := []struct {
+		name    string
+		v       *int
+		allowed []int
+	}{
+		{"columns", &o.Columns, photoColumns},
+		{"width", &o.Width, photoWidths},
+		{"spacing", &o.Spacing, photoSpacings},
+	}
+	for _, p := range ints {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || !containsInt(p.allowed, n) {
+			return o, errorf(http.StatusBadRequest, "invalid %v %q; expected one of %v", p.name, v, p.allowed)
+		}
+		*p.v = n
+	}
+
+	switch v := q.Get("background"); v {
+	case "":
+	case "none":
+		o.Background = nil
+	default:
+		c, err := gopher.ParseColour(v)
+		if err != nil {
+			return o, errorf(http.StatusBadRequest, "invalid background %q: %v", v, err)
+		}
+		o.Background = c
+	}
+
+	if v := q.Get("captions"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return o, errorf(http.StatusBadRequest, "invalid captions %q; expected true or false", v)
+		}
+		o.Captions = b
+	}
+
+	return o, nil
+}
+
+func containsInt(vs []int, v int) bool {
+	for _, w := range vs {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) teamPhoto(w http.ResponseWriter, r *http.Request, id string) error {
+	rec, t, err := s.loadTeam(id)
+	if err != nil {
+		return err
+	}
+
+	o, err := photoOptions(r)
+	if err != nil {
+		return err
+	}
+
+	ms, versions, err := s.members(t)
+	if err != nil {
+		return err
+	}
+	if len(ms) == 0 {
+		return errorf(http.StatusNotFound, "team %v has no saved gophers", id)
+	}
+
+	if width, height, _ := export.MosaicSize(len(ms), o); width > MaxPhotoSize || height > MaxPhotoSize {
+		return errorf(http.StatusBadRequest, "photo of %vx%v pixels is larger than %vx%v", width, height, MaxPhotoSize, MaxPhotoSize)
+	}
+
+	// the photo changes only with the team, its gophers and the options
+	key := fmt.Sprintf("%v@%v %v %v", id, rec.Version, versions, mosaicKey(o))
+
+	b, err := s.draw(r, s.photos, key, func() (image.Image, error) {
+		return export.Mosaic(ms, s.art, o)
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.Write(b)
+
+	return nil
+}
+
+var teamPageTmpl = template.Must(template.New("team").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.members { display: flex; flex-wrap: wrap; gap: 16px; }
+.member { width: 200px; text-align: center; }
+.member img { width: 200px; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<div class="members">
+{{range .Members}}<div class="member"><img src="{{.Thumbnail}}" alt=""><div>{{.Name}}</div></div>
+{{end}}</div>
+<p><a href="{{.Photo}}">Team photo</a></p>
+</body>
+</html>
+`))
+
+func (s *Server) teamPage(w http.ResponseWriter, id string) error {
+	_, t, err := s.loadTeam(id)
+	if err != nil {
+		return err
+	}
+
+	// leave out the members whose gophers have been deleted
+	live := t
+	live.Members = nil
+	for _, m := range t.Members {
+		if _, err := s.store.Get(gopherPrefix + m.Gopher); err == nil {
+			live.Members = append(live.Members, m)
+		} else if !store.IsNotFound(err) {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := teamPageTmpl.Execute(&buf, teamToJSON(live)); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+
+	return nil
+}
+
+func teamToJSON(t Team) teamJSON {
+	res := teamJSON{
+		ID:      t.ID,
+		Created: t.Created,
+		Updated: t.Updated,
+		Name:    t.Name,
+		Members: []memberJSON{},
+		Photo:   teamsPrefix + "/" + t.ID + "/photo.png",
+		Page:    teamPagePrefix + t.ID,
+	}
+
+	for _, m := range t.Members {
+		res.Members = append(res.Members, memberJSON{
+			Name:      m.Name,
+			Gopher:    m.Gopher,
+			Thumbnail: apiPrefix + "/" + m.Gopher + "/thumbnail.png",
+		})
+	}
+
+	return res
+}
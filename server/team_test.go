@@ -0,0 +1,70 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"myitcv.io/gopherize.me/store"
+)
+
+func TestTeamPhotoCache(t *testing.T) {
+	s := newTestServer(store.NewMemory())
+	g1, g2 := create(t, s), create(t, s)
+
+	body := fmt.Sprintf(`{"Name": "Gophers", "Members": [{"Name": "One", "Gopher": %q}, {"Name": "Two", "Gopher": %q}]}`, g1.ID, g2.ID)
+	w := do(s, "POST", teamsPrefix, strings.NewReader(body), "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create team gave %v: %v", w.Code, w.Body)
+	}
+	var team teamJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &team); err != nil {
+		t.Fatal(err)
+	}
+
+	photo := func(query string) []byte {
+		w := do(s, "GET", team.Photo+query, nil, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("photo gave %v: %v", w.Code, w.Body)
+		}
+		return w.Body.Bytes()
+	}
+
+	p1 := photo("?width=64")
+	if n := s.photos.order.Len(); n != 1 {
+		t.Fatalf("%v photos cached; want 1", n)
+	}
+
+	// the same photo, asked for again, is not drawn again
+	if p := photo("?width=64&junk=1"); !bytes.Equal(p, p1) || s.photos.order.Len() != 1 {
+		t.Errorf("second request drew the photo again")
+	}
+
+	// other options draw another photo
+	photo("?width=64&captions=false")
+	if n := s.photos.order.Len(); n != 2 {
+		t.Errorf("%v photos cached; want 2", n)
+	}
+
+	// options other than those allowed draw nothing
+	for _, q := range []string{"?width=32", "?columns=200", "?spacing=1024"} {
+		if w := do(s, "GET", team.Photo+q, nil, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("photo%v gave %v", q, w.Code)
+		}
+	}
+	if n := s.photos.order.Len(); n != 2 {
+		t.Errorf("%v photos cached; want 2", n)
+	}
+
+	// a change to a gopher of the team draws another photo too
+	w = do(s, "PUT", apiPrefix+"/"+g2.ID, strings.NewReader("body=pink_gopher eyes=eyes"), g2.EditToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update gave %v: %v", w.Code, w.Body)
+	}
+	if p := photo("?width=64"); bytes.Equal(p, p1) {
+		t.Errorf("photo unchanged after a gopher of the team changed")
+	}
+}
@@ -0,0 +1,54 @@
+package site
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Compile compiles the client with the gopherjs command, minifying it if
+// minify is set, and returns the compiled JavaScript, without its source
+// map. The environment, and so GOPATH, is that of the current process.
+func Compile(gopherjs string, minify bool) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "gopherize-site")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, ClientJS)
+
+	args := []string{"build", "-o", out}
+	if minify {
+		args = append(args, "-m")
+	}
+	args = append(args, ClientPackage)
+
+	cmd := exec.Command(gopherjs, args...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to compile client: %v\n%s", err, output.Bytes())
+	}
+
+	js, err := ioutil.ReadFile(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return trimSourceMap(js), nil
+}
+
+// trimSourceMap removes the reference to its source map that ends js, the
+// output of GopherJS; the source map is not part of the site.
+func trimSourceMap(js []byte) []byte {
+	t := bytes.TrimRight(js, "\n")
+	if i := bytes.LastIndexByte(t, '\n'); i != -1 && bytes.HasPrefix(t[i+1:], []byte("//# sourceMappingURL=")) {
+		return t[:i+1]
+	}
+	return js
+}
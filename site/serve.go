@@ -0,0 +1,268 @@
+package site
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contentTypes are the types of the files of a site, which are not left to
+// the tables of the system.
+var contentTypes = map[string]string{
+	".css":   "text/css; charset=utf-8",
+	".eot":   "application/vnd.ms-fontobject",
+	".html":  "text/html; charset=utf-8",
+	".ico":   "image/x-icon",
+	".js":    "application/javascript; charset=utf-8",
+	".json":  "application/json",
+	".png":   "image/png",
+	".svg":   "image/svg+xml",
+	".ttf":   "font/ttf",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+}
+
+// compressible lists the extensions of files that are worth compressing;
+// images and most fonts are compressed already.
+var compressible = map[string]bool{
+	".css":  true,
+	".eot":  true,
+	".html": true,
+	".ico":  true,
+	".js":   true,
+	".json": true,
+	".svg":  true,
+	".ttf":  true,
+}
+
+// Cache-Control headers of fingerprinted assets, which never change, of
+// artwork, and of anything else, the page in particular.
+const (
+	immutable   = "public, max-age=31536000, immutable"
+	artworkAge  = "public, max-age=86400"
+	revalidated = "no-cache"
+)
+
+// Handler serves the site built in fsys. Fingerprinted assets, as listed by
+// its manifest, if any, are cached by clients for good; the rest are
+// revalidated. Text is gzipped for clients that accept it.
+func Handler(fsys http.FileSystem) http.Handler {
+	return &handler{
+		fsys:  fsys,
+		files: make(map[string]*file),
+	}
+}
+
+type handler struct {
+	fsys http.FileSystem
+
+	mu       sync.Mutex
+	manifest Manifest
+	manTime  time.Time
+	files    map[string]*file
+}
+
+// file is what is computed for serving a file of a site: its ETag and, if
+// it is compressible, its gzipped contents. They are computed once, outside
+// the lock of the handler, and again only if the size or modification time
+// of the file change.
+type file struct {
+	size    int64
+	modTime time.Time
+
+	once sync.Once
+	err  error
+	etag string
+	gz   []byte
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = Page
+	}
+
+	sf, err := h.fsys.Open("/" + name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer sf.Close()
+
+	fi, err := sf.Stat()
+	if err != nil || fi.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, fp := h.file(name, fi)
+
+	f.once.Do(func() {
+		f.err = f.compute(name, sf)
+	})
+	if f.err != nil {
+		h.forget(name, f)
+		http.Error(w, "failed to read "+name, http.StatusInternalServerError)
+		return
+	}
+
+	ext := path.Ext(name)
+	ct, ok := contentTypes[ext]
+	if !ok {
+		ct = mime.TypeByExtension(ext)
+	}
+	if ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+
+	switch {
+	case fp:
+		w.Header().Set("Cache-Control", immutable)
+	case strings.HasPrefix(name, "artwork/"):
+		w.Header().Set("Cache-Control", artworkAge)
+	default:
+		w.Header().Set("Cache-Control", revalidated)
+	}
+
+	var content io.ReadSeeker = sf
+	etag := f.etag
+	if compressible[ext] {
+		w.Header().Set("Vary", "Accept-Encoding")
+		if acceptsGzip(r) {
+			w.Header().Set("Content-Encoding", "gzip")
+			content = bytes.NewReader(f.gz)
+			etag += "-gzip"
+		}
+	}
+	w.Header().Set("ETag", `"`+etag+`"`)
+
+	http.ServeContent(w, r, name, fi.ModTime(), content)
+}
+
+// stat returns the FileInfo of the file name of the site.
+func (h *handler) stat(name string) (os.FileInfo, error) {
+	f, err := h.fsys.Open("/" + name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return f.Stat()
+}
+
+// file returns the served form of the file name, described by fi, and
+// whether it is fingerprinted.
+func (h *handler) file(name string, fi os.FileInfo) (*file, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.loadManifest()
+
+	f, ok := h.files[name]
+	if !ok || f.size != fi.Size() || !f.modTime.Equal(fi.ModTime()) {
+		f = &file{
+			size:    fi.Size(),
+			modTime: fi.ModTime(),
+		}
+		h.files[name] = f
+	}
+
+	return f, h.manifest.Fingerprinted(name)
+}
+
+// forget discards f, the served form of the file name, so that it is
+// computed again by the next request for it.
+func (h *handler) forget(name string, f *file) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.files[name] == f {
+		delete(h.files, name)
+	}
+}
+
+// compute reads the file name from sf, leaving sf at its start, to compute
+// the served form f of it.
+func (f *file) compute(name string, sf http.File) error {
+	b, err := ioutil.ReadAll(sf)
+	if err != nil {
+		return err
+	}
+	if _, err := sf.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(b)
+	f.etag = hex.EncodeToString(sum[:8])
+	if compressible[path.Ext(name)] {
+		f.gz = compress(b)
+	}
+
+	return nil
+}
+
+// loadManifest reads the manifest of the site, when it first exists and
+// whenever it changes.
+func (h *handler) loadManifest() {
+	fi, err := h.stat(ManifestFile)
+	if err != nil {
+		h.manifest = nil
+		return
+	}
+	if h.manifest != nil && fi.ModTime().Equal(h.manTime) {
+		return
+	}
+
+	m, err := ReadManifest(h.fsys)
+	if err != nil {
+		return
+	}
+
+	h.manifest, h.manTime = m, fi.ModTime()
+}
+
+func compress(b []byte) []byte {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		panic(fmt.Errorf("failed to create gzip writer: %v", err))
+	}
+	zw.Write(b)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// acceptsGzip reports whether the client making r accepts gzipped
+// responses.
+func acceptsGzip(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		v = strings.TrimSpace(v)
+		if i := strings.IndexByte(v, ';'); i != -1 {
+			if q := strings.TrimSpace(v[i+1:]); q == "q=0" || q == "q=0.0" {
+				continue
+			}
+			v = strings.TrimSpace(v[:i])
+		}
+		if v == "gzip" {
+			return true
+		}
+	}
+	return false
+}
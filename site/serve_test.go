@@ -0,0 +1,164 @@
+package site
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip", true},
+		{"gzip;q=0.5", true},
+		{" gzip ; q=1.0 ", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0.0", false},
+		{"deflate, br", false},
+		{"gzipped", false},
+		{"*", false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.header != "" {
+			r.Header.Set("Accept-Encoding", test.header)
+		}
+		if got := acceptsGzip(r); got != test.want {
+			t.Errorf("acceptsGzip with Accept-Encoding %q = %v; want %v", test.header, got, test.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		Page:                   "<html></html>",
+		"client.0123456789.js": "main();",
+		"artwork/a/b.png":      "png",
+		ManifestFile:           `{"client.js": "client.0123456789.js"}`,
+	}
+	for name, c := range files {
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := Handler(http.Dir(dir))
+
+	tests := []struct {
+		method, path string
+		code         int
+		contentType  string
+		cacheControl string
+		body         string
+	}{
+		{"GET", "/", 200, "text/html; charset=utf-8", revalidated, "<html></html>"},
+		{"GET", "/" + Page, 200, "text/html; charset=utf-8", revalidated, "<html></html>"},
+		{"GET", "/client.0123456789.js", 200, "application/javascript; charset=utf-8", immutable, "main();"},
+		{"GET", "/artwork/a/b.png", 200, "image/png", artworkAge, "png"},
+		{"HEAD", "/artwork/a/b.png", 200, "image/png", artworkAge, ""},
+		{"GET", "/artwork", 404, "", "", ""},
+		{"GET", "/missing.js", 404, "", "", ""},
+		{"GET", "/../" + filepath.Base(dir) + "/" + Page, 404, "", "", ""},
+		{"POST", "/", 405, "", "", ""},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+
+		if w.Code != test.code {
+			t.Errorf("%v %v gave %v; want %v", test.method, test.path, w.Code, test.code)
+			continue
+		}
+		if w.Code != 200 {
+			continue
+		}
+		if ct := w.Header().Get("Content-Type"); ct != test.contentType {
+			t.Errorf("%v %v gave Content-Type %q; want %q", test.method, test.path, ct, test.contentType)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != test.cacheControl {
+			t.Errorf("%v %v gave Cache-Control %q; want %q", test.method, test.path, cc, test.cacheControl)
+		}
+		if b := w.Body.String(); b != test.body {
+			t.Errorf("%v %v gave %q; want %q", test.method, test.path, b, test.body)
+		}
+	}
+}
+
+func TestHandlerGzip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	page := bytes.Repeat([]byte("<p>gopher</p>"), 100)
+	if err := writeFile(filepath.Join(dir, Page), page); err != nil {
+		t.Fatal(err)
+	}
+
+	h := Handler(http.Dir(dir))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if ce := w.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("gave Content-Encoding %q; want gzip", ce)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, page) {
+		t.Errorf("gzipped page is %q; want %q", b, page)
+	}
+	gzETag := w.Header().Get("ETag")
+
+	// revalidation with the ETag of the gzipped page
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("If-None-Match", gzETag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("revalidation gave %v; want %v", w.Code, http.StatusNotModified)
+	}
+
+	// a client that does not accept gzip has the page as is, with another
+	// ETag
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if ce := w.Header().Get("Content-Encoding"); ce != "" {
+		t.Errorf("gave Content-Encoding %q without Accept-Encoding", ce)
+	}
+	if !bytes.Equal(w.Body.Bytes(), page) {
+		t.Errorf("page is %q; want %q", w.Body.Bytes(), page)
+	}
+	if etag := w.Header().Get("ETag"); etag == gzETag {
+		t.Errorf("page and gzipped page have the same ETag %v", etag)
+	}
+}
@@ -0,0 +1,363 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// Package site builds the static site of gopherize.me: the compiled client,
+// the page that loads it, its stylesheets and scripts, and the artwork.
+//
+// The names of the assets loaded by the page are fingerprinted with a hash
+// of their contents, as in client.0123456789.js, so that they can be cached
+// forever; the page itself, and the artwork, which the client loads by name,
+// keep their names. The assets renamed are listed in the manifest written
+// alongside them. Building the same sources gives the same files, so that
+// sites can be diffed between releases.
+//
+// Handler serves a built site, caching what can be cached.
+package site
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// ClientPackage is the import path of the client compiled by GopherJS.
+	ClientPackage = "myitcv.io/gopherize.me/client"
+
+	// Page is the name of the page that loads the client.
+	Page = "index.html"
+
+	// ManifestFile is the name of the manifest of a built site.
+	ManifestFile = "manifest.json"
+
+	// ClientJS is the name under which the page loads the compiled client.
+	ClientJS = "client.js"
+
+	// StampFile is the name of the empty file by which Build marks a
+	// directory as holding a site it built, and so one it may replace.
+	StampFile = ".gopherize-site"
+)
+
+// Manifest maps the names of the fingerprinted assets of a site to the names
+// under which they are written, slash separated and relative to the root of
+// the site.
+type Manifest map[string]string
+
+// Fingerprinted reports whether the file name, as found in the built site,
+// is a fingerprinted asset.
+func (m Manifest) Fingerprinted(name string) bool {
+	for _, v := range m {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadManifest reads the manifest of the site built in fsys.
+func ReadManifest(fsys http.FileSystem) (Manifest, error) {
+	f, err := fsys.Open("/" + ManifestFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %v: %v", ManifestFile, err)
+	}
+
+	return m, nil
+}
+
+// Options describe the sources of a site.
+type Options struct {
+	// Client is the directory of the client package, holding the page, its
+	// stylesheet and the inc directory of third party assets.
+	Client string
+
+	// Artwork is the artwork directory.
+	Artwork string
+
+	// JS is the client compiled by GopherJS, as returned by Compile; any
+	// reference to a source map is dropped.
+	JS []byte
+}
+
+// Build writes the site described by o to the directory out, which must
+// either not exist, be empty but for hidden files such as .git, or hold a
+// site previously built by Build, as marked by StampFile, whose files are
+// replaced.
+func Build(out string, o Options) (Manifest, error) {
+	if err := clean(out); err != nil {
+		return nil, err
+	}
+	// stamped before anything is written, so that a site only partly built
+	// is replaced by the next Build too
+	if err := writeFile(filepath.Join(out, StampFile), nil); err != nil {
+		return nil, err
+	}
+
+	files, err := readClient(o.Client)
+	if err != nil {
+		return nil, err
+	}
+	files[ClientJS] = trimSourceMap(o.JS)
+
+	b := &builder{files: files, manifest: make(Manifest)}
+
+	page, ok := files[Page]
+	if !ok {
+		return nil, fmt.Errorf("%v has no %v", o.Client, Page)
+	}
+	files[Page] = b.rewrite(Page, page, htmlRef)
+
+	for _, name := range b.names() {
+		if err := writeFile(filepath.Join(out, filepath.FromSlash(name)), files[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := copyDir(filepath.Join(out, "artwork"), o.Artwork); err != nil {
+		return nil, err
+	}
+
+	m, err := json.MarshalIndent(b.manifest, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(out, ManifestFile), append(m, '\n')); err != nil {
+		return nil, err
+	}
+
+	return b.manifest, nil
+}
+
+// readClient returns the contents of the files of the client directory dir
+// that are part of the site, keyed by their slash separated names: the page,
+// client.css and the inc directory.
+func readClient(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	for _, name := range []string{Page, "client.css"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		files[name] = b
+	}
+
+	inc := filepath.Join(dir, "inc")
+	err := filepath.Walk(inc, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = b
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// builder fingerprints the assets of a site.
+type builder struct {
+	files    map[string][]byte
+	manifest Manifest
+}
+
+// names returns the names of the files of b in order.
+func (b *builder) names() []string {
+	var res []string
+	for name := range b.files {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+var (
+	// htmlRef matches the src and href attributes of a page, and cssRef
+	// the url() references of a stylesheet; the reference is the last
+	// submatch.
+	htmlRef = regexp.MustCompile(`\b(src|href)="([^"]*)"`)
+	cssRef  = regexp.MustCompile(`\burl\(\s*['"]?([^'")]*)['"]?\s*\)`)
+)
+
+// rewrite returns c, the contents of the file name, with each reference
+// matched by re to a file of the site replaced by a reference to its
+// fingerprinted name.
+func (b *builder) rewrite(name string, c []byte, re *regexp.Regexp) []byte {
+	return re.ReplaceAllFunc(c, func(m []byte) []byte {
+		sub := re.FindSubmatchIndex(m)
+		start, end := sub[len(sub)-2], sub[len(sub)-1]
+		ref := string(m[start:end])
+
+		// keep any query or fragment, as in font.eot?#iefix
+		target, suffix := ref, ""
+		if i := strings.IndexAny(ref, "?#"); i != -1 {
+			target, suffix = ref[:i], ref[i:]
+		}
+		if target == "" || strings.Contains(target, ":") || strings.HasPrefix(target, "/") {
+			return m
+		}
+
+		p := path.Join(path.Dir(name), target)
+		fp, ok := b.fingerprint(p)
+		if !ok {
+			return m
+		}
+
+		rel := relPath(path.Dir(name), fp)
+
+		var res []byte
+		res = append(res, m[:start]...)
+		res = append(res, rel+suffix...)
+		res = append(res, m[end:]...)
+		return res
+	})
+}
+
+// fingerprint renames the file name, and any assets it refers to, to their
+// fingerprinted names, returning the new name of name and whether it is a
+// file of the site.
+func (b *builder) fingerprint(name string) (string, bool) {
+	if fp, ok := b.manifest[name]; ok {
+		return fp, true
+	}
+
+	c, ok := b.files[name]
+	if !ok {
+		return "", false
+	}
+
+	if path.Ext(name) == ".css" {
+		c = b.rewrite(name, c, cssRef)
+	}
+
+	sum := sha256.Sum256(c)
+	ext := path.Ext(name)
+	fp := strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:5]) + ext
+
+	delete(b.files, name)
+	b.files[fp] = c
+	b.manifest[name] = fp
+
+	return fp, true
+}
+
+// relPath returns the slash separated path of target relative to the
+// directory dir, both relative to the root of the site.
+func relPath(dir, target string) string {
+	if dir == "." {
+		return target
+	}
+
+	ds := strings.Split(dir, "/")
+	ts := strings.Split(target, "/")
+
+	i := 0
+	for i < len(ds) && i < len(ts)-1 && ds[i] == ts[i] {
+		i++
+	}
+
+	return strings.Repeat("../", len(ds)-i) + strings.Join(ts[i:], "/")
+}
+
+// clean prepares the directory out for a site to be written to it, removing
+// all but the hidden files of a site built before. A directory that is not
+// stamped by Build is left alone, even if it looks like a site.
+func clean(out string) error {
+	fis, err := ioutil.ReadDir(out)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var old []string
+	built := false
+	for _, fi := range fis {
+		if fi.Name() == StampFile {
+			built = true
+		}
+		if strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		old = append(old, fi.Name())
+	}
+
+	if len(old) > 0 && !built {
+		return fmt.Errorf("%v is not empty, and holds no site", out)
+	}
+
+	for _, name := range old {
+		if err := os.RemoveAll(filepath.Join(out, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyDir copies the regular files of the directory src, whose symbolic
+// links are followed, to dst.
+func copyDir(dst, src string) error {
+	src, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return err
+	}
+
+	return filepath.Walk(src, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		return writeFile(filepath.Join(dst, rel), b)
+	})
+}
+
+func writeFile(p string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p, b, 0666)
+}
@@ -0,0 +1,286 @@
+package site
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fingerprinted returns the fingerprinted name of the file name whose
+// contents, once rewritten, are c.
+func fingerprinted(name, c string) string {
+	sum := sha256.Sum256([]byte(c))
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:5]) + ext
+}
+
+func TestRelPath(t *testing.T) {
+	tests := []struct {
+		dir, target, want string
+	}{
+		{".", "client.css", "client.css"},
+		{".", "inc/a/b.css", "inc/a/b.css"},
+		{"inc", "inc/b.css", "b.css"},
+		{"inc/a", "inc/a/b.css", "b.css"},
+		{"inc/a", "inc/fonts/f.woff", "../fonts/f.woff"},
+		{"inc/a/b", "inc/fonts/f.woff", "../../fonts/f.woff"},
+		{"inc/a", "client.css", "../../client.css"},
+		{"inc", "incs/x.css", "../incs/x.css"},
+	}
+
+	for _, test := range tests {
+		if got := relPath(test.dir, test.target); got != test.want {
+			t.Errorf("relPath(%q, %q) = %q; want %q", test.dir, test.target, got, test.want)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	const font = "font"
+	css := `@font-face { src: url('../fonts/f.woff?v=1#x'); }`
+	cssOut := `@font-face { src: url('../fonts/` + path.Base(fingerprinted("inc/fonts/f.woff", font)) + `?v=1#x'); }`
+
+	b := &builder{
+		files: map[string][]byte{
+			"inc/css/a.css":    []byte(css),
+			"inc/fonts/f.woff": []byte(font),
+		},
+		manifest: make(Manifest),
+	}
+
+	got, ok := b.fingerprint("inc/css/a.css")
+	if want := fingerprinted("inc/css/a.css", cssOut); !ok || got != want {
+		t.Fatalf("fingerprint = %q, %v; want %q, true", got, ok, want)
+	}
+
+	wantFiles := map[string][]byte{
+		fingerprinted("inc/css/a.css", cssOut):  []byte(cssOut),
+		fingerprinted("inc/fonts/f.woff", font): []byte(font),
+	}
+	if !reflect.DeepEqual(b.files, wantFiles) {
+		t.Errorf("files = %q; want %q", b.files, wantFiles)
+	}
+
+	wantManifest := Manifest{
+		"inc/css/a.css":    fingerprinted("inc/css/a.css", cssOut),
+		"inc/fonts/f.woff": fingerprinted("inc/fonts/f.woff", font),
+	}
+	if !reflect.DeepEqual(b.manifest, wantManifest) {
+		t.Errorf("manifest = %v; want %v", b.manifest, wantManifest)
+	}
+
+	// a second reference gives the same name, without renaming again
+	if again, ok := b.fingerprint("inc/css/a.css"); !ok || again != got {
+		t.Errorf("second fingerprint = %q, %v; want %q, true", again, ok, got)
+	}
+
+	if fp, ok := b.fingerprint("missing.js"); ok {
+		t.Errorf("fingerprint of a missing file = %q, true; want false", fp)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	const js, png = "js", "png"
+
+	tests := []struct {
+		name, in, want string
+	}{
+		{
+			"index.html",
+			`<script src="client.js"></script>`,
+			`<script src="` + fingerprinted("client.js", js) + `"></script>`,
+		},
+		{
+			"index.html",
+			`<link href="inc/i.png">`,
+			`<link href="` + fingerprinted("inc/i.png", png) + `">`,
+		},
+		{
+			"inc/x.html",
+			`<img src="i.png?x">`,
+			`<img src="` + path.Base(fingerprinted("inc/i.png", png)) + `?x">`,
+		},
+		{
+			"index.html",
+			`<a href="https://github.com/myitcv/gopherize.me">`,
+			`<a href="https://github.com/myitcv/gopherize.me">`,
+		},
+		{
+			"index.html",
+			`<a href="/client.js"><a href="#top"><a href="">`,
+			`<a href="/client.js"><a href="#top"><a href="">`,
+		},
+		{
+			"index.html",
+			`<script src="missing.js"></script>`,
+			`<script src="missing.js"></script>`,
+		},
+		{
+			"index.html",
+			`<p>src="client.js"</p>`,
+			`<p>src="` + fingerprinted("client.js", js) + `"</p>`,
+		},
+	}
+
+	for _, test := range tests {
+		b := &builder{
+			files: map[string][]byte{
+				"client.js": []byte(js),
+				"inc/i.png": []byte(png),
+			},
+			manifest: make(Manifest),
+		}
+
+		if got := string(b.rewrite(test.name, []byte(test.in), htmlRef)); got != test.want {
+			t.Errorf("rewrite(%q, %q) = %q; want %q", test.name, test.in, got, test.want)
+		}
+	}
+}
+
+func TestTrimSourceMap(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"a();\n//# sourceMappingURL=client.js.map\n", "a();\n"},
+		{"a();\n//# sourceMappingURL=client.js.map", "a();\n"},
+		{"a();\n", "a();\n"},
+		{"a();\n// a comment\n", "a();\n// a comment\n"},
+	}
+
+	for _, test := range tests {
+		if got := string(trimSourceMap([]byte(test.in))); got != test.want {
+			t.Errorf("trimSourceMap(%q) = %q; want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	client := filepath.Join(tmp, "client")
+	files := map[string]string{
+		Page:            `<link href="client.css"><script src="client.js"></script>`,
+		"client.css":    `body { background: url(inc/bg.png); }`,
+		"inc/bg.png":    "png",
+		"inc/unused.js": "unused",
+		"README.md":     "not part of the site",
+	}
+	for name, c := range files {
+		if err := writeFile(filepath.Join(client, filepath.FromSlash(name)), []byte(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeFile(filepath.Join(tmp, "artwork", "a", "b.png"), []byte("artwork")); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(tmp, "out")
+	o := Options{
+		Client:  client,
+		Artwork: filepath.Join(tmp, "artwork"),
+		JS:      []byte("main();\n//# sourceMappingURL=client.js.map\n"),
+	}
+
+	m, err := Build(out, o)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	css := `body { background: url(` + fingerprinted("inc/bg.png", "png") + `); }`
+	want := map[string]string{
+		ManifestFile: "",
+		StampFile:    "",
+		Page: `<link href="` + fingerprinted("client.css", css) + `"><script src="` +
+			fingerprinted(ClientJS, "main();\n") + `"></script>`,
+		fingerprinted("client.css", css):     css,
+		fingerprinted(ClientJS, "main();\n"): "main();\n",
+		fingerprinted("inc/bg.png", "png"):   "png",
+		"inc/unused.js":                      "unused",
+		"artwork/a/b.png":                    "artwork",
+	}
+
+	got := make(map[string]string)
+	err = filepath.Walk(out, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(out, p)
+		if err != nil {
+			return err
+		}
+		got[filepath.ToSlash(rel)] = string(b)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got[ManifestFile] = ""
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Build wrote %q; want %q", got, want)
+	}
+
+	rm, err := ReadManifest(http.Dir(out))
+	if err != nil {
+		t.Fatalf("ReadManifest: %v", err)
+	}
+	if !reflect.DeepEqual(rm, m) || len(m) != 3 {
+		t.Errorf("ReadManifest = %v; Build returned %v", rm, m)
+	}
+
+	// building again, over the same site, gives the same manifest
+	m2, err := Build(out, o)
+	if err != nil {
+		t.Fatalf("second Build: %v", err)
+	}
+	if !reflect.DeepEqual(m2, m) {
+		t.Errorf("second Build gave manifest %v; want %v", m2, m)
+	}
+}
+
+func TestBuildNotEmpty(t *testing.T) {
+	out, err := ioutil.TempDir("", "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	if err := writeFile(filepath.Join(out, "precious.txt"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Build(out, Options{}); err == nil {
+		t.Errorf("Build over a directory holding no site gave no error")
+	}
+	if _, err := os.Stat(filepath.Join(out, "precious.txt")); err != nil {
+		t.Errorf("Build removed a file of a directory holding no site: %v", err)
+	}
+
+	// a manifest alone does not make a site built by Build
+	if err := writeFile(filepath.Join(out, ManifestFile), []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Build(out, Options{}); err == nil {
+		t.Errorf("Build over a directory holding a manifest but no stamp gave no error")
+	}
+	for _, name := range []string{"precious.txt", ManifestFile} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Errorf("Build removed %v of a directory with no stamp: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Dir is a Store that keeps each record as a JSON file in a directory, named
+// after its key. It is suited to small numbers of records that are
+// convenient to inspect and back up as files. A directory must only be used
+// by one Dir at a time.
+type Dir struct {
+	dir string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+var _ Store = (*Dir)(nil)
+
+// dirExt is the extension of the files of records.
+const dirExt = ".json"
+
+// maxDirName is the length of the longest file name of a record. Escaping
+// can triple the length of a key, beyond what file systems allow, so longer
+// names are cut short and end with "~" and a hash of the key instead.
+const maxDirName = 200
+
+// OpenDir returns a Dir store for the directory dir, creating it if need
+// be.
+func OpenDir(dir string) (*Dir, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, &Error{Op: "open", Err: err}
+	}
+	return &Dir{dir: dir}, nil
+}
+
+// escapeKey returns the file name of the record key. Bytes other than lower
+// case letters, digits, - and _ are escaped as %XX, so that names are
+// portable, including to case-insensitive file systems. Names longer than
+// maxDirName are hashed, as isHashedName reports.
+func escapeKey(key string) string {
+	var sb strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+
+	name := sb.String()
+	if len(name)+len(dirExt) <= maxDirName {
+		return name + dirExt
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	hash := "~" + hex.EncodeToString(sum[:16])
+
+	// cut the name short, but not within an escape
+	n := maxDirName - len(hash) - len(dirExt)
+	if i := strings.LastIndexByte(name[:n], '%'); i > n-3 {
+		n = i
+	}
+
+	return name[:n] + hash + dirExt
+}
+
+// isHashedName reports whether the file name was cut short by escapeKey, so
+// that its key must be read from the record.
+func isHashedName(name string) bool {
+	return strings.HasSuffix(name, dirExt) && strings.Contains(name, "~")
+}
+
+// unescapeKey is the inverse of escapeKey for names that are not hashed,
+// reporting false for names that are not those of records.
+func unescapeKey(name string) (string, bool) {
+	if !strings.HasSuffix(name, dirExt) || strings.Contains(name, "~") {
+		return "", false
+	}
+	name = strings.TrimSuffix(name, dirExt)
+
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '%' {
+			sb.WriteByte(name[i])
+			continue
+		}
+		if i+2 >= len(name) {
+			return "", false
+		}
+		v, err := strconv.ParseUint(name[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", false
+		}
+		sb.WriteByte(byte(v))
+		i += 2
+	}
+
+	return sb.String(), sb.Len() > 0
+}
+
+func (d *Dir) path(key string) string {
+	return filepath.Join(d.dir, escapeKey(key))
+}
+
+// read returns the record key, with a version of NoVersion if it does not
+// exist.
+func (d *Dir) read(op, key string) (Record, error) {
+	r, err := readFile(d.path(key))
+	if err != nil {
+		return Record{}, &Error{Op: op, Key: key, Err: err}
+	}
+	if r.Version == NoVersion {
+		r.Key = key
+	}
+	return r, nil
+}
+
+// readFile returns the record in the file path, with a version of NoVersion
+// if it does not exist.
+func readFile(path string) (Record, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Record{}, nil
+	}
+	if err != nil {
+		return Record{}, err
+	}
+
+	var r Record
+	if err := json.Unmarshal(b, &r); err != nil {
+		return Record{}, fmt.Errorf("corrupt record: %v", err)
+	}
+
+	r.Value = copyBytes(r.Value)
+	return r, nil
+}
+
+// check returns an error for operation op on key if the store is closed or
+// the key is invalid.
+func (d *Dir) check(op, key string) error {
+	if d.closed {
+		return &Error{Op: op, Key: key, Err: ErrClosed}
+	}
+	return checkKey(op, key)
+}
+
+func (d *Dir) Get(key string) (Record, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.check("get", key); err != nil {
+		return Record{}, err
+	}
+
+	r, err := d.read("get", key)
+	if err != nil {
+		return Record{}, err
+	}
+	if r.Version == NoVersion {
+		return Record{}, &Error{Op: "get", Key: key, Err: ErrNotFound}
+	}
+
+	return r, nil
+}
+
+func (d *Dir) Put(key string, value []byte, version int64) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.check("put", key); err != nil {
+		return 0, err
+	}
+
+	cur, err := d.read("put", key)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkVersion("put", key, cur.Version, version); err != nil {
+		return 0, err
+	}
+
+	r := Record{Key: key, Value: copyBytes(value), Version: cur.Version + 1}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return 0, &Error{Op: "put", Key: key, Err: err}
+	}
+	if err := writeFile(d.path(key), b); err != nil {
+		return 0, &Error{Op: "put", Key: key, Err: err}
+	}
+
+	return r.Version, nil
+}
+
+// writeFile writes b to the file path atomically, via a temporary file in
+// the same directory.
+func writeFile(path string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func (d *Dir) Delete(key string, version int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.check("delete", key); err != nil {
+		return err
+	}
+
+	cur, err := d.read("delete", key)
+	if err != nil {
+		return err
+	}
+	if cur.Version == NoVersion {
+		return &Error{Op: "delete", Key: key, Err: ErrNotFound}
+	}
+	if err := checkVersion("delete", key, cur.Version, version); err != nil {
+		return err
+	}
+
+	if err := os.Remove(d.path(key)); err != nil {
+		return &Error{Op: "delete", Key: key, Err: err}
+	}
+
+	return nil
+}
+
+func (d *Dir) List(prefix, after string, limit int) ([]Record, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return nil, &Error{Op: "list", Err: ErrClosed}
+	}
+
+	f, err := os.Open(d.dir)
+	if err != nil {
+		return nil, &Error{Op: "list", Err: err}
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return nil, &Error{Op: "list", Err: err}
+	}
+
+	// escaped names do not sort as their keys do
+	var keys []string
+	for _, n := range names {
+		k, ok := unescapeKey(n)
+		if isHashedName(n) {
+			r, err := readFile(filepath.Join(d.dir, n))
+			if err != nil {
+				return nil, &Error{Op: "list", Err: err}
+			}
+			k, ok = r.Key, r.Version != NoVersion
+		}
+		if ok && strings.HasPrefix(k, prefix) && k > after {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	res := make([]Record, 0, len(keys))
+	for _, k := range keys {
+		r, err := d.read("list", k)
+		if err != nil {
+			return nil, err
+		}
+		if r.Version == NoVersion {
+			// deleted since the directory was read
+			continue
+		}
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+func (d *Dir) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	return nil
+}
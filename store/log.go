@@ -0,0 +1,308 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Log is a Store that keeps its records in a single append-only file, one
+// JSON entry per write, replayed into memory when it is opened. Entries
+// made obsolete by later writes are removed by compaction, which rewrites
+// the file with just the current records; it happens automatically once
+// obsolete entries outnumber current ones, or on request by Compact.
+//
+// A write that fails is cut from the file. One that was interrupted, for
+// example by a crash, leaves an incomplete last entry, which is discarded
+// when the file is next opened.
+type Log struct {
+	path string
+
+	mu     sync.Mutex
+	f      *os.File
+	t      table
+	dead   int
+	closed bool
+}
+
+var _ Store = (*Log)(nil)
+
+// logEntry is an entry of the file of a Log.
+type logEntry struct {
+	Key     string
+	Version int64
+	Value   []byte `json:",omitempty"`
+
+	// Deleted marks the deletion of the record Key
+	Deleted bool `json:",omitempty"`
+}
+
+// minCompact is the number of obsolete entries below which a Log is not
+// compacted automatically, however few current ones there are.
+const minCompact = 1000
+
+// OpenLog opens the Log store in the file path, creating it if need be.
+func OpenLog(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, &Error{Op: "open", Err: err}
+	}
+
+	l := &Log{
+		path: path,
+		f:    f,
+		t:    make(table),
+	}
+
+	if err := l.replay(); err != nil {
+		f.Close()
+		return nil, &Error{Op: "open", Err: err}
+	}
+
+	return l, nil
+}
+
+// replay reads the entries of the file into memory, truncating an
+// incomplete or unreadable last entry, and leaves the file positioned for appending.
+func (l *Log) replay() error {
+	br := bufio.NewReader(l.f)
+
+	var off int64
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			if len(b) > 0 {
+				// a write that was interrupted
+				if err := l.f.Truncate(off); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var e logEntry
+		if err := json.Unmarshal(b, &e); err != nil {
+			// only the last entry can have been torn by a write that
+			// was interrupted; any other is corrupt
+			if _, perr := br.Peek(1); perr == io.EOF {
+				if err := l.f.Truncate(off); err != nil {
+					return err
+				}
+				break
+			}
+			return fmt.Errorf("%v:%v: corrupt entry: %v", l.path, line, err)
+		}
+		l.apply(e)
+
+		off += int64(len(b))
+	}
+
+	_, err := l.f.Seek(off, io.SeekStart)
+	return err
+}
+
+// apply applies e to the records in memory.
+func (l *Log) apply(e logEntry) {
+	if _, ok := l.t[e.Key]; ok {
+		l.dead++
+	}
+
+	if e.Deleted {
+		delete(l.t, e.Key)
+		// the entry of the deletion is itself obsolete
+		l.dead++
+		return
+	}
+
+	l.t[e.Key] = Record{Key: e.Key, Value: e.Value, Version: e.Version}
+}
+
+// append writes e to the file and applies it, compacting the file if it
+// has become mostly obsolete.
+func (l *Log) append(op string, e logEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return &Error{Op: op, Key: e.Key, Err: err}
+	}
+
+	off, err := l.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return &Error{Op: op, Key: e.Key, Err: err}
+	}
+
+	// a write that fails may leave part of the entry in the file, which
+	// is cut off so that it does not corrupt the entries that follow
+	_, err = l.f.Write(append(b, '\n'))
+	if err == nil {
+		err = l.f.Sync()
+	}
+	if err != nil {
+		if terr := l.truncate(off); terr != nil {
+			err = fmt.Errorf("%v; and failed to remove partial entry: %v", err, terr)
+		}
+		return &Error{Op: op, Key: e.Key, Err: err}
+	}
+
+	l.apply(e)
+
+	if l.dead >= minCompact && l.dead > len(l.t) {
+		// the write has succeeded; a failure to compact is reported by
+		// the next compaction
+		l.compact()
+	}
+
+	return nil
+}
+
+// truncate cuts the file of l off at off, where the next entry is then
+// written.
+func (l *Log) truncate(off int64) error {
+	if err := l.f.Truncate(off); err != nil {
+		return err
+	}
+	_, err := l.f.Seek(off, io.SeekStart)
+	return err
+}
+
+func (l *Log) check(op, key string) error {
+	if l.closed {
+		return &Error{Op: op, Key: key, Err: ErrClosed}
+	}
+	return nil
+}
+
+func (l *Log) Get(key string) (Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.check("get", key); err != nil {
+		return Record{}, err
+	}
+
+	return l.t.get(key)
+}
+
+func (l *Log) Put(key string, value []byte, version int64) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.check("put", key); err != nil {
+		return 0, err
+	}
+
+	r, err := l.t.put(key, value, version)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := l.append("put", logEntry{Key: key, Version: r.Version, Value: r.Value}); err != nil {
+		return 0, err
+	}
+
+	return r.Version, nil
+}
+
+func (l *Log) Delete(key string, version int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.check("delete", key); err != nil {
+		return err
+	}
+
+	if err := l.t.delete(key, version); err != nil {
+		return err
+	}
+
+	return l.append("delete", logEntry{Key: key, Deleted: true})
+}
+
+func (l *Log) List(prefix, after string, limit int) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.check("list", ""); err != nil {
+		return nil, err
+	}
+
+	return l.t.list(prefix, after, limit), nil
+}
+
+// Compact rewrites the file of l with just its current records.
+func (l *Log) Compact() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.check("compact", ""); err != nil {
+		return err
+	}
+
+	return l.compact()
+}
+
+func (l *Log) compact() error {
+	tmp, err := os.OpenFile(l.path+".compact", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return &Error{Op: "compact", Err: err}
+	}
+
+	fail := func(err error) error {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return &Error{Op: "compact", Err: err}
+	}
+
+	var buf bytes.Buffer
+	for _, r := range l.t.list("", "", 0) {
+		b, err := json.Marshal(logEntry{Key: r.Key, Version: r.Version, Value: r.Value})
+		if err != nil {
+			return fail(err)
+		}
+		buf.Write(append(b, '\n'))
+	}
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return fail(err)
+	}
+
+	// make the rename durable
+	if d, err := os.Open(filepath.Dir(l.path)); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	l.f.Close()
+	l.f = tmp
+	l.dead = 0
+
+	return nil
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+
+	if err := l.f.Close(); err != nil {
+		return &Error{Op: "close", Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// table is an in-memory set of records, keyed by key, that implements the
+// semantics of Store for the stores that hold their records in memory.
+type table map[string]Record
+
+func (t table) get(key string) (Record, error) {
+	if err := checkKey("get", key); err != nil {
+		return Record{}, err
+	}
+
+	r, ok := t[key]
+	if !ok {
+		return Record{}, &Error{Op: "get", Key: key, Err: ErrNotFound}
+	}
+
+	r.Value = copyBytes(r.Value)
+	return r, nil
+}
+
+// put returns the record that results from a Put, without applying it.
+func (t table) put(key string, value []byte, version int64) (Record, error) {
+	if err := checkKey("put", key); err != nil {
+		return Record{}, err
+	}
+
+	cur := t[key].Version
+	if err := checkVersion("put", key, cur, version); err != nil {
+		return Record{}, err
+	}
+
+	return Record{Key: key, Value: copyBytes(value), Version: cur + 1}, nil
+}
+
+// delete checks that a Delete can be applied, without applying it.
+func (t table) delete(key string, version int64) error {
+	if err := checkKey("delete", key); err != nil {
+		return err
+	}
+
+	r, ok := t[key]
+	if !ok {
+		return &Error{Op: "delete", Key: key, Err: ErrNotFound}
+	}
+
+	return checkVersion("delete", key, r.Version, version)
+}
+
+func (t table) list(prefix, after string, limit int) []Record {
+	var keys []string
+	for k := range t {
+		if strings.HasPrefix(k, prefix) && k > after {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	res := make([]Record, 0, len(keys))
+	for _, k := range keys {
+		r := t[k]
+		r.Value = copyBytes(r.Value)
+		res = append(res, r)
+	}
+
+	return res
+}
+
+// Memory is a Store that holds its records in memory, for development and
+// tests.
+type Memory struct {
+	mu     sync.Mutex
+	t      table
+	closed bool
+}
+
+var _ Store = (*Memory)(nil)
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{t: make(table)}
+}
+
+func (m *Memory) Get(key string) (Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return Record{}, &Error{Op: "get", Key: key, Err: ErrClosed}
+	}
+
+	return m.t.get(key)
+}
+
+func (m *Memory) Put(key string, value []byte, version int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return 0, &Error{Op: "put", Key: key, Err: ErrClosed}
+	}
+
+	r, err := m.t.put(key, value, version)
+	if err != nil {
+		return 0, err
+	}
+	m.t[key] = r
+
+	return r.Version, nil
+}
+
+func (m *Memory) Delete(key string, version int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return &Error{Op: "delete", Key: key, Err: ErrClosed}
+	}
+
+	if err := m.t.delete(key, version); err != nil {
+		return err
+	}
+	delete(m.t, key)
+
+	return nil
+}
+
+func (m *Memory) List(prefix, after string, limit int) ([]Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, &Error{Op: "list", Err: ErrClosed}
+	}
+
+	return m.t.list(prefix, after, limit), nil
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	return nil
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// Package store defines the storage used for anything that gopherize.me
+// persists, such as saved gophers, as a simple key-value store with
+// optimistic concurrency, together with implementations of it: in memory,
+// as a directory of files, and as a single append-only log.
+//
+// Every record has a version, which starts at 1 when the record is created
+// and increases by one each time it is replaced. Writes state the version
+// they expect to replace, so that concurrent updates are detected rather
+// than lost: a writer that loses the race gets an error satisfying
+// IsConflict, and should read the record again before retrying.
+//
+// The package storetest checks that an implementation meets this contract.
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Record is a value held by a Store.
+type Record struct {
+	Key     string
+	Value   []byte
+	Version int64
+}
+
+// Versions with special meaning when given to Put and Delete.
+const (
+	// NoVersion requires that the record does not exist.
+	NoVersion int64 = 0
+
+	// AnyVersion replaces or deletes the record whatever its version.
+	AnyVersion int64 = -1
+)
+
+// Store is a key-value store. Keys are non-empty strings of at most MaxKey
+// bytes. Stores are safe for concurrent use, and their methods neither
+// retain the values passed to them nor share the values they return.
+type Store interface {
+	// Get returns the record key, or an error satisfying IsNotFound.
+	Get(key string) (Record, error)
+
+	// Put sets the value of key, provided its current version is version,
+	// NoVersion if key must not exist, or version is AnyVersion. It
+	// returns the new version of the record, or an error satisfying
+	// IsConflict if the version is not as expected.
+	Put(key string, value []byte, version int64) (int64, error)
+
+	// Delete removes the record key, provided its current version is
+	// version or version is AnyVersion. It returns an error satisfying
+	// IsNotFound if there is no such record, or IsConflict if its version
+	// is not as expected.
+	Delete(key string, version int64) error
+
+	// List returns, in order of key, at most limit records whose keys start
+	// with prefix and sort after after, so that a list can be continued
+	// from the key of the last record returned. A limit of 0 or less means
+	// no limit.
+	List(prefix, after string, limit int) ([]Record, error)
+
+	// Close releases the resources of the store.
+	Close() error
+}
+
+// MaxKey is the maximum length of a key in bytes.
+const MaxKey = 200
+
+var (
+	// ErrNotFound is the cause of errors for records that do not exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrConflict is the cause of errors for writes that expected a
+	// different version of a record.
+	ErrConflict = errors.New("version conflict")
+
+	// ErrInvalidKey is the cause of errors for keys that cannot be stored.
+	ErrInvalidKey = errors.New("invalid key")
+
+	// ErrClosed is the cause of errors for stores that have been closed.
+	ErrClosed = errors.New("store closed")
+)
+
+// Error is the type of the errors returned by the stores of this package.
+type Error struct {
+	Op  string
+	Key string
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("store: %v: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("store: %v %q: %v", e.Op, e.Key, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsNotFound reports whether err is due to a record that does not exist.
+func IsNotFound(err error) bool {
+	return cause(err) == ErrNotFound
+}
+
+// IsConflict reports whether err is due to a write that expected a
+// different version of a record.
+func IsConflict(err error) bool {
+	return cause(err) == ErrConflict
+}
+
+// cause returns the error underlying err, if it is an *Error.
+func cause(err error) error {
+	if e, ok := err.(*Error); ok {
+		return e.Err
+	}
+	return err
+}
+
+// checkKey returns an error for operation op if key is not valid.
+func checkKey(op, key string) error {
+	if key == "" || len(key) > MaxKey || strings.IndexByte(key, 0) != -1 {
+		return &Error{Op: op, Key: key, Err: ErrInvalidKey}
+	}
+	return nil
+}
+
+// checkVersion returns an error for operation op on key, whose current
+// version is cur, NoVersion if it does not exist, if the expected version
+// is not cur.
+func checkVersion(op, key string, cur, version int64) error {
+	switch {
+	case version == AnyVersion:
+		return nil
+	case version < AnyVersion:
+		return &Error{Op: op, Key: key, Err: fmt.Errorf("invalid version %v", version)}
+	case version != cur:
+		return &Error{Op: op, Key: key, Err: ErrConflict}
+	}
+	return nil
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return append([]byte{}, b...)
+}
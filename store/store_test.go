@@ -0,0 +1,137 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"myitcv.io/gopherize.me/store"
+	"myitcv.io/gopherize.me/store/storetest"
+)
+
+// tempDir returns a new temporary directory and a function that removes it.
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestMemory(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, func() store.Store) {
+		return store.NewMemory(), nil
+	})
+}
+
+func TestDir(t *testing.T) {
+	tmp, done := tempDir(t)
+	defer done()
+
+	storetest.Run(t, func(t *testing.T) (store.Store, func() store.Store) {
+		dir, err := ioutil.TempDir(tmp, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		open := func() store.Store {
+			s, err := store.OpenDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}
+		return open(), open
+	})
+}
+
+func TestLog(t *testing.T) {
+	tmp, done := tempDir(t)
+	defer done()
+
+	storetest.Run(t, func(t *testing.T) (store.Store, func() store.Store) {
+		dir, err := ioutil.TempDir(tmp, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		open := func() store.Store {
+			s, err := store.OpenLog(filepath.Join(dir, "log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}
+		return open(), open
+	})
+}
+
+func TestLogTornEntry(t *testing.T) {
+	tmp, done := tempDir(t)
+	defer done()
+
+	good := `{"Key":"k","Version":1,"Value":"dg=="}` + "\n"
+
+	tests := []struct {
+		name, contents string
+		ok             bool
+	}{
+		{"incomplete", good + `{"Key":"k","Vers`, true},
+		{"unreadable", good + `{"Key":"k","Vers` + "\n", true},
+		{"corrupt", good + `{"Key":"k","Vers` + "\n" + good, false},
+	}
+
+	for _, test := range tests {
+		p := filepath.Join(tmp, test.name)
+		if err := ioutil.WriteFile(p, []byte(test.contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		l, err := store.OpenLog(p)
+		if !test.ok {
+			if err == nil {
+				l.Close()
+				t.Errorf("%v: OpenLog succeeded", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: OpenLog: %v", test.name, err)
+			continue
+		}
+
+		// the torn entry is cut off, so that the next one is readable
+		if _, err := l.Put("k", []byte("w"), 1); err != nil {
+			t.Errorf("%v: Put: %v", test.name, err)
+		}
+		l.Close()
+
+		l, err = store.OpenLog(p)
+		if err != nil {
+			t.Errorf("%v: reopening: %v", test.name, err)
+			continue
+		}
+		r, err := l.Get("k")
+		if err != nil || string(r.Value) != "w" || r.Version != 2 {
+			t.Errorf("%v: Get gave %+v, %v; want w at version 2", test.name, r, err)
+		}
+		l.Close()
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	s := store.NewMemory()
+
+	_, err := s.Get("missing")
+	if !store.IsNotFound(err) || store.IsConflict(err) {
+		t.Errorf("Get of a missing key gave %v", err)
+	}
+	if !store.IsNotFound(store.ErrNotFound) {
+		t.Errorf("IsNotFound(ErrNotFound) is false")
+	}
+
+	s.Put("k", nil, store.NoVersion)
+	_, err = s.Put("k", nil, store.NoVersion)
+	if !store.IsConflict(err) || store.IsNotFound(err) {
+		t.Errorf("Put of an existing key gave %v", err)
+	}
+}
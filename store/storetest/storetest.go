@@ -0,0 +1,398 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// Package storetest checks that implementations of store.Store meet its
+// contract. Each implementation runs the same checks from a test of its own:
+//
+//	func TestDir(t *testing.T) {
+//		tmp, err := ioutil.TempDir("", "")
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		defer os.RemoveAll(tmp)
+//
+//		storetest.Run(t, func(t *testing.T) (store.Store, func() store.Store) {
+//			dir, err := ioutil.TempDir(tmp, "")
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			open := func() store.Store {
+//				s, err := store.OpenDir(dir)
+//				if err != nil {
+//					t.Fatal(err)
+//				}
+//				return s
+//			}
+//			return open(), open
+//		})
+//	}
+package storetest
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"myitcv.io/gopherize.me/store"
+)
+
+// Opener returns a new, empty store for the test t. For a store that
+// persists its records it also returns a function that reopens the store
+// once it has been closed; otherwise that function is nil.
+type Opener func(t *testing.T) (s store.Store, reopen func() store.Store)
+
+// Run checks the store returned by open, calling it afresh for each check.
+func Run(t *testing.T, open Opener) {
+	checks := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store, reopen func() store.Store)
+	}{
+		{"GetMissing", testGetMissing},
+		{"PutGet", testPutGet},
+		{"Versions", testVersions},
+		{"Delete", testDelete},
+		{"InvalidKeys", testInvalidKeys},
+		{"LongKeys", testLongKeys},
+		{"Isolation", testIsolation},
+		{"List", testList},
+		{"Concurrent", testConcurrent},
+		{"Closed", testClosed},
+		{"Persistence", testPersistence},
+	}
+
+	for _, c := range checks {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			s, reopen := open(t)
+			defer func() {
+				if s != nil {
+					s.Close()
+				}
+			}()
+			c.fn(t, s, reopen)
+		})
+	}
+}
+
+func mustPut(t *testing.T, s store.Store, key, value string, version int64) int64 {
+	t.Helper()
+
+	v, err := s.Put(key, []byte(value), version)
+	if err != nil {
+		t.Fatalf("Put(%q, %q, %v): %v", key, value, version, err)
+	}
+	return v
+}
+
+func wantRecord(t *testing.T, s store.Store, key, value string, version int64) {
+	t.Helper()
+
+	r, err := s.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	if r.Key != key || string(r.Value) != value || r.Version != version {
+		t.Fatalf("Get(%q) = {%q, %q, %v}; want {%q, %q, %v}", key, r.Key, r.Value, r.Version, key, value, version)
+	}
+}
+
+func testGetMissing(t *testing.T, s store.Store, _ func() store.Store) {
+	_, err := s.Get("missing")
+	if !store.IsNotFound(err) {
+		t.Fatalf("Get of missing key: got %v; want not found", err)
+	}
+	if _, ok := err.(*store.Error); !ok {
+		t.Fatalf("Get of missing key: got error of type %T; want *store.Error", err)
+	}
+}
+
+func testPutGet(t *testing.T, s store.Store, _ func() store.Store) {
+	if v := mustPut(t, s, "a", "one", store.NoVersion); v != 1 {
+		t.Fatalf("version of new record is %v; want 1", v)
+	}
+	wantRecord(t, s, "a", "one", 1)
+
+	// empty values are values nonetheless
+	mustPut(t, s, "empty", "", store.NoVersion)
+	wantRecord(t, s, "empty", "", 1)
+
+	// keys may contain any bytes other than NUL
+	for _, k := range []string{"gophers/06gl27sn", "A b.C", "..", "日本", "%41"} {
+		mustPut(t, s, k, k, store.NoVersion)
+		wantRecord(t, s, k, k, 1)
+	}
+}
+
+func testVersions(t *testing.T, s store.Store, _ func() store.Store) {
+	mustPut(t, s, "k", "1", store.NoVersion)
+
+	if _, err := s.Put("k", []byte("x"), store.NoVersion); !store.IsConflict(err) {
+		t.Fatalf("Put of existing key with NoVersion: got %v; want conflict", err)
+	}
+	if _, err := s.Put("k", []byte("x"), 2); !store.IsConflict(err) {
+		t.Fatalf("Put with future version: got %v; want conflict", err)
+	}
+	if _, err := s.Put("new", []byte("x"), 1); !store.IsConflict(err) {
+		t.Fatalf("Put of missing key with version 1: got %v; want conflict", err)
+	}
+	wantRecord(t, s, "k", "1", 1)
+
+	if v := mustPut(t, s, "k", "2", 1); v != 2 {
+		t.Fatalf("version after update is %v; want 2", v)
+	}
+	if _, err := s.Put("k", []byte("x"), 1); !store.IsConflict(err) {
+		t.Fatalf("Put with stale version: got %v; want conflict", err)
+	}
+	if v := mustPut(t, s, "k", "3", store.AnyVersion); v != 3 {
+		t.Fatalf("version after unconditional update is %v; want 3", v)
+	}
+	wantRecord(t, s, "k", "3", 3)
+
+	if v := mustPut(t, s, "any", "1", store.AnyVersion); v != 1 {
+		t.Fatalf("version of record created unconditionally is %v; want 1", v)
+	}
+}
+
+func testDelete(t *testing.T, s store.Store, _ func() store.Store) {
+	if err := s.Delete("missing", store.AnyVersion); !store.IsNotFound(err) {
+		t.Fatalf("Delete of missing key: got %v; want not found", err)
+	}
+
+	mustPut(t, s, "k", "1", store.NoVersion)
+	mustPut(t, s, "k", "2", 1)
+
+	if err := s.Delete("k", 1); !store.IsConflict(err) {
+		t.Fatalf("Delete with stale version: got %v; want conflict", err)
+	}
+	if err := s.Delete("k", 2); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get("k"); !store.IsNotFound(err) {
+		t.Fatalf("Get of deleted key: got %v; want not found", err)
+	}
+	if err := s.Delete("k", store.AnyVersion); !store.IsNotFound(err) {
+		t.Fatalf("Delete of deleted key: got %v; want not found", err)
+	}
+
+	// a record created again starts afresh
+	if v := mustPut(t, s, "k", "again", store.NoVersion); v != 1 {
+		t.Fatalf("version of recreated record is %v; want 1", v)
+	}
+
+	if err := s.Delete("k", store.AnyVersion); err != nil {
+		t.Fatalf("unconditional Delete: %v", err)
+	}
+}
+
+func testInvalidKeys(t *testing.T, s store.Store, _ func() store.Store) {
+	long := string(bytes.Repeat([]byte("k"), store.MaxKey+1))
+
+	for _, k := range []string{"", "nul\x00", long} {
+		if _, err := s.Put(k, []byte("x"), store.AnyVersion); err == nil {
+			t.Errorf("Put(%q) succeeded; want error", k)
+		}
+		if _, err := s.Get(k); err == nil || store.IsNotFound(err) {
+			t.Errorf("Get(%q): got %v; want invalid key", k, err)
+		}
+	}
+
+	mustPut(t, s, string(bytes.Repeat([]byte("k"), store.MaxKey)), "x", store.NoVersion)
+}
+
+func testLongKeys(t *testing.T, s store.Store, _ func() store.Store) {
+	// keys of the greatest length, of bytes that a store may need to
+	// escape, and differing only at their ends
+	long := string(bytes.Repeat([]byte("K/"), store.MaxKey/2))
+	keys := []string{
+		long[:store.MaxKey-1] + "a",
+		long[:store.MaxKey-1] + "b",
+		long,
+		string(bytes.Repeat([]byte("é"), store.MaxKey/2)),
+	}
+
+	for _, k := range keys {
+		mustPut(t, s, k, "v"+k, store.NoVersion)
+	}
+	for _, k := range keys {
+		wantRecord(t, s, k, "v"+k, 1)
+	}
+
+	rs, err := s.List("K/", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 3 || rs[0].Key != keys[2] || rs[1].Key != keys[0] || rs[2].Key != keys[1] {
+		t.Errorf("List of long keys gave %v records, not those put in order", len(rs))
+	}
+
+	if err := s.Delete(keys[0], 1); err != nil {
+		t.Fatalf("Delete of long key: %v", err)
+	}
+	if _, err := s.Get(keys[1]); err != nil {
+		t.Errorf("Get of long key after Delete of another: %v", err)
+	}
+}
+
+func testIsolation(t *testing.T, s store.Store, _ func() store.Store) {
+	v := []byte("value")
+	mustPut(t, s, "k", string(v), store.NoVersion)
+	if _, err := s.Put("k", v, store.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	// the store must not retain v
+	v[0] = 'X'
+	wantRecord(t, s, "k", "value", 2)
+
+	// nor share the values it returns
+	r, _ := s.Get("k")
+	r.Value[0] = 'X'
+	rs, _ := s.List("", "", 0)
+	rs[0].Value[0] = 'X'
+	wantRecord(t, s, "k", "value", 2)
+}
+
+func testList(t *testing.T, s store.Store, _ func() store.Store) {
+	keys := []string{"b/2", "a/1", "b/1", "b/10", "c", "b/3", "a/2"}
+	for _, k := range keys {
+		mustPut(t, s, k, "v"+k, store.NoVersion)
+	}
+	mustPut(t, s, "b/3", "vb/3", 1)
+
+	list := func(prefix, after string, limit int) string {
+		t.Helper()
+
+		rs, err := s.List(prefix, after, limit)
+		if err != nil {
+			t.Fatalf("List(%q, %q, %v): %v", prefix, after, limit, err)
+		}
+
+		var buf bytes.Buffer
+		for _, r := range rs {
+			if string(r.Value) != "v"+r.Key {
+				t.Fatalf("List returned value %q for key %q", r.Value, r.Key)
+			}
+			fmt.Fprintf(&buf, "%v@%v ", r.Key, r.Version)
+		}
+		return buf.String()
+	}
+
+	for _, c := range []struct {
+		prefix, after string
+		limit         int
+		want          string
+	}{
+		{"", "", 0, "a/1@1 a/2@1 b/1@1 b/10@1 b/2@1 b/3@2 c@1 "},
+		{"b/", "", 0, "b/1@1 b/10@1 b/2@1 b/3@2 "},
+		{"b/", "", 2, "b/1@1 b/10@1 "},
+		{"b/", "b/10", 2, "b/2@1 b/3@2 "},
+		{"b/", "b/3", 2, ""},
+		{"", "b", -1, "b/1@1 b/10@1 b/2@1 b/3@2 c@1 "},
+		{"d", "", 0, ""},
+		{"b/1", "", 0, "b/1@1 b/10@1 "},
+	} {
+		if got := list(c.prefix, c.after, c.limit); got != c.want {
+			t.Errorf("List(%q, %q, %v) = %q; want %q", c.prefix, c.after, c.limit, got, c.want)
+		}
+	}
+
+	if err := s.Delete("b/2", store.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := list("b/", "", 0), "b/1@1 b/10@1 b/3@2 "; got != want {
+		t.Errorf("List after Delete = %q; want %q", got, want)
+	}
+}
+
+func testConcurrent(t *testing.T, s store.Store, _ func() store.Store) {
+	const writers, writes = 8, 20
+
+	mustPut(t, s, "counter", "", store.NoVersion)
+
+	// each writer increments the version of the record writes times,
+	// retrying on conflict, so that no update may be lost
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for n := 0; n < writes; {
+				r, err := s.Get("counter")
+				if err != nil {
+					errs <- err
+					return
+				}
+				_, err = s.Put("counter", r.Value, r.Version)
+				if store.IsConflict(err) {
+					continue
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+				n++
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	wantRecord(t, s, "counter", "", 1+writers*writes)
+}
+
+func testClosed(t *testing.T, s store.Store, _ func() store.Store) {
+	mustPut(t, s, "k", "v", store.NoVersion)
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if _, err := s.Get("k"); err == nil {
+		t.Errorf("Get after Close succeeded")
+	}
+	if _, err := s.Put("k", nil, store.AnyVersion); err == nil {
+		t.Errorf("Put after Close succeeded")
+	}
+	if _, err := s.List("", "", 0); err == nil {
+		t.Errorf("List after Close succeeded")
+	}
+}
+
+func testPersistence(t *testing.T, s store.Store, reopen func() store.Store) {
+	if reopen == nil {
+		t.Skip("store does not persist its records")
+	}
+
+	mustPut(t, s, "kept", "1", store.NoVersion)
+	mustPut(t, s, "kept", "2", 1)
+	mustPut(t, s, "deleted", "x", store.NoVersion)
+	if err := s.Delete("deleted", store.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	s2 := reopen()
+	defer s2.Close()
+
+	wantRecord(t, s2, "kept", "2", 2)
+	if _, err := s2.Get("deleted"); !store.IsNotFound(err) {
+		t.Fatalf("Get of deleted key after reopening: got %v; want not found", err)
+	}
+
+	// versions continue from where they were
+	if v := mustPut(t, s2, "kept", "3", 2); v != 3 {
+		t.Fatalf("version after reopening is %v; want 3", v)
+	}
+}
@@ -0,0 +1,177 @@
+package term
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+const (
+	upperHalf = "▀"
+	lowerHalf = "▄"
+
+	reset = "\x1b[0m"
+)
+
+// asciiRamp holds characters of increasing density; opaque pixels are drawn
+// with at least the first non-space character, so that light areas remain
+// visible.
+const asciiRamp = " .:-=+*#%@"
+
+// pixel returns the colour of the pixel of m at (x, y), and whether it is to
+// be drawn at all.
+func pixel(m *image.NRGBA, x, y int, bg color.Color) (color.NRGBA, bool) {
+	c := m.NRGBAAt(x, y)
+
+	if bg == nil {
+		c.A = 0xff
+		return c, m.NRGBAAt(x, y).A >= 0x80
+	}
+
+	b := color.NRGBAModel.Convert(bg).(color.NRGBA)
+	blend := func(f, b uint8) uint8 {
+		return uint8((int(f)*int(c.A) + int(b)*(0xff-int(c.A)) + 0x7f) / 0xff)
+	}
+
+	return color.NRGBA{R: blend(c.R, b.R), G: blend(c.G, b.G), B: blend(c.B, b.B), A: 0xff}, true
+}
+
+// drawBlocks draws m, whose height is even, with a half block character for
+// each pair of vertically adjacent pixels.
+func drawBlocks(w *bufio.Writer, m *image.NRGBA, o Options) {
+	b := m.Bounds()
+
+	colour := func(c color.NRGBA, fg bool) string {
+		if o.Mode == Mode256 {
+			if fg {
+				return fmt.Sprintf("\x1b[38;5;%dm", xterm256(c))
+			}
+			return fmt.Sprintf("\x1b[48;5;%dm", xterm256(c))
+		}
+		if fg {
+			return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B)
+		}
+		return fmt.Sprintf("\x1b[48;2;%d;%d;%dm", c.R, c.G, c.B)
+	}
+
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		// the escape sequence that sets the colours of the previous cell,
+		// so that it is only repeated when the colours change
+		var last string
+
+		for x := b.Min.X; x < b.Max.X; x++ {
+			top, topOK := pixel(m, x, y, o.Background)
+			bot, botOK := pixel(m, x, y+1, o.Background)
+
+			var esc, ch string
+			switch {
+			case topOK && botOK:
+				esc, ch = colour(top, true)+colour(bot, false), upperHalf
+			case topOK:
+				esc, ch = reset+colour(top, true), upperHalf
+			case botOK:
+				esc, ch = reset+colour(bot, true), lowerHalf
+			default:
+				esc, ch = reset, " "
+			}
+
+			if esc != last {
+				w.WriteString(esc)
+				last = esc
+			}
+			w.WriteString(ch)
+		}
+
+		w.WriteString(reset + "\n")
+	}
+}
+
+// drawASCII draws m, whose height is even, with a character for each pair of
+// vertically adjacent pixels, chosen by their darkness.
+func drawASCII(w *bufio.Writer, m *image.NRGBA, o Options) {
+	b := m.Bounds()
+
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		line := make([]byte, 0, b.Dx())
+
+		for x := b.Min.X; x < b.Max.X; x++ {
+			var dark float64
+			n := 0
+
+			for _, py := range []int{y, y + 1} {
+				c, ok := pixel(m, x, py, o.Background)
+				if !ok {
+					continue
+				}
+				dark += 1 - (0.299*float64(c.R)+0.587*float64(c.G)+0.114*float64(c.B))/0xff
+				n++
+			}
+
+			if n == 0 {
+				line = append(line, ' ')
+				continue
+			}
+
+			i := 1 + int(dark/float64(n)*float64(len(asciiRamp)-2)+0.5)
+			line = append(line, asciiRamp[i])
+		}
+
+		// trailing spaces are noise in banners and files
+		for len(line) > 0 && line[len(line)-1] == ' ' {
+			line = line[:len(line)-1]
+		}
+
+		w.Write(line)
+		w.WriteString("\n")
+	}
+}
+
+// xterm256 returns the index of the colour of the xterm 256 colour palette
+// closest to c, from the 6x6x6 colour cube and the grey ramp. The first 16
+// colours are avoided because terminals commonly redefine them.
+func xterm256(c color.NRGBA) int {
+	levels := [6]int{0, 95, 135, 175, 215, 255}
+
+	nearest := func(v uint8) int {
+		best := 0
+		for i, l := range levels {
+			if abs(int(v)-l) < abs(int(v)-levels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+
+	r, g, b := nearest(c.R), nearest(c.G), nearest(c.B)
+	cube := 16 + 36*r + 6*g + b
+	cubeDist := sq(levels[r]-int(c.R)) + sq(levels[g]-int(c.G)) + sq(levels[b]-int(c.B))
+
+	// the grey ramp runs from 8 to 238 in steps of 10
+	avg := (int(c.R) + int(c.G) + int(c.B)) / 3
+	gi := (avg - 3) / 10
+	if gi < 0 {
+		gi = 0
+	}
+	if gi > 23 {
+		gi = 23
+	}
+	gv := 8 + 10*gi
+	greyDist := sq(gv-int(c.R)) + sq(gv-int(c.G)) + sq(gv-int(c.B))
+
+	if greyDist < cubeDist {
+		return 232 + gi
+	}
+	return cube
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sq(v int) int {
+	return v * v
+}
@@ -0,0 +1,181 @@
+package term
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+)
+
+// kittyChunk is the largest amount of base64 encoded data the kitty graphics
+// protocol allows in a single escape sequence.
+const kittyChunk = 4096
+
+// drawKitty sends m as a PNG using the kitty graphics protocol, to be shown
+// over o.Columns cells; the terminal works out the rows from the aspect
+// ratio of m.
+func drawKitty(w *bufio.Writer, m *image.NRGBA, o Options) error {
+	if o.Background != nil {
+		flat := image.NewNRGBA(m.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.NewUniform(o.Background), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), m, m.Bounds().Min, draw.Over)
+		m = flat
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		return fmt.Errorf("failed to encode image: %v", err)
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	// a=T transmits and displays the image, f=100 says it is a PNG and q=2
+	// stops the terminal from replying
+	ctrl := fmt.Sprintf("a=T,f=100,q=2,c=%d,", o.Columns)
+
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > kittyChunk {
+			chunk = chunk[:kittyChunk]
+		}
+		data = data[len(chunk):]
+
+		more := 0
+		if len(data) > 0 {
+			more = 1
+		}
+
+		fmt.Fprintf(w, "\x1b_G%sm=%d;%s\x1b\\", ctrl, more, chunk)
+
+		// the control data is only given with the first chunk
+		ctrl = ""
+	}
+
+	// the cursor is left on the last row of the image
+	w.WriteString("\n")
+
+	return nil
+}
+
+// drawSixel draws m as sixels, dithered to the Plan 9 palette. Pixels that
+// are not drawn are left showing the background of the terminal.
+func drawSixel(w *bufio.Writer, m *image.NRGBA, o Options) {
+	b := m.Bounds()
+
+	flat := image.NewNRGBA(b)
+	visible := make([]bool, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c, ok := pixel(m, x, y, o.Background)
+			flat.SetNRGBA(x, y, c)
+			visible[(y-b.Min.Y)*b.Dx()+x-b.Min.X] = ok
+		}
+	}
+
+	pm := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), palette.Plan9)
+	draw.FloydSteinberg.Draw(pm, pm.Bounds(), flat, b.Min)
+
+	// P2=1 leaves pixels that are not drawn transparent; the raster
+	// attributes give square pixels and the size of the image
+	fmt.Fprintf(w, "\x1bP0;1;0q\"1;1;%d;%d", b.Dx(), b.Dy())
+
+	var used [256]bool
+	for i, v := range visible {
+		if v {
+			used[pm.Pix[i]] = true
+		}
+	}
+	for i, c := range pm.Palette {
+		if !used[i] {
+			continue
+		}
+		cr, cg, cb, _ := c.RGBA()
+		fmt.Fprintf(w, "#%d;2;%d;%d;%d", i, percent(cr), percent(cg), percent(cb))
+	}
+
+	width, height := pm.Bounds().Dx(), pm.Bounds().Dy()
+
+	// the sixels of each colour of the current band, in the order in which
+	// the colours first appear
+	var rows [256][]byte
+	var order []uint8
+	var inBand [256]bool
+
+	for band := 0; band < height; band += 6 {
+		for _, c := range order {
+			inBand[c] = false
+		}
+		order = order[:0]
+
+		for dy := 0; dy < 6 && band+dy < height; dy++ {
+			for x := 0; x < width; x++ {
+				i := (band+dy)*width + x
+				if !visible[i] {
+					continue
+				}
+
+				c := pm.Pix[i]
+				if rows[c] == nil {
+					rows[c] = make([]byte, width)
+				}
+				if !inBand[c] {
+					// the first use of c in this band; clear the previous
+					inBand[c] = true
+					order = append(order, c)
+					for j := range rows[c] {
+						rows[c][j] = 0
+					}
+				}
+				rows[c][x] |= 1 << uint(dy)
+			}
+		}
+
+		for j, c := range order {
+			if j > 0 {
+				// return to the start of the band for the next colour
+				w.WriteByte('$')
+			}
+			fmt.Fprintf(w, "#%d", c)
+			writeSixels(w, rows[c])
+		}
+
+		w.WriteByte('-')
+	}
+
+	w.WriteString("\x1b\\")
+}
+
+// writeSixels writes the sixels with bits s, run length encoded, omitting
+// trailing blanks.
+func writeSixels(w *bufio.Writer, s []byte) {
+	for len(s) > 0 && s[len(s)-1] == 0 {
+		s = s[:len(s)-1]
+	}
+
+	for len(s) > 0 {
+		n := 1
+		for n < len(s) && s[n] == s[0] {
+			n++
+		}
+
+		ch := '?' + s[0]
+		if n > 3 {
+			fmt.Fprintf(w, "!%d%c", n, ch)
+		} else {
+			for i := 0; i < n; i++ {
+				w.WriteByte(ch)
+			}
+		}
+
+		s = s[n:]
+	}
+}
+
+// percent converts a 16 bit colour component to the percentage used by
+// sixel colour definitions.
+func percent(v uint32) int {
+	return int((v*100 + 0x7fff) / 0xffff)
+}
@@ -0,0 +1,10 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package term
+
+// size returns zeros, because the size of the terminal cannot be determined
+// on this platform.
+func size() (columns, rows, width, height int) {
+	return 0, 0, 0, 0
+}
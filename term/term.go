@@ -0,0 +1,219 @@
+// Copyright (c) 2016 Paul Jolly <paul@myitcv.org.uk>, all rights reserved.
+// Use of this document is governed by a license found in the LICENSE document.
+
+// Package term draws images in a terminal, using coloured character cells or
+// the graphics protocols of terminals that support them, so that gophers can
+// be previewed without a browser, for example over SSH, or used in banners
+// and messages of the day.
+package term
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"strings"
+
+	"myitcv.io/gopherize.me/render"
+)
+
+// Mode is a way of drawing images in a terminal.
+type Mode string
+
+const (
+	// ModeKitty sends the image, at the resolution of the terminal, using
+	// the graphics protocol of the kitty terminal.
+	ModeKitty Mode = "kitty"
+
+	// ModeSixel draws the image, at the resolution of the terminal, as
+	// sixels, in at most 256 colours.
+	ModeSixel Mode = "sixel"
+
+	// ModeTrueColour draws two pixels per character cell, using the upper
+	// half block character in 24 bit colour.
+	ModeTrueColour Mode = "truecolor"
+
+	// Mode256 is ModeTrueColour restricted to the 256 colours of xterm.
+	Mode256 Mode = "256"
+
+	// ModeASCII draws one character per cell, chosen by the darkness of the
+	// pixels it covers, without any colour.
+	ModeASCII Mode = "ascii"
+)
+
+// Modes lists the modes, best first.
+var Modes = []Mode{ModeKitty, ModeSixel, ModeTrueColour, Mode256, ModeASCII}
+
+// ParseMode returns the mode called s.
+func ParseMode(s string) (Mode, error) {
+	for _, m := range Modes {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown mode %q", s)
+}
+
+// Detect returns the best mode supported by the terminal, as described by
+// the environment variables NO_COLOR, KITTY_WINDOW_ID, TERM, COLORTERM and
+// TERM_PROGRAM. Terminals cannot be queried for sixel support without
+// reading from them, so sixels are only detected for terminals known to
+// support them.
+func Detect() Mode {
+	return detect(os.Getenv)
+}
+
+func detect(getenv func(string) string) Mode {
+	if getenv("NO_COLOR") != "" {
+		return ModeASCII
+	}
+
+	t := getenv("TERM")
+
+	switch {
+	case getenv("KITTY_WINDOW_ID") != "", t == "xterm-kitty", t == "xterm-ghostty":
+		return ModeKitty
+	case strings.Contains(t, "sixel"), strings.HasPrefix(t, "foot"), strings.HasPrefix(t, "mlterm"):
+		return ModeSixel
+	}
+
+	switch getenv("TERM_PROGRAM") {
+	case "ghostty":
+		return ModeKitty
+	case "WezTerm":
+		return ModeSixel
+	}
+
+	switch getenv("COLORTERM") {
+	case "truecolor", "24bit":
+		return ModeTrueColour
+	}
+
+	switch {
+	case t == "" || t == "dumb":
+		return ModeASCII
+	case strings.Contains(t, "truecolor") || strings.Contains(t, "direct"):
+		return ModeTrueColour
+	}
+
+	return Mode256
+}
+
+// Options describe how an image is drawn.
+type Options struct {
+	Mode Mode
+
+	// Columns is the width of the image in character cells.
+	Columns int
+
+	// CellWidth and CellHeight are the size of a character cell in pixels,
+	// which determines the resolution of images drawn by ModeKitty and
+	// ModeSixel. Zero means DefaultCellWidth and DefaultCellHeight.
+	CellWidth, CellHeight int
+
+	// Background, if set, is the colour over which partly transparent
+	// pixels are drawn. Otherwise pixels are either drawn opaque or left
+	// blank, showing the background of the terminal.
+	Background color.Color
+}
+
+// The size of a character cell in pixels assumed when it is not known.
+const (
+	DefaultCellWidth  = 10
+	DefaultCellHeight = 20
+)
+
+// Draw writes m to w as drawn in the terminal as described by o. Character
+// cells are assumed to be twice as tall as they are wide, except by the
+// image modes, which use the cell size of o.
+func Draw(w io.Writer, m image.Image, o Options) error {
+	if o.Columns < 1 {
+		return fmt.Errorf("invalid number of columns %v", o.Columns)
+	}
+
+	b := m.Bounds()
+	if b.Empty() {
+		return nil
+	}
+
+	bw := bufio.NewWriter(w)
+
+	switch o.Mode {
+	case ModeKitty, ModeSixel:
+		cw, ch := o.CellWidth, o.CellHeight
+		if cw < 1 || ch < 1 {
+			cw, ch = DefaultCellWidth, DefaultCellHeight
+		}
+
+		width := o.Columns * cw
+		height := width * b.Dy() / b.Dx()
+		if height < 1 {
+			height = 1
+		}
+
+		px := render.Resize(m, width, height)
+
+		var err error
+		if o.Mode == ModeKitty {
+			err = drawKitty(bw, px, o)
+		} else {
+			drawSixel(bw, px, o)
+		}
+		if err != nil {
+			return err
+		}
+
+	case ModeTrueColour, Mode256, ModeASCII:
+		// each cell is two pixels tall, whether the pixels are drawn as half
+		// blocks or averaged into a single character
+		rows := (o.Columns*b.Dy()/b.Dx() + 1) / 2
+		if rows < 1 {
+			rows = 1
+		}
+
+		px := render.Resize(m, o.Columns, rows*2)
+
+		if o.Mode == ModeASCII {
+			drawASCII(bw, px, o)
+		} else {
+			drawBlocks(bw, px, o)
+		}
+
+	default:
+		return fmt.Errorf("unknown mode %q", o.Mode)
+	}
+
+	return bw.Flush()
+}
+
+// Size returns the size of the terminal in character cells, or 80 x 24 if it
+// cannot be determined. The columns may be overridden by the environment
+// variable COLUMNS.
+func Size() (columns, rows int) {
+	columns, rows, _, _ = size()
+
+	if c := os.Getenv("COLUMNS"); c != "" {
+		fmt.Sscan(c, &columns)
+	}
+
+	if columns < 1 {
+		columns = 80
+	}
+	if rows < 1 {
+		rows = 24
+	}
+
+	return columns, rows
+}
+
+// CellSize returns the size of a character cell of the terminal in pixels,
+// or DefaultCellWidth x DefaultCellHeight if it cannot be determined.
+func CellSize() (width, height int) {
+	columns, rows, w, h := size()
+	if columns < 1 || rows < 1 || w < columns || h < rows {
+		return DefaultCellWidth, DefaultCellHeight
+	}
+	return w / columns, h / rows
+}
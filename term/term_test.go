@@ -0,0 +1,117 @@
+package term
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"myitcv.io/gopherize.me/gopher"
+	"myitcv.io/gopherize.me/render"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// testRecipe is the gopher drawn by the golden tests.
+const testRecipe = `
+body=blue_gopher
+eyes=goofy_eyes
+extras=coffee
+`
+
+func testGopher(t *testing.T) image.Image {
+	g, err := gopher.ParseRecipeText([]byte(testRecipe), gopher.DefaultConfig)
+	if err != nil {
+		t.Fatalf("failed to parse recipe: %v", err)
+	}
+
+	m, err := render.Compose(g, render.NewDir(filepath.Join("..", "artwork")))
+	if err != nil {
+		t.Fatalf("failed to draw gopher: %v", err)
+	}
+
+	return m
+}
+
+func TestDrawGolden(t *testing.T) {
+	m := testGopher(t)
+
+	for _, mode := range Modes {
+		for _, bg := range []bool{false, true} {
+			o := Options{
+				Mode:       mode,
+				Columns:    16,
+				CellWidth:  4,
+				CellHeight: 8,
+			}
+			name := string(mode)
+			if bg {
+				o.Background = color.White
+				name += "_background"
+			}
+
+			var buf bytes.Buffer
+			if err := Draw(&buf, m, o); err != nil {
+				t.Errorf("%v: %v", name, err)
+				continue
+			}
+
+			fn := filepath.Join("testdata", name+".golden")
+
+			if *update {
+				if err := ioutil.WriteFile(fn, buf.Bytes(), 0666); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+
+			want, err := ioutil.ReadFile(fn)
+			if err != nil {
+				t.Fatalf("%v; run go test -update to create it", err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("%v: output differs from %v; run go test -update if the change is intended\ngot:\n%q", name, fn, buf.Bytes())
+			}
+		}
+	}
+}
+
+func TestDrawInvalid(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+
+	if err := Draw(ioutil.Discard, m, Options{Mode: ModeASCII}); err == nil {
+		t.Errorf("no error drawing in 0 columns")
+	}
+	if err := Draw(ioutil.Discard, m, Options{Mode: "braille", Columns: 4}); err == nil {
+		t.Errorf("no error drawing in an unknown mode")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want Mode
+	}{
+		{map[string]string{}, ModeASCII},
+		{map[string]string{"TERM": "dumb"}, ModeASCII},
+		{map[string]string{"TERM": "xterm-kitty", "NO_COLOR": "1"}, ModeASCII},
+		{map[string]string{"TERM": "xterm-kitty"}, ModeKitty},
+		{map[string]string{"TERM": "xterm-256color", "KITTY_WINDOW_ID": "1"}, ModeKitty},
+		{map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "ghostty"}, ModeKitty},
+		{map[string]string{"TERM": "foot"}, ModeSixel},
+		{map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "WezTerm"}, ModeSixel},
+		{map[string]string{"TERM": "xterm-256color", "COLORTERM": "truecolor"}, ModeTrueColour},
+		{map[string]string{"TERM": "xterm-direct"}, ModeTrueColour},
+		{map[string]string{"TERM": "xterm-256color"}, Mode256},
+	}
+
+	for _, test := range tests {
+		got := detect(func(k string) string { return test.env[k] })
+		if got != test.want {
+			t.Errorf("detect(%v) = %v; want %v", test.env, got, test.want)
+		}
+	}
+}